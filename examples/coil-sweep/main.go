@@ -0,0 +1,119 @@
+// coil-sweep builds multi-filar coils (using the coil package) over
+// ranges of their parameters and reports on each of them: the size of
+// the board, the length, resistance and inductance of the winding and
+// the violations of a manufacturer's design rules.
+//
+// Each parameter is a single value, a comma-separated list of values
+// or a range as "start:stop:step", for example:
+//
+//	coil-sweep -n 10:100:10 -trace 0.15,0.2 -gap 0.15,0.2 -fab jlcpcb -csv coils.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/coil"
+	"github.com/gmlewis/go-gerber/gerber/drc"
+	"github.com/gmlewis/go-gerber/gerber/sweep"
+)
+
+var (
+	coils    = flag.String("coils", "2", "Number of interleaved windings on each layer (2 = bifilar)")
+	layers   = flag.String("layers", "2", "Number of copper layers (even)")
+	n        = flag.String("n", "10:50:10", "Approximate number of turns of each winding on each layer")
+	gap      = flag.String("gap", "0.15", "Gap between traces in mm (6mil = 0.15mm)")
+	trace    = flag.String("trace", "0.15", "Width of traces in mm")
+	parallel = flag.Bool("parallel", false, "Connect the windings in parallel rather than in series")
+	prefix   = flag.String("prefix", "coil", "Filename prefix of each variant")
+	fab      = flag.String("fab", "", "Manufacturer profile (jlcpcb, oshpark, pcbway, eurocircuits or a JSON file) to check each variant against")
+	workers  = flag.Int("workers", 0, "Number of variants to build at once (0 = number of CPUs)")
+	csvOut   = flag.String("csv", "", "CSV report filename (default standard output)")
+	jsonOut  = flag.String("json", "", "JSON report filename")
+	zipDir   = flag.String("zip", "", "Directory to write the ZIP file of each variant to (empty to not write)")
+)
+
+func main() {
+	flag.Parse()
+
+	cfg := &sweep.Config{Workers: *workers, ZipDir: *zipDir}
+	for _, f := range []struct{ name, value string }{
+		{"coils", *coils}, {"layers", *layers}, {"n", *n}, {"gap", *gap}, {"trace", *trace},
+	} {
+		p, err := sweep.ParseParam(f.name, f.value)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg.Params = append(cfg.Params, p)
+	}
+
+	var profile *gerber.Profile
+	if *fab != "" {
+		p, err := gerber.OpenProfile(*fab)
+		if err != nil {
+			log.Fatal(err)
+		}
+		profile = p
+		cfg.Rules = drc.ProfileRules(p)
+	}
+
+	topology := coil.Series
+	if *parallel {
+		topology = coil.Parallel
+	}
+	design := func(p sweep.Params) (*gerber.Gerber, error) {
+		g, err := coil.New(&coil.Config{
+			Name:     fmt.Sprintf("%v-%vx%v-n%v-t%v-g%v", *prefix, p["layers"], p["coils"], p["n"], p["trace"], p["gap"]),
+			Coils:    int(p["coils"]),
+			Layers:   int(p["layers"]),
+			Turns:    p["n"],
+			Trace:    p["trace"],
+			Gap:      p["gap"],
+			Topology: topology,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			if err := g.SetProfile(profile); err != nil {
+				return nil, err
+			}
+		}
+		return g, nil
+	}
+
+	results, err := sweep.Run(design, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := write(*csvOut, results, sweep.WriteCSV); err != nil {
+		log.Fatal(err)
+	}
+	if *jsonOut != "" {
+		if err := write(*jsonOut, results, sweep.WriteJSON); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// write writes the report to the file, or to standard output
+// if the filename is empty.
+func write(filename string, results []*sweep.Result, report func(io.Writer, []*sweep.Result) error) error {
+	if filename == "" {
+		return report(os.Stdout, results)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := report(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
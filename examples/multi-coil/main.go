@@ -0,0 +1,107 @@
+// multi-coil creates Gerber files (and a bundled ZIP) representing
+// multi-filar coils stacked on any even number of PCB layers
+// using the coil package.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	_ "github.com/gmlewis/go-fonts/fonts/freeserif"
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/coil"
+	"github.com/gmlewis/go-gerber/gerber/drc"
+	"github.com/gmlewis/go-gerber/gerber/netlist"
+	"github.com/gmlewis/go-gerber/gerber/viewer"
+)
+
+var (
+	coils    = flag.Int("coils", 2, "Number of interleaved windings on each layer (2 = bifilar)")
+	layers   = flag.Int("layers", 2, "Number of copper layers (even)")
+	n        = flag.Float64("n", 100, "Approximate number of turns of each winding on each layer")
+	gap      = flag.Float64("gap", 0.15, "Gap between traces in mm (6mil = 0.15mm)")
+	trace    = flag.Float64("trace", 0.15, "Width of traces in mm")
+	parallel = flag.Bool("parallel", false, "Connect the windings in parallel rather than in series")
+	padAngle = flag.Float64("pad-angle", 0, "Angle (in degrees) at which to place the terminal pads")
+	arcs     = flag.Bool("arcs", false, "Write the spirals as native arcs rather than filled regions")
+	prefix   = flag.String("prefix", "multi-coil", "Filename prefix for all Gerber files and zip")
+	fontName = flag.String("font", "freeserif", "Name of font to use for writing source on PCB (empty to not write)")
+	fab      = flag.String("fab", "", "Manufacturer profile (jlcpcb, oshpark, pcbway, eurocircuits or a JSON file) to check and write the design for")
+	analyze  = flag.Bool("analyze", false, "Report the length, resistance and inductance of the coil")
+	stats    = flag.Bool("stats", false, "Report the copper area and other statistics of each layer")
+	thieving = flag.Float64("thieving", 0, "Fill the empty areas of the copper layers with dots up to this percentage of the board (0 = none)")
+	view     = flag.Bool("view", false, "View the resulting design using Fyne")
+)
+
+func main() {
+	flag.Parse()
+
+	topology := coil.Series
+	if *parallel {
+		topology = coil.Parallel
+	}
+	g, err := coil.New(&coil.Config{
+		Name:     fmt.Sprintf("%v-%vx%v-n%v", *prefix, *layers, *coils, *n),
+		Coils:    *coils,
+		Layers:   *layers,
+		Turns:    *n,
+		Trace:    *trace,
+		Gap:      *gap,
+		Topology: topology,
+		PadAngle: *padAngle,
+		Font:     *fontName,
+		Arcs:     *arcs,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	mbb := g.MBB()
+	fmt.Printf("n=%v: (%.2f,%.2f)\n", *n, mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+
+	if *fab != "" {
+		p, err := gerber.OpenProfile(*fab)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := g.SetProfile(p); err != nil {
+			log.Fatal(err)
+		}
+		for _, v := range drc.Check(g, drc.ProfileRules(p), nil) {
+			fmt.Println(v)
+		}
+	}
+
+	if *analyze {
+		for _, r := range netlist.Extract(g, nil).Analyze(nil) {
+			fmt.Println(r)
+		}
+	}
+
+	if *thieving > 0 {
+		n, err := g.Thieve(&gerber.Thieving{Density: *thieving})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Added %v thieving dots.\n", n)
+	}
+
+	if *stats {
+		for _, s := range g.Stats() {
+			fmt.Printf("%v: %v primitives, %v apertures, area %.2fmm² (%.1f%%), smallest %vmm", s.Layer.Filename, s.Primitives, s.Apertures, s.Area, s.Coverage, s.Smallest)
+			if s.DrillHits != nil {
+				fmt.Printf(", drill hits %v", s.DrillHits)
+			}
+			fmt.Println()
+		}
+	}
+
+	if err := g.WriteGerber(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("Done.")
+
+	if *view {
+		viewer.Gerber(g, true)
+	}
+}
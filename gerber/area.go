@@ -0,0 +1,305 @@
+package gerber
+
+import (
+	"math"
+	"sort"
+)
+
+// areaEpsilon is the distance either side of an edge at which
+// the image is sampled to tell whether the edge bounds it.
+const areaEpsilon = 1e-8
+
+// shape is a flattened polygon (with holes, by the even-odd rule)
+// of an image.
+type shape struct {
+	dark     bool
+	contours [][]Pt
+	mbb      MBB
+}
+
+func (s *shape) contains(pt Pt) bool {
+	if pt[0] < s.mbb.Min[0] || pt[0] > s.mbb.Max[0] || pt[1] < s.mbb.Min[1] || pt[1] > s.mbb.Max[1] {
+		return false
+	}
+	var inside bool
+	for _, c := range s.contours {
+		if pointInContour(pt, c) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// flatImage is the flattened image of a layer: its dark and clear
+// shapes in the order that they are drawn, each over those before it.
+type flatImage struct {
+	shapes []*shape
+	grid   *shapeGrid
+}
+
+// add adds a shape to the image, ignoring degenerate contours.
+func (im *flatImage) add(dark bool, contours ...[]Pt) {
+	s := &shape{dark: dark}
+	for _, c := range contours {
+		if len(c) < 3 {
+			continue
+		}
+		if len(s.contours) == 0 {
+			s.mbb = MBB{Min: c[0], Max: c[0]}
+		}
+		for _, pt := range c {
+			s.mbb.Min = Pt{math.Min(s.mbb.Min[0], pt[0]), math.Min(s.mbb.Min[1], pt[1])}
+			s.mbb.Max = Pt{math.Max(s.mbb.Max[0], pt[0]), math.Max(s.mbb.Max[1], pt[1])}
+		}
+		s.contours = append(s.contours, c)
+	}
+	if len(s.contours) > 0 {
+		im.shapes = append(im.shapes, s)
+	}
+}
+
+// addFeature adds the feature to the image: a stroked path
+// as the union of its segments, each with round ends.
+func (im *flatImage) addFeature(f *Feature, dark bool) {
+	if f.IsRegion() {
+		im.add(dark, append([][]Pt{f.Region}, f.Holes...)...)
+		return
+	}
+	r := 0.5 * f.Width
+	if r <= 0 {
+		return
+	}
+	if len(f.Path) == 1 {
+		im.add(dark, capsule(f.Path[0], f.Path[0], r))
+	}
+	for i := 1; i < len(f.Path); i++ {
+		im.add(dark, capsule(f.Path[i-1], f.Path[i], r))
+	}
+}
+
+// capsule returns the outline of the segment stroked with a circular
+// aperture of radius r, or of a circle if the segment has no length.
+func capsule(p1, p2 Pt, r float64) []Pt {
+	n := int(math.Ceil(math.Pi / segmentAngle(r, DefaultTolerance)))
+	var pts []Pt
+	arc := func(c Pt, from float64, points int) {
+		for i := 0; i < points; i++ {
+			a := from + math.Pi*float64(i)/float64(n)
+			pts = append(pts, Pt{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)})
+		}
+	}
+	if p1 == p2 {
+		arc(p1, 0, 2*n)
+		return pts
+	}
+	angle := math.Atan2(p2[1]-p1[1], p2[0]-p1[0])
+	arc(p2, angle-0.5*math.Pi, n+1)
+	arc(p1, angle+0.5*math.Pi, n+1)
+	return pts
+}
+
+// top returns the index of the topmost shape containing the point,
+// or -1 if there is none.
+func (im *flatImage) top(pt Pt) int {
+	ids := im.grid.at(pt)
+	for j := len(ids) - 1; j >= 0; j-- {
+		if im.shapes[ids[j]].contains(pt) {
+			return ids[j]
+		}
+	}
+	return -1
+}
+
+func (im *flatImage) dark(i int) bool {
+	return i >= 0 && im.shapes[i].dark
+}
+
+// area returns the area of the dark parts of the image.
+//
+// It is found by the shoelace formula around the boundary of the
+// image. The edges of the shapes are split wherever they meet other
+// edges, and each of the resulting pieces that is dark on one side and
+// not on the other bounds the image. Such a piece is counted once, for
+// the topmost of the shapes on either side of it, whose edge it is.
+func (im *flatImage) area() float64 {
+	im.grid = newShapeGrid(im.shapes)
+	var sum float64
+	for i, s := range im.shapes {
+		for _, c := range s.contours {
+			for k, a := range c {
+				sum += im.edgeArea(i, a, c[(k+1)%len(c)])
+			}
+		}
+	}
+	return sum
+}
+
+// edgeArea returns the contribution to the area of the image
+// of the pieces of the edge a-b of shape i that bound the image.
+func (im *flatImage) edgeArea(i int, a, b Pt) float64 {
+	d := Pt{b[0] - a[0], b[1] - a[1]}
+	l := math.Hypot(d[0], d[1])
+	if l == 0 {
+		return 0
+	}
+	mbb := MBB{Min: Pt{math.Min(a[0], b[0]), math.Min(a[1], b[1])}, Max: Pt{math.Max(a[0], b[0]), math.Max(a[1], b[1])}}
+	ts := []float64{0, 1}
+	for _, j := range im.grid.near(mbb) {
+		if !mbbIntersects(im.shapes[j].mbb, mbb) {
+			continue
+		}
+		for _, c := range im.shapes[j].contours {
+			for k, p := range c {
+				q := c[(k+1)%len(c)]
+				if math.Max(p[0], q[0]) < mbb.Min[0] || math.Min(p[0], q[0]) > mbb.Max[0] ||
+					math.Max(p[1], q[1]) < mbb.Min[1] || math.Min(p[1], q[1]) > mbb.Max[1] {
+					continue
+				}
+				ts = append(ts, splits(a, b, p, q)...)
+			}
+		}
+	}
+	sort.Float64s(ts)
+
+	// The normal to the left of the edge.
+	n := Pt{-areaEpsilon * d[1] / l, areaEpsilon * d[0] / l}
+	var sum float64
+	for k := 1; k < len(ts); k++ {
+		if (ts[k]-ts[k-1])*l < areaEpsilon {
+			continue
+		}
+		p0 := Pt{a[0] + ts[k-1]*d[0], a[1] + ts[k-1]*d[1]}
+		p1 := Pt{a[0] + ts[k]*d[0], a[1] + ts[k]*d[1]}
+		m := Pt{0.5 * (p0[0] + p1[0]), 0.5 * (p0[1] + p1[1])}
+		left, right := im.top(Pt{m[0] + n[0], m[1] + n[1]}), im.top(Pt{m[0] - n[0], m[1] - n[1]})
+		if im.dark(left) == im.dark(right) || (left != i || right > i) && (right != i || left > i) {
+			continue
+		}
+		cross := 0.5 * (p0[0]*p1[1] - p1[0]*p0[1])
+		if im.dark(left) {
+			sum += cross
+		} else {
+			sum -= cross
+		}
+	}
+	return sum
+}
+
+// splits returns the positions along a-b (strictly between its ends,
+// as fractions of its length) where the segment p-q meets it.
+func splits(a, b, p, q Pt) []float64 {
+	r, s, ap := Pt{b[0] - a[0], b[1] - a[1]}, Pt{q[0] - p[0], q[1] - p[1]}, Pt{p[0] - a[0], p[1] - a[1]}
+	cross := func(u, v Pt) float64 { return u[0]*v[1] - u[1]*v[0] }
+	den := cross(r, s)
+	rr := r[0]*r[0] + r[1]*r[1]
+	if math.Abs(den) <= 1e-12*rr {
+		// Parallel segments meet where they overlap.
+		if math.Abs(cross(ap, r)) > 1e-12*rr {
+			return nil
+		}
+		var result []float64
+		for _, pt := range []Pt{p, q} {
+			if t := ((pt[0]-a[0])*r[0] + (pt[1]-a[1])*r[1]) / rr; t > 0 && t < 1 {
+				result = append(result, t)
+			}
+		}
+		return result
+	}
+	t, u := cross(ap, s)/den, cross(ap, r)/den
+	if t > 0 && t < 1 && u >= 0 && u <= 1 {
+		return []float64{t}
+	}
+	return nil
+}
+
+// shapeGrid is a uniform grid of the shapes of an image,
+// for finding those near a point or an edge.
+type shapeGrid struct {
+	min    Pt
+	size   float64
+	nx, ny int
+	cells  [][]int // the indices of the shapes overlapping each cell, ascending
+	seen   []int   // the last query to find each shape
+	query  int
+	found  []int
+}
+
+func newShapeGrid(shapes []*shape) *shapeGrid {
+	g := &shapeGrid{seen: make([]int, len(shapes)), size: 1}
+	if len(shapes) == 0 {
+		g.nx, g.ny, g.cells = 1, 1, make([][]int, 1)
+		return g
+	}
+	// Size the cells like the shapes, within a limit on their number.
+	mbb := shapes[0].mbb
+	var sum float64
+	for _, s := range shapes {
+		mbb.Join(&s.mbb)
+		sum += math.Max(s.mbb.Max[0]-s.mbb.Min[0], s.mbb.Max[1]-s.mbb.Min[1])
+	}
+	w, h := mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1]
+	g.size = math.Max(sum/float64(len(shapes)), 1e-3)
+	if limit := float64(4*len(shapes) + 16); (w/g.size+1)*(h/g.size+1) > limit {
+		g.size = math.Max(g.size, math.Max(w, h)/math.Sqrt(limit))
+		for (w/g.size+1)*(h/g.size+1) > limit {
+			g.size *= 1.25
+		}
+	}
+	g.min = mbb.Min
+	g.nx, g.ny = int(w/g.size)+1, int(h/g.size)+1
+	g.cells = make([][]int, g.nx*g.ny)
+	for i, s := range shapes {
+		x0, y0, x1, y1 := g.span(s.mbb)
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				g.cells[y*g.nx+x] = append(g.cells[y*g.nx+x], i)
+			}
+		}
+	}
+	return g
+}
+
+// span returns the range of cells overlapped by the box.
+func (g *shapeGrid) span(mbb MBB) (x0, y0, x1, y1 int) {
+	return g.cell(mbb.Min[0], g.min[0], g.nx), g.cell(mbb.Min[1], g.min[1], g.ny), g.cell(mbb.Max[0], g.min[0], g.nx), g.cell(mbb.Max[1], g.min[1], g.ny)
+}
+
+// cell returns the cell along an axis of the grid (starting at min,
+// with n cells) of the coordinate v.
+func (g *shapeGrid) cell(v, min float64, n int) int {
+	i := int(math.Floor((v - min) / g.size))
+	switch {
+	case i < 0:
+		return 0
+	case i >= n:
+		return n - 1
+	}
+	return i
+}
+
+// at returns the indices of the shapes that may contain the point,
+// in ascending order.
+func (g *shapeGrid) at(pt Pt) []int {
+	return g.cells[g.cell(pt[1], g.min[1], g.ny)*g.nx+g.cell(pt[0], g.min[0], g.nx)]
+}
+
+// near returns the indices of the shapes that may overlap the box.
+func (g *shapeGrid) near(mbb MBB) []int {
+	x0, y0, x1, y1 := g.span(mbb)
+	if x0 == x1 && y0 == y1 {
+		return g.cells[y0*g.nx+x0]
+	}
+	g.query++
+	g.found = g.found[:0]
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			for _, i := range g.cells[y*g.nx+x] {
+				if g.seen[i] != g.query {
+					g.seen[i] = g.query
+					g.found = append(g.found, i)
+				}
+			}
+		}
+	}
+	return g.found
+}
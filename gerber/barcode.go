@@ -0,0 +1,167 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/code39"
+)
+
+// BarcodeT represents a linear (1D) barcode, such as Code128 or Code39,
+// and satisfies the Primitive interface. Its bars are written as filled
+// regions, optionally followed by the human-readable Text.
+type BarcodeT struct {
+	Center Pt
+	// BarWidth is the width of the narrowest bar (one module).
+	BarWidth float64
+	Height   float64
+	// QuietZone is the width of the empty margin (in modules) that
+	// must precede and follow the bars. It is included in the MBB.
+	QuietZone int
+	Content   string
+	// Text is the optional human-readable text. See SetText.
+	Text *TextT
+
+	modules []bool     // dark modules from left to right
+	xf      *Transform // optional transformation
+	mbb     *MBB       // cached minimum bounding box
+}
+
+// Code128 returns a Code128 barcode primitive centered at center
+// that encodes the content. It has the standard quiet zone of 10 modules.
+// All dimensions are in millimeters.
+func Code128(center Pt, content string, barWidth, height float64) (*BarcodeT, error) {
+	bc, err := code128.Encode(content)
+	if err != nil {
+		return nil, fmt.Errorf("code128: %v", err)
+	}
+	return linearCode(center, bc, barWidth, height), nil
+}
+
+// Code39 returns a Code39 barcode primitive centered at center
+// that encodes the content (in full ASCII mode), with an optional
+// modulo 43 check character. It has the standard quiet zone of
+// 10 modules.
+// All dimensions are in millimeters.
+func Code39(center Pt, content string, checksum bool, barWidth, height float64) (*BarcodeT, error) {
+	bc, err := code39.Encode(content, checksum, true)
+	if err != nil {
+		return nil, fmt.Errorf("code39: %v", err)
+	}
+	return linearCode(center, bc, barWidth, height), nil
+}
+
+func linearCode(center Pt, bc barcode.Barcode, barWidth, height float64) *BarcodeT {
+	b := bc.Bounds()
+	modules := make([]bool, b.Dx())
+	for x := range modules {
+		r, _, _, _ := bc.At(b.Min.X+x, b.Min.Y).RGBA()
+		modules[x] = r < 0x8000
+	}
+	return &BarcodeT{
+		Center:    center,
+		BarWidth:  barWidth,
+		Height:    height,
+		QuietZone: 10,
+		Content:   bc.Content(),
+		modules:   modules,
+	}
+}
+
+// SetText adds the human-readable content, centered beneath the bars
+// and separated from them by gap, in the given font and size.
+// It returns the barcode for chaining.
+// All dimensions are in millimeters.
+func (b *BarcodeT) SetText(fontName string, pts, gap float64) *BarcodeT {
+	b.Text = Text(b.Center[0], b.Center[1]-0.5*b.Height-gap, 1.0, b.Content, fontName, pts, &TopCenter)
+	if b.xf != nil {
+		b.Text = b.Text.Transform(*b.xf).(*TextT)
+	}
+	b.mbb = nil
+	return b
+}
+
+// Modules returns the number of modules making up the bars,
+// not including the quiet zone.
+func (b *BarcodeT) Modules() int {
+	return len(b.modules)
+}
+
+// point returns the point x modules from the left end of the bars
+// and y millimeters above their bottom.
+func (b *BarcodeT) point(x, y float64) Pt {
+	pt := Pt{
+		b.Center[0] + (x-0.5*float64(len(b.modules)))*b.BarWidth,
+		b.Center[1] - 0.5*b.Height + y,
+	}
+	if b.xf != nil {
+		return b.xf.Apply(pt)
+	}
+	return pt
+}
+
+// Regions returns the closed contours of the bars.
+func (b *BarcodeT) Regions() [][]Pt {
+	var regions [][]Pt
+	for _, r := range mergeModules([][]bool{b.modules}) {
+		regions = append(regions, []Pt{
+			b.point(float64(r.x0), 0),
+			b.point(float64(r.x1), 0),
+			b.point(float64(r.x1), b.Height),
+			b.point(float64(r.x0), b.Height),
+		})
+	}
+	return regions
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (b *BarcodeT) WriteGerber(w io.Writer, apertureIndex int) error {
+	for _, region := range b.Regions() {
+		Polygon(Pt{}, true, region, 0).WriteGerber(w, apertureIndex)
+	}
+	if b.Text != nil {
+		return b.Text.WriteGerber(w, apertureIndex)
+	}
+	return nil
+}
+
+// Aperture returns nil for BarcodeT because it uses the default aperture.
+func (b *BarcodeT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box of the barcode,
+// including its quiet zone and any text.
+func (b *BarcodeT) MBB() MBB {
+	if b.mbb != nil {
+		return *b.mbb
+	}
+	q := float64(b.QuietZone)
+	n := float64(len(b.modules))
+	corners := []Pt{b.point(-q, 0), b.point(n+q, 0), b.point(n+q, b.Height), b.point(-q, b.Height)}
+	b.mbb = &MBB{Min: corners[0], Max: corners[0]}
+	for _, pt := range corners[1:] {
+		b.mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	if b.Text != nil {
+		mbb := b.Text.MBB()
+		b.mbb.Join(&mbb)
+	}
+	return *b.mbb
+}
+
+// Transform returns a transformed copy of the barcode.
+func (b *BarcodeT) Transform(xf Transform) Primitive {
+	n := *b
+	n.mbb = nil
+	if b.Text != nil {
+		n.Text = b.Text.Transform(xf).(*TextT)
+	}
+	if b.xf != nil {
+		xf = b.xf.Then(xf)
+	}
+	n.xf = &xf
+	return &n
+}
@@ -0,0 +1,104 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBarcodeT_Primitive(t *testing.T) {
+	var p Primitive = &BarcodeT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("BarcodeT does not implement the Primitive interface")
+	}
+}
+
+func TestCode128(t *testing.T) {
+	const eps = 1e-9
+	b, err := Code128(Pt{10, 5}, "1234", 0.25, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Start C, two digit pairs and the check symbol (11 modules, 3 bars
+	// each), then the stop symbol (13 modules, 4 bars).
+	if got, want := b.Modules(), 4*11+13; got != want {
+		t.Errorf("Modules = %v, want %v", got, want)
+	}
+	regions := b.Regions()
+	if got, want := len(regions), 4*3+4; got != want {
+		t.Errorf("len(Regions) = %v, want %v", got, want)
+	}
+	for _, region := range regions {
+		if h := region[2][1] - region[1][1]; math.Abs(h-8) > eps {
+			t.Errorf("bar height = %v, want 8", h)
+		}
+	}
+
+	width := 0.25 * float64(b.Modules()+20)
+	want := MBB{Min: Pt{10 - 0.5*width, 1}, Max: Pt{10 + 0.5*width, 9}}
+	got := b.MBB()
+	for i := 0; i < 2; i++ {
+		if math.Abs(got.Min[i]-want.Min[i]) > eps || math.Abs(got.Max[i]-want.Max[i]) > eps {
+			t.Errorf("MBB = %v, want %v", got, want)
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteGerber(&buf, 11); err != nil {
+		t.Fatal(err)
+	}
+	// Each bar region is closed by drawing back to its start.
+	if n := checkRegions(t, buf.String()); n != len(regions) {
+		t.Errorf("WriteGerber wrote %v regions, want %v", n, len(regions))
+	}
+}
+
+func TestCode39(t *testing.T) {
+	b, err := Code39(Pt{0, 0}, "AB-12", false, 0.2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each of the 7 characters (including the start and stop '*')
+	// has 5 bars.
+	if got, want := len(b.Regions()), 7*5; got != want {
+		t.Errorf("len(Regions) = %v, want %v", got, want)
+	}
+	withChecksum, err := Code39(Pt{0, 0}, "AB-12", true, 0.2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(withChecksum.Regions()), 8*5; got != want {
+		t.Errorf("with checksum len(Regions) = %v, want %v", got, want)
+	}
+}
+
+func TestBarcodeT_SetText(t *testing.T) {
+	b, err := Code128(Pt{0, 0}, "SN-0042", 0.25, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bars := b.MBB()
+	b.SetText("freeserif", 6, 0.5)
+	got := b.MBB()
+	text := b.Text.MBB()
+	if got.Max[1] != bars.Max[1] || math.Abs(text.Max[1]-(bars.Min[1]-0.5)) > 1e-9 || got.Min[1] != text.Min[1] {
+		t.Errorf("MBB = %v, text MBB = %v, want text 0.5mm below bars %v", got, text, bars)
+	}
+
+	var buf bytes.Buffer
+	if err := b.WriteGerber(&buf, 11); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "G36*"); n <= len(b.Regions()) {
+		t.Errorf("WriteGerber wrote %v regions, want more than the %v bars", n, len(b.Regions()))
+	}
+
+	// The text moves with the bars.
+	moved := b.Transform(Translate(0, 10)).(*BarcodeT)
+	if mbb := moved.MBB(); math.Abs(mbb.Min[1]-(got.Min[1]+10)) > 1e-9 {
+		t.Errorf("transformed MBB = %v, want %v moved up by 10", mbb, got)
+	}
+}
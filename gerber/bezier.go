@@ -0,0 +1,321 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// maxBezierDepth limits the recursive subdivision of a Bezier segment
+// when it is flattened (2^16 chords per segment).
+const maxBezierDepth = 16
+
+// BezierT represents a chain of quadratic or cubic Bezier curve
+// segments and satisfies the Primitive interface.
+//
+// The curve is either stroked with an aperture of the given Shape and
+// Thickness or, if Filled is set, written as a filled region bounded
+// by the (closed) curve.
+type BezierT struct {
+	// Degree is 2 for quadratic or 3 for cubic segments.
+	Degree int
+	// Points are the end and control points of the segments.
+	// Each segment shares its first point with the end of the previous
+	// segment, so a chain of n segments has n*Degree+1 points:
+	// P0, C1, [C2,] P1, C1, [C2,] P2, ...
+	Points    []Pt
+	Shape     Shape
+	Thickness float64
+	// Filled causes the curve to be written as a filled region
+	// rather than stroked.
+	Filled bool
+	// Tolerance is the maximum deviation (sagitta) in millimeters
+	// when flattening the curve. Zero means DefaultTolerance.
+	Tolerance float64
+
+	mbb *MBB // cached minimum bounding box
+}
+
+// QuadBezier returns a chain of quadratic Bezier segments
+// (with 2n+1 points) stroked with the given aperture.
+// All dimensions are in millimeters.
+func QuadBezier(points []Pt, shape Shape, thickness float64) *BezierT {
+	return &BezierT{
+		Degree:    2,
+		Points:    points,
+		Shape:     shape,
+		Thickness: thickness,
+	}
+}
+
+// CubicBezier returns a chain of cubic Bezier segments
+// (with 3n+1 points) stroked with the given aperture.
+// All dimensions are in millimeters.
+func CubicBezier(points []Pt, shape Shape, thickness float64) *BezierT {
+	return &BezierT{
+		Degree:    3,
+		Points:    points,
+		Shape:     shape,
+		Thickness: thickness,
+	}
+}
+
+// CatmullRom returns a (uniform) Catmull-Rom spline passing through
+// all of the given points, stroked with the given aperture.
+// If closed is true, the spline also joins the last point back to the
+// first, which makes it suitable for filling.
+// The spline is represented by the equivalent cubic Bezier segments.
+func CatmullRom(points []Pt, closed bool, shape Shape, thickness float64) *BezierT {
+	n := len(points)
+	at := func(i int) Pt {
+		if closed {
+			return points[(i%n+n)%n]
+		}
+		// Duplicate the end points to clamp the spline.
+		if i < 0 {
+			i = 0
+		}
+		if i >= n {
+			i = n - 1
+		}
+		return points[i]
+	}
+
+	segments := n - 1
+	if closed {
+		segments = n
+	}
+	var pts []Pt
+	if n > 0 {
+		pts = append(pts, points[0])
+	}
+	for i := 0; i < segments; i++ {
+		p0, p1, p2, p3 := at(i-1), at(i), at(i+1), at(i+2)
+		pts = append(pts,
+			Pt{p1[0] + (p2[0]-p0[0])/6, p1[1] + (p2[1]-p0[1])/6},
+			Pt{p2[0] - (p3[0]-p1[0])/6, p2[1] - (p3[1]-p1[1])/6},
+			p2,
+		)
+	}
+	return CubicBezier(pts, shape, thickness)
+}
+
+// segments returns the control points of each complete segment.
+func (b *BezierT) segments() [][]Pt {
+	if b.Degree < 1 {
+		return nil
+	}
+	var segs [][]Pt
+	for i := 0; i+b.Degree < len(b.Points); i += b.Degree {
+		segs = append(segs, b.Points[i:i+b.Degree+1])
+	}
+	return segs
+}
+
+func (b *BezierT) tolerance() float64 {
+	if b.Tolerance > 0 {
+		return b.Tolerance
+	}
+	return DefaultTolerance
+}
+
+// Flatten returns the curve flattened into straight chords that deviate
+// from it by at most its tolerance.
+func (b *BezierT) Flatten() []Pt {
+	segs := b.segments()
+	if len(segs) == 0 {
+		return nil
+	}
+	tol := b.tolerance()
+	pts := []Pt{segs[0][0]}
+	for _, seg := range segs {
+		pts = flattenBezier(pts, seg, tol, 0)
+	}
+	return pts
+}
+
+// flattenBezier appends the flattened segment (excluding its first point)
+// to pts by recursive subdivision at t=0.5.
+func flattenBezier(pts []Pt, seg []Pt, tol float64, depth int) []Pt {
+	if depth >= maxBezierDepth || bezierFlat(seg, tol) {
+		return append(pts, seg[len(seg)-1])
+	}
+	left, right := splitBezier(seg, 0.5)
+	pts = flattenBezier(pts, left, tol, depth+1)
+	return flattenBezier(pts, right, tol, depth+1)
+}
+
+// bezierFlat reports whether all of the control points of the segment
+// are within tol of its chord, which bounds the deviation of the curve.
+// The distance is to the chord itself, not the line through it, so that
+// control points beyond its ends (where the curve overshoots) count.
+func bezierFlat(seg []Pt, tol float64) bool {
+	p0, p1 := seg[0], seg[len(seg)-1]
+	for _, c := range seg[1 : len(seg)-1] {
+		if d, _ := pointSegmentDistance(c, p0, p1); d > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// splitBezier splits the segment at t using de Casteljau's algorithm.
+func splitBezier(seg []Pt, t float64) (left, right []Pt) {
+	n := len(seg)
+	work := append([]Pt(nil), seg...)
+	left = make([]Pt, n)
+	right = make([]Pt, n)
+	for i := 0; i < n; i++ {
+		left[i] = work[0]
+		right[n-1-i] = work[n-1-i]
+		for j := 0; j < n-1-i; j++ {
+			work[j] = Pt{work[j][0] + t*(work[j+1][0]-work[j][0]), work[j][1] + t*(work[j+1][1]-work[j][1])}
+		}
+	}
+	return left, right
+}
+
+// bezierPoint returns the point on the segment at t.
+func bezierPoint(seg []Pt, t float64) Pt {
+	work := append([]Pt(nil), seg...)
+	for n := len(work) - 1; n > 0; n-- {
+		for j := 0; j < n; j++ {
+			work[j] = Pt{work[j][0] + t*(work[j+1][0]-work[j][0]), work[j][1] + t*(work[j+1][1]-work[j][1])}
+		}
+	}
+	return work[0]
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (b *BezierT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if b.Degree != 2 && b.Degree != 3 {
+		return fmt.Errorf("unsupported Bezier degree %v", b.Degree)
+	}
+	if len(b.Points) < b.Degree+1 || (len(b.Points)-1)%b.Degree != 0 {
+		return fmt.Errorf("Bezier of degree %v has %v points; want a multiple of %v plus 1", b.Degree, len(b.Points), b.Degree)
+	}
+	pts := b.Flatten()
+	if !b.Filled {
+		fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
+		for i, pt := range pts {
+			op := "D01"
+			if i == 0 {
+				op = "D02"
+			}
+			fmt.Fprintf(w, "X%06dY%06d%v*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]), op)
+		}
+		return nil
+	}
+
+	if len(pts) < 3 {
+		return errors.New("filled Bezier must enclose an area")
+	}
+	if first, last := pts[0], pts[len(pts)-1]; first != last {
+		pts = append(pts, first)
+	}
+	io.WriteString(w, "G54D11*\n")
+	io.WriteString(w, "G36*\n")
+	for i, pt := range pts {
+		op := "D01"
+		if i == 0 {
+			op = "D02"
+		}
+		fmt.Fprintf(w, "X%06dY%06d%v*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]), op)
+	}
+	io.WriteString(w, "G37*\n")
+	return nil
+}
+
+// Aperture returns the primitive's desired aperture,
+// or nil if the curve is filled (using the default aperture).
+func (b *BezierT) Aperture() *Aperture {
+	if b.Filled {
+		return nil
+	}
+	return &Aperture{
+		Shape: b.Shape,
+		Size:  b.Thickness,
+	}
+}
+
+// MBB returns the minimum bounding box of the exact curve
+// (including the stroke width), found from the roots of its derivative.
+func (b *BezierT) MBB() MBB {
+	if b.mbb != nil {
+		return *b.mbb
+	}
+	for _, seg := range b.segments() {
+		ts := []float64{0, 1}
+		for axis := 0; axis < 2; axis++ {
+			ts = append(ts, bezierExtrema(seg, axis)...)
+		}
+		for _, t := range ts {
+			pt := bezierPoint(seg, t)
+			v := &MBB{Min: pt, Max: pt}
+			if b.mbb == nil {
+				b.mbb = v
+				continue
+			}
+			b.mbb.Join(v)
+		}
+	}
+	if b.mbb == nil {
+		b.mbb = &MBB{}
+	}
+	if !b.Filled {
+		b.mbb.Min[0] -= 0.5 * b.Thickness
+		b.mbb.Min[1] -= 0.5 * b.Thickness
+		b.mbb.Max[0] += 0.5 * b.Thickness
+		b.mbb.Max[1] += 0.5 * b.Thickness
+	}
+	return *b.mbb
+}
+
+// bezierExtrema returns the parameters in (0,1) at which the given
+// coordinate of the (quadratic or cubic) segment has a local extremum.
+func bezierExtrema(seg []Pt, axis int) []float64 {
+	var ts []float64
+	add := func(t float64) {
+		if t > 0 && t < 1 {
+			ts = append(ts, t)
+		}
+	}
+	switch len(seg) {
+	case 3:
+		p0, p1, p2 := seg[0][axis], seg[1][axis], seg[2][axis]
+		if d := p0 - 2*p1 + p2; d != 0 {
+			add((p0 - p1) / d)
+		}
+	case 4:
+		p0, p1, p2, p3 := seg[0][axis], seg[1][axis], seg[2][axis], seg[3][axis]
+		// The derivative is 3*(a*t^2 + b*t + c).
+		a := -p0 + 3*p1 - 3*p2 + p3
+		b := 2 * (p0 - 2*p1 + p2)
+		c := p1 - p0
+		if math.Abs(a) < 1e-12 {
+			if b != 0 {
+				add(-c / b)
+			}
+			return ts
+		}
+		disc := b*b - 4*a*c
+		if disc < 0 {
+			return ts
+		}
+		sq := math.Sqrt(disc)
+		add((-b + sq) / (2 * a))
+		add((-b - sq) / (2 * a))
+	}
+	return ts
+}
+
+// Transform returns a transformed copy of the curve.
+// Bezier curves are affine invariant, so only the points are transformed.
+func (b *BezierT) Transform(xf Transform) Primitive {
+	n := *b
+	n.Points = xf.transformPts(b.Points)
+	n.Thickness *= xf.ScaleFactor()
+	n.mbb = nil
+	return &n
+}
@@ -0,0 +1,210 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBezierT_Primitive(t *testing.T) {
+	var p Primitive = &BezierT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("BezierT does not implement the Primitive interface")
+	}
+}
+
+// sCurveX is the distance from x=1 to the extrema of the S-curve below.
+var sCurveX = func() float64 {
+	t := 0.5 - math.Sqrt(4752)/264
+	return 24*t - 66*t*t + 44*t*t*t - 1
+}()
+
+func TestBezierT_MBB(t *testing.T) {
+	const eps = 1e-6
+	tests := []struct {
+		name string
+		b    *BezierT
+		want MBB
+	}{
+		{
+			name: "quadratic",
+			b:    QuadBezier([]Pt{{0, 0}, {1, 2}, {2, 0}}, CircleShape, 0),
+			want: MBB{Min: Pt{0, 0}, Max: Pt{2, 1}},
+		},
+		{
+			name: "cubic, stroked",
+			b:    CubicBezier([]Pt{{0, 0}, {0, 4}, {4, 4}, {4, 0}}, CircleShape, 0.2),
+			want: MBB{Min: Pt{-0.1, -0.1}, Max: Pt{4.1, 3.1}},
+		},
+		{
+			name: "cubic S-curve",
+			b:    CubicBezier([]Pt{{0, 0}, {8, 3}, {-6, 3}, {2, 0}}, RectShape, 0),
+			// x(t) = 24t - 66t^2 + 44t^3 has extrema at t = 1/2 ± sqrt(4752)/264.
+			want: MBB{Min: Pt{1 - sCurveX, 0}, Max: Pt{1 + sCurveX, 2.25}},
+		},
+		{
+			name: "collinear overshoot",
+			b:    QuadBezier([]Pt{{0, 0}, {4, 0}, {2, 0}}, CircleShape, 0),
+			// x(t) = 8t - 6t^2 turns back at t = 2/3.
+			want: MBB{Min: Pt{0, 0}, Max: Pt{8.0 / 3, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.b.MBB()
+			for i := 0; i < 2; i++ {
+				if math.Abs(got.Min[i]-tt.want.Min[i]) > eps || math.Abs(got.Max[i]-tt.want.Max[i]) > eps {
+					t.Errorf("MBB = %v, want %v", got, tt.want)
+					break
+				}
+			}
+
+			// The flattened curve must lie within the MBB and touch it.
+			var flat *MBB
+			for _, pt := range tt.b.Flatten() {
+				v := MBB{Min: pt, Max: pt}
+				if flat == nil {
+					flat = &v
+					continue
+				}
+				flat.Join(&v)
+			}
+			hw := 0.5 * tt.b.Thickness
+			tol := tt.b.tolerance()
+			for i := 0; i < 2; i++ {
+				if flat.Min[i]-hw < got.Min[i]-eps || flat.Max[i]+hw > got.Max[i]+eps {
+					t.Errorf("flattened MBB %v is outside %v", flat, got)
+				}
+				if flat.Min[i]-hw > got.Min[i]+tol || flat.Max[i]+hw < got.Max[i]-tol {
+					t.Errorf("flattened MBB %v is too far inside %v", flat, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBezierT_Flatten(t *testing.T) {
+	// A quarter circle approximated by a cubic Bezier.
+	const k = 0.5522847498
+	b := CubicBezier([]Pt{{10, 0}, {10, 10 * k}, {10 * k, 10}, {0, 10}}, CircleShape, 0.1)
+
+	for _, tol := range []float64{0.1, 0.01, 0.001} {
+		b.Tolerance = tol
+		pts := b.Flatten()
+		if pts[0] != b.Points[0] || pts[len(pts)-1] != b.Points[3] {
+			t.Fatalf("tol=%v: Flatten end points = %v, %v", tol, pts[0], pts[len(pts)-1])
+		}
+		// Each chord's midpoint must be within tol of the curve,
+		// which is (very nearly) a circle of radius 10.
+		for i := 1; i < len(pts); i++ {
+			mid := Pt{0.5 * (pts[i][0] + pts[i-1][0]), 0.5 * (pts[i][1] + pts[i-1][1])}
+			if d := 10 - math.Hypot(mid[0], mid[1]); d > tol+3e-3 {
+				t.Errorf("tol=%v: chord %v deviates by %v", tol, i, d)
+			}
+		}
+		if tol == 0.001 && len(pts) < 10 {
+			t.Errorf("tol=%v: only %v points", tol, len(pts))
+		}
+	}
+}
+
+func TestCatmullRom(t *testing.T) {
+	pts := []Pt{{0, 0}, {1, 1}, {2, 0}, {3, 1}}
+
+	open := CatmullRom(pts, false, CircleShape, 0.1)
+	if got, want := len(open.Points), 3*3+1; got != want {
+		t.Fatalf("open len(Points) = %v, want %v", got, want)
+	}
+	closed := CatmullRom(pts, true, CircleShape, 0.1)
+	if got, want := len(closed.Points), 4*3+1; got != want {
+		t.Fatalf("closed len(Points) = %v, want %v", got, want)
+	}
+	if closed.Points[0] != closed.Points[len(closed.Points)-1] {
+		t.Errorf("closed spline does not end at its start")
+	}
+
+	// The spline must pass through every point.
+	for i, pt := range pts {
+		if got := open.Points[3*i]; got != pt {
+			t.Errorf("open spline point %v = %v, want %v", i, got, pt)
+		}
+	}
+	// Tangent continuity: the control points either side of each
+	// interior point are collinear with it.
+	for i := 1; i < len(pts)-1; i++ {
+		c1, p, c2 := open.Points[3*i-1], open.Points[3*i], open.Points[3*i+1]
+		if cross := (p[0]-c1[0])*(c2[1]-p[1]) - (p[1]-c1[1])*(c2[0]-p[0]); math.Abs(cross) > 1e-12 {
+			t.Errorf("spline is not smooth at point %v", i)
+		}
+	}
+}
+
+func TestBezierT_WriteGerber(t *testing.T) {
+	filled := CatmullRom([]Pt{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, true, CircleShape, 0)
+	filled.Filled = true
+
+	tests := []struct {
+		name    string
+		b       *BezierT
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "stroked",
+			b:    QuadBezier([]Pt{{0, 0}, {1, 1}, {2, 0}}, CircleShape, 0.1),
+			want: []string{"G54D12*\nX000000Y000000D02*\n", "X2000000Y000000D01*\n"},
+		},
+		{
+			name: "filled",
+			b:    filled,
+			want: []string{"G54D11*\nG36*\nX000000Y000000D02*\n", "X000000Y000000D01*\nG37*\n"},
+		},
+		{
+			name:    "incomplete segment",
+			b:       CubicBezier([]Pt{{0, 0}, {1, 1}, {2, 0}}, CircleShape, 0.1),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.b.WriteGerber(&buf, 12)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("WriteGerber = nil error, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("WriteGerber = %q, missing %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBezierT_Transform(t *testing.T) {
+	const eps = 1e-9
+	b := CubicBezier([]Pt{{0, 0}, {0, 4}, {4, 4}, {4, 0}}, CircleShape, 0.2)
+	got := b.Transform(Rotate(90).Then(Scale(2, 2))).(*BezierT)
+	want := MBB{Min: Pt{-6.2, -0.2}, Max: Pt{0.2, 8.2}}
+	mbb := got.MBB()
+	for i := 0; i < 2; i++ {
+		if math.Abs(mbb.Min[i]-want.Min[i]) > eps || math.Abs(mbb.Max[i]-want.Max[i]) > eps {
+			t.Errorf("MBB = %v, want %v", mbb, want)
+			break
+		}
+	}
+	if b.Points[1] != (Pt{0, 4}) {
+		t.Errorf("Transform modified the original: %v", b.Points)
+	}
+}
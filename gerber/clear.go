@@ -0,0 +1,48 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+)
+
+// ClearT represents a primitive drawn with clear polarity, erasing
+// whatever was drawn earlier in the layer where it overlaps, and
+// satisfies the Primitive interface.
+type ClearT struct {
+	Primitive Primitive
+}
+
+// Clear returns a primitive that erases the area covered by p.
+// Compound primitives cannot be cleared as a whole.
+func Clear(p Primitive) *ClearT {
+	return &ClearT{Primitive: p}
+}
+
+// WriteGerber writes the primitive to the Gerber file
+// between changes of polarity.
+func (c *ClearT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if _, ok := c.Primitive.(Compound); ok {
+		return fmt.Errorf("cannot clear compound primitive %T", c.Primitive)
+	}
+	io.WriteString(w, "%LPC*%\n")
+	if err := c.Primitive.WriteGerber(w, apertureIndex); err != nil {
+		return err
+	}
+	io.WriteString(w, "%LPD*%\n")
+	return nil
+}
+
+// Aperture returns the aperture of the cleared primitive.
+func (c *ClearT) Aperture() *Aperture {
+	return c.Primitive.Aperture()
+}
+
+// MBB returns the minimum bounding box of the cleared area.
+func (c *ClearT) MBB() MBB {
+	return c.Primitive.MBB()
+}
+
+// Transform returns a transformed copy of the primitive.
+func (c *ClearT) Transform(xf Transform) Primitive {
+	return Clear(c.Primitive.Transform(xf))
+}
@@ -0,0 +1,331 @@
+// Package coil generates multi-layer, multi-filar PCB coils:
+// interleaved Archimedean spirals stacked on several copper layers
+// and joined by vias, complete with terminal pads, drill, solder mask,
+// board outline and optional silkscreen labels.
+//
+// Each winding (one of the interleaved spirals on a layer) zig-zags
+// through the layers: inward on the top layer, outward on the next,
+// and so on, so that the current circulates in the same sense on every
+// layer. Adjacent layers are joined alternately by a via inside the
+// innermost turn and by a via outside the outermost turn.
+// The number of turns is adjusted slightly so that all of these vias
+// are evenly spaced around their rings and never coincide.
+//
+// All dimensions are in millimeters and all angles are in degrees.
+package coil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// Topology represents how the windings are connected to the
+// two terminal pads.
+type Topology int
+
+const (
+	// Series joins the windings end-to-start into one long conductor
+	// between the two terminal pads.
+	Series Topology = iota
+	// Parallel joins the starts of all windings to one terminal pad
+	// and their ends to the other.
+	Parallel
+)
+
+// Config describes a coil.
+// Zero-valued fields take the documented defaults.
+type Config struct {
+	// Name is the filename prefix for the Gerber files (default "coil").
+	Name string
+	// Coils is the number of interleaved windings on each layer
+	// (default 2, a bifilar coil).
+	Coils int
+	// Layers is the (even) number of copper layers (default 2).
+	Layers int
+	// Turns is the approximate number of turns of each winding on each
+	// layer. It is rounded down to a multiple of one half and then
+	// increased by a small fraction to space the vias evenly.
+	Turns float64
+	// Trace is the width of the spiral traces (default 0.2).
+	Trace float64
+	// Gap is the gap between adjacent traces (default 0.2).
+	Gap float64
+	// InnerRadius is the radius of the centerline of the innermost turn.
+	// Zero means the smallest radius that leaves room for the inner vias.
+	InnerRadius float64
+	// Topology selects how the windings are connected.
+	Topology Topology
+	// ViaPad and ViaDrill are the via pad and drill diameters
+	// (defaults 0.6 and 0.3).
+	ViaPad, ViaDrill float64
+	// Pad and PadDrill are the terminal pad and drill diameters
+	// (defaults 2.0 and 1.0).
+	Pad, PadDrill float64
+	// PadAngle is the angle around the coil at which the two
+	// terminal pads are placed (side by side).
+	PadAngle float64
+	// Margin is the distance from the terminal pads to the
+	// (circular) board outline (default 1.0).
+	Margin float64
+	// Font is the name of the font used to label the board.
+	// Empty means no labels are written.
+	Font string
+	// Arcs causes the spirals to be written as native circular arcs
+	// rather than as filled regions.
+	Arcs bool
+}
+
+// withDefaults returns a copy of the config with its defaults filled in.
+func (c Config) withDefaults() Config {
+	def := func(v *float64, d float64) {
+		if *v == 0 {
+			*v = d
+		}
+	}
+	if c.Name == "" {
+		c.Name = "coil"
+	}
+	if c.Coils == 0 {
+		c.Coils = 2
+	}
+	if c.Layers == 0 {
+		c.Layers = 2
+	}
+	def(&c.Trace, 0.2)
+	def(&c.Gap, 0.2)
+	def(&c.ViaPad, 0.6)
+	def(&c.ViaDrill, 0.3)
+	def(&c.Pad, 2.0)
+	def(&c.PadDrill, 1.0)
+	def(&c.Margin, 1.0)
+	return c
+}
+
+func (c *Config) validate() error {
+	switch {
+	case c.Coils < 1:
+		return fmt.Errorf("coil must have at least one winding, got %v", c.Coils)
+	case c.Layers < 2 || c.Layers%2 != 0:
+		return fmt.Errorf("coil must have an even number of layers >= 2, got %v", c.Layers)
+	case c.Turns < 1:
+		return fmt.Errorf("coil must have at least one turn, got %v", c.Turns)
+	case c.Trace <= 0 || c.Gap <= 0:
+		return fmt.Errorf("trace (%v) and gap (%v) must be positive", c.Trace, c.Gap)
+	case c.ViaDrill >= c.ViaPad:
+		return fmt.Errorf("via drill (%v) must be smaller than via pad (%v)", c.ViaDrill, c.ViaPad)
+	case c.PadDrill >= c.Pad:
+		return fmt.Errorf("pad drill (%v) must be smaller than pad (%v)", c.PadDrill, c.Pad)
+	case c.Topology != Series && c.Topology != Parallel:
+		return fmt.Errorf("unknown topology %v", c.Topology)
+	}
+	return nil
+}
+
+// layout holds the computed dimensions of a coil.
+type layout struct {
+	Config
+	slots  int     // number of evenly-spaced vias in each ring
+	turns  float64 // actual turns per winding per layer
+	start  float64 // start angle (in turns) of the first top-layer winding
+	rIn    float64 // radius of the inner via ring
+	r0     float64 // radius of the innermost turn
+	rEnd   float64 // radius of the outermost turn
+	rOut   float64 // radius of the outer via ring
+	rBus   float64 // radius of the terminal leads (or bus rings)
+	rPad   float64 // radius of the terminal pads
+	rBoard float64 // radius of the board outline
+	padSep float64 // angular separation (in degrees) of the terminal pads
+}
+
+// ringRadius returns the smallest radius at which n vias can be
+// evenly spaced with the given center-to-center distance.
+func ringRadius(n int, dist float64) float64 {
+	if n < 2 {
+		return 0
+	}
+	return 0.5 * dist / math.Sin(math.Pi/float64(n))
+}
+
+func newLayout(c Config) (*layout, error) {
+	l := &layout{Config: c}
+	pairs := c.Layers / 2
+	l.slots = c.Coils * pairs
+	// Joining layers 2m and 2m+1 on the inside and 2m+1 and 2m+2 on the
+	// outside requires that 2*turns = 1/slots (mod 1).
+	u := 1 / float64(l.slots)
+	l.turns = 0.5*math.Floor(2*c.Turns) + 0.5*u
+	// Rotate the design so that the start of the first winding
+	// (on the top layer) is at PadAngle.
+	l.start = c.PadAngle/360 + l.turns
+
+	viaDist := c.ViaPad + c.Gap
+	viaToTrace := 0.5*c.ViaPad + c.Gap + 0.5*c.Trace
+	l.rIn = ringRadius(l.slots, viaDist)
+	minR0 := l.rIn + viaToTrace
+	switch {
+	case c.InnerRadius == 0:
+		l.r0 = minR0
+	case c.InnerRadius < minR0:
+		return nil, fmt.Errorf("inner radius %v leaves no room for %v vias; need at least %.3f", c.InnerRadius, l.slots, minR0)
+	default:
+		l.r0 = c.InnerRadius
+		l.rIn = c.InnerRadius - viaToTrace
+	}
+
+	pitch := float64(c.Coils) * (c.Trace + c.Gap)
+	l.rEnd = l.r0 + pitch*l.turns
+	l.rOut = l.rEnd + viaToTrace
+	l.rBus = l.rOut + viaToTrace
+	l.rPad = l.rBus + 0.5*c.Trace + c.Gap + 0.5*c.Pad
+	l.padSep = 360 * 2 * math.Asin(0.5*(c.Pad+c.Gap)/l.rPad) / (2 * math.Pi)
+	l.rBoard = l.rPad + 0.5*c.Pad + c.Margin
+	return l, nil
+}
+
+// spiral returns the winding of the given coil on the given layer.
+func (l *layout) spiral(layer, coil int) *gerber.SpiralT {
+	pitch := float64(l.Coils) * (l.Trace + l.Gap)
+	dir := gerber.Clockwise
+	if layer%2 == 1 {
+		dir = gerber.CounterClockwise
+	}
+	angle := l.start + 2*float64(layer/2)*l.turns + float64(coil)/float64(l.Coils)
+	s := gerber.Spiral(gerber.Pt{0, 0}, l.r0, pitch, l.turns, l.Trace, 360*angle, dir)
+	s.Arcs = l.Arcs
+	return s
+}
+
+// polar returns the point at the given radius in the direction of pt.
+func polar(r float64, pt gerber.Pt) gerber.Pt {
+	d := math.Hypot(pt[0], pt[1])
+	return gerber.Pt{r * pt[0] / d, r * pt[1] / d}
+}
+
+// atAngle returns the point at the given radius and angle (in degrees).
+func atAngle(r, angle float64) gerber.Pt {
+	a := math.Pi * angle / 180
+	return gerber.Pt{r * math.Cos(a), r * math.Sin(a)}
+}
+
+// New generates the coil described by c and returns the complete
+// Gerber design: copper layers (top, inner and bottom), solder masks,
+// drill, outline and (if c.Font is set) the top silkscreen.
+func New(c *Config) (*gerber.Gerber, error) {
+	if c == nil {
+		return nil, errors.New("coil: nil config")
+	}
+	cfg := c.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	l, err := newLayout(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	g := gerber.New(cfg.Name)
+	copper := make([]*gerber.Layer, cfg.Layers)
+	copper[0] = g.TopCopper()
+	topMask := g.TopSolderMask()
+	for i := 1; i < cfg.Layers-1; i++ {
+		copper[i] = g.LayerN(i + 1)
+	}
+	copper[cfg.Layers-1] = g.BottomCopper()
+	bottomMask := g.BottomSolderMask()
+	drill := g.Drill()
+	outline := g.Outline()
+
+	hole := func(pt gerber.Pt, padD, drillD float64) {
+		for _, layer := range copper {
+			layer.Add(gerber.Circle(pt, padD))
+		}
+		topMask.Add(gerber.Circle(pt, padD))
+		bottomMask.Add(gerber.Circle(pt, padD))
+		drill.Add(gerber.Circle(pt, drillD))
+	}
+	stub := func(layer *gerber.Layer, p1, p2 gerber.Pt) {
+		layer.Add(gerber.Line(p1[0], p1[1], p2[0], p2[1], gerber.CircleShape, cfg.Trace))
+	}
+
+	// Windings and their vias. Each via joins the ends of two windings,
+	// so only add it the first time one of its ends is seen.
+	vias := map[[2]int64]bool{}
+	via := func(pt gerber.Pt) {
+		key := [2]int64{int64(math.Round(1e4 * pt[0])), int64(math.Round(1e4 * pt[1]))}
+		if !vias[key] {
+			vias[key] = true
+			hole(pt, cfg.ViaPad, cfg.ViaDrill)
+		}
+	}
+	last := cfg.Layers - 1
+	for i, layer := range copper {
+		for k := 0; k < cfg.Coils; k++ {
+			s := l.spiral(i, k)
+			layer.Add(s)
+
+			inner := polar(l.rIn, s.StartPoint())
+			stub(layer, s.StartPoint(), inner)
+			via(inner)
+
+			outer := polar(l.rOut, s.EndPoint())
+			terminal := (i == 0 || i == last) && (cfg.Topology == Parallel || (i == 0 && k == 0) || (i == last && k == cfg.Coils-1))
+			if terminal {
+				stub(layer, s.EndPoint(), polar(l.rBus, s.EndPoint()))
+				continue
+			}
+			stub(layer, s.EndPoint(), outer)
+			via(outer)
+		}
+	}
+
+	// Terminal pads, placed either side of PadAngle and joined to
+	// the windings by leads on the top and bottom layers.
+	padA := atAngle(l.rPad, cfg.PadAngle+0.5*l.padSep)
+	padB := atAngle(l.rPad, cfg.PadAngle-0.5*l.padSep)
+	if cfg.Topology == Parallel {
+		copper[0].Add(gerber.Arc(gerber.Pt{0, 0}, l.rBus, gerber.CircleShape, 1, 1, 0, 360, cfg.Trace))
+		copper[last].Add(gerber.Arc(gerber.Pt{0, 0}, l.rBus, gerber.CircleShape, 1, 1, 0, 360, cfg.Trace))
+	} else {
+		copper[0].Add(gerber.Arc(gerber.Pt{0, 0}, l.rBus, gerber.CircleShape, 1, 1, cfg.PadAngle, cfg.PadAngle+0.5*l.padSep, cfg.Trace))
+		copper[last].Add(gerber.Arc(gerber.Pt{0, 0}, l.rBus, gerber.CircleShape, 1, 1, cfg.PadAngle-0.5*l.padSep, cfg.PadAngle, cfg.Trace))
+	}
+	stub(copper[0], atAngle(l.rBus, cfg.PadAngle+0.5*l.padSep), padA)
+	stub(copper[last], atAngle(l.rBus, cfg.PadAngle-0.5*l.padSep), padB)
+	hole(padA, cfg.Pad, cfg.PadDrill)
+	hole(padB, cfg.Pad, cfg.PadDrill)
+
+	outline.Add(gerber.Arc(gerber.Pt{0, 0}, l.rBoard, gerber.CircleShape, 1, 1, 0, 360, 0.1))
+
+	if cfg.Font != "" {
+		pts := 36.0 * l.rBoard / 139.18 // determined empirically
+		message := fmt.Sprintf(messageFmt, cfg.Layers, cfg.Coils, cfg.Topology, cfg.Trace, cfg.Gap, l.turns)
+		labelA := atAngle(l.rPad, cfg.PadAngle+1.5*l.padSep)
+		labelB := atAngle(l.rPad, cfg.PadAngle-1.5*l.padSep)
+		g.TopSilkscreen().Add(
+			gerber.Text(0, 0.5*l.rEnd, 1.0, message, cfg.Font, pts, &gerber.Center),
+			gerber.Text(labelA[0], labelA[1], 1.0, "A", cfg.Font, pts, &gerber.Center),
+			gerber.Text(labelB[0], labelB[1], 1.0, "B", cfg.Font, pts, &gerber.Center),
+		)
+	}
+
+	return g, nil
+}
+
+const messageFmt = `%v-layer, %v-filar coil (%v).
+Trace size = %0.2fmm.
+Gap size = %0.2fmm.
+Each spiral has %0.3f turns.`
+
+// String returns the name of the topology.
+func (t Topology) String() string {
+	switch t {
+	case Series:
+		return "series"
+	case Parallel:
+		return "parallel"
+	}
+	return fmt.Sprintf("Topology(%d)", int(t))
+}
@@ -0,0 +1,180 @@
+package coil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/drc"
+)
+
+func layerByRole(g *gerber.Gerber, role gerber.LayerRole) *gerber.Layer {
+	for _, l := range g.Layers {
+		if l.Role == role {
+			return l
+		}
+	}
+	return nil
+}
+
+// hole returns the center and diameter of a drill hole.
+func hole(p gerber.Primitive) (gerber.Pt, float64) {
+	mbb := p.MBB()
+	return gerber.Pt{0.5 * (mbb.Min[0] + mbb.Max[0]), 0.5 * (mbb.Min[1] + mbb.Max[1])}, mbb.Max[0] - mbb.Min[0]
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantVias  int
+		wantLeads int // winding ends that are not joined by a via
+	}{
+		{name: "bifilar", cfg: Config{Turns: 10}, wantVias: 2 + 1, wantLeads: 2},
+		{name: "single", cfg: Config{Coils: 1, Turns: 5.5}, wantVias: 1, wantLeads: 2},
+		{name: "quad series", cfg: Config{Layers: 4, Turns: 20}, wantVias: 4 + 2 + 1, wantLeads: 2},
+		{name: "quad parallel", cfg: Config{Layers: 4, Turns: 20, Topology: Parallel}, wantVias: 4 + 2, wantLeads: 4},
+		{name: "trifilar 6 layers", cfg: Config{Coils: 3, Layers: 6, Turns: 30, Arcs: true}, wantVias: 9 + 6 + 2, wantLeads: 2},
+		{name: "pads at 90", cfg: Config{Turns: 10, PadAngle: 90}, wantVias: 3, wantLeads: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(&tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := tt.cfg.withDefaults()
+
+			var copper []*gerber.Layer
+			for _, l := range g.Layers {
+				if l.Role == gerber.TopCopperRole || l.Role == gerber.BottomCopperRole || l.Role == gerber.LayerNRole(len(copper)+1) {
+					copper = append(copper, l)
+				}
+			}
+			if len(copper) != cfg.Layers {
+				t.Fatalf("copper layers = %v, want %v", len(copper), cfg.Layers)
+			}
+
+			drill := layerByRole(g, gerber.DrillRole)
+			if drill == nil {
+				t.Fatal("missing drill layer")
+			}
+			holes := drill.Primitives
+			if got, want := len(holes), tt.wantVias+2; got != want {
+				t.Errorf("drill holes = %v, want %v", got, want)
+			}
+
+			// Holes must not overlap one another.
+			for i, h1 := range holes {
+				c1, _ := hole(h1)
+				for _, h2 := range holes[i+1:] {
+					c2, _ := hole(h2)
+					if d := math.Hypot(c1[0]-c2[0], c1[1]-c2[1]); d < cfg.ViaPad+cfg.Gap-1e-9 {
+						t.Errorf("holes at %v and %v are only %v apart", c1, c2, d)
+					}
+				}
+			}
+
+			// Every winding end must line up with a via, except for the
+			// ends that lead to the terminal pads.
+			var ends, leads int
+			for _, layer := range copper {
+				for _, p := range layer.Primitives {
+					s, ok := p.(*gerber.SpiralT)
+					if !ok {
+						continue
+					}
+					if s.Arcs != cfg.Arcs {
+						t.Errorf("spiral Arcs = %v, want %v", s.Arcs, cfg.Arcs)
+					}
+					for _, pt := range []gerber.Pt{s.StartPoint(), s.EndPoint()} {
+						ends++
+						a := math.Atan2(pt[1], pt[0])
+						found := false
+						for _, h := range holes {
+							c, d := hole(h)
+							if math.Abs(d-cfg.ViaDrill) > 1e-9 {
+								continue
+							}
+							if math.Hypot(c[0]-pt[0], c[1]-pt[1]) > 0.5*(cfg.ViaPad+cfg.Trace)+cfg.Gap+1e-9 {
+								continue
+							}
+							if d := math.Abs(math.Remainder(math.Atan2(c[1], c[0])-a, 2*math.Pi)); d < 1e-9 {
+								found = true
+								break
+							}
+						}
+						if !found {
+							leads++
+						}
+					}
+				}
+			}
+			if want := 2 * cfg.Coils * cfg.Layers; ends != want {
+				t.Errorf("winding ends = %v, want %v", ends, want)
+			}
+			if leads != tt.wantLeads {
+				t.Errorf("leads = %v, want %v", leads, tt.wantLeads)
+			}
+
+			mbb := g.MBB()
+			if math.Abs(mbb.Max[0]+mbb.Min[0]) > 0.1 || math.Abs(mbb.Max[1]+mbb.Min[1]) > 0.1 {
+				t.Errorf("design is not centered: %v", mbb)
+			}
+		})
+	}
+}
+
+func TestNew_PadAngle(t *testing.T) {
+	g, err := New(&Config{Turns: 10, PadAngle: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The two terminal pads are the last two drill holes and must
+	// straddle the +Y axis.
+	holes := layerByRole(g, gerber.DrillRole).Primitives
+	a, _ := hole(holes[len(holes)-2])
+	b, _ := hole(holes[len(holes)-1])
+	if a[1] <= 0 || b[1] <= 0 || math.Abs(a[0]+b[0]) > 1e-9 || math.Abs(a[1]-b[1]) > 1e-9 {
+		t.Errorf("pads at %v and %v are not either side of 90 degrees", a, b)
+	}
+}
+
+func TestNew_Profiles(t *testing.T) {
+	// The defaults can be made by every built-in manufacturer.
+	g, err := New(&Config{Turns: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, p := range gerber.Profiles {
+		t.Run(name, func(t *testing.T) {
+			if vs := drc.Check(g, drc.ProfileRules(p), nil); len(vs) != 0 {
+				t.Errorf("Check = %v, want no violations", vs)
+			}
+		})
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{name: "nil config"},
+		{name: "odd layers", cfg: &Config{Layers: 3, Turns: 10}},
+		{name: "no turns", cfg: &Config{Turns: 0.5}},
+		{name: "negative coils", cfg: &Config{Coils: -1, Turns: 10}},
+		{name: "via drill too big", cfg: &Config{Turns: 10, ViaDrill: 0.6}},
+		{name: "inner radius too small", cfg: &Config{Turns: 10, InnerRadius: 0.1}},
+		{name: "unknown topology", cfg: &Config{Turns: 10, Topology: 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("New = nil error, want error")
+			}
+		})
+	}
+}
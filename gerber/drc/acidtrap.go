@@ -0,0 +1,228 @@
+package drc
+
+import (
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// stroke is a stroked path of an item on a copper layer.
+type stroke struct {
+	it *item
+	f  *gerber.Feature
+}
+
+// checkAcidTraps checks the angles within the copper: at the corners
+// of each trace, where a trace ends on (or at the end of) another trace
+// and at the concave corners of the copper regions. Traces ending
+// within regions (such as pads) are not checked.
+func checkAcidTraps(layer *gerber.Layer, items []*item, minAngle float64) []Violation {
+	var result []Violation
+	report := func(loc gerber.Pt, angle float64, its ...*item) {
+		// Right angles must not be reported for rounding errors.
+		if angle >= minAngle-1e-6 {
+			return
+		}
+		v := Violation{
+			Kind:     AcidTrap,
+			Layer:    layer,
+			Location: loc,
+			Actual:   angle,
+			Required: minAngle,
+			Nets:     netNames(its...),
+		}
+		for i, it := range its {
+			if i == 0 || it != its[0] {
+				v.Primitives = append(v.Primitives, it.p)
+			}
+		}
+		result = append(result, v)
+	}
+
+	var strokes []stroke
+	for _, it := range items {
+		for _, f := range it.features {
+			if f.IsRegion() {
+				for i, c := range append([][]gerber.Pt{f.Region}, f.Holes...) {
+					for _, corner := range regionCorners(c, i > 0) {
+						report(corner.pt, corner.angle, it)
+					}
+				}
+				continue
+			}
+			if len(f.Path) < 2 {
+				continue
+			}
+			strokes = append(strokes, stroke{it: it, f: f})
+			for _, corner := range pathCorners(f.Path) {
+				report(corner.pt, corner.angle, it)
+			}
+		}
+	}
+
+	for i, a := range strokes {
+		path := a.f.Path
+		for _, end := range []int{0, len(path) - 1} {
+			e := path[end]
+			dir, ok := leaving(path, end)
+			if !ok || closed(path) {
+				continue
+			}
+			for j, b := range strokes {
+				if i == j || gap(b.f.MBB(), gerber.MBB{Min: e, Max: e}) > 0 {
+					continue
+				}
+				dirs, atEnd, ok := junction(b.f, e)
+				// Traces meeting end to end are checked once.
+				if !ok || (atEnd && j < i) {
+					continue
+				}
+				angle := 180.0
+				for _, d := range dirs {
+					angle = math.Min(angle, angleBetween(dir, d))
+				}
+				report(e, angle, a.it, b.it)
+			}
+		}
+	}
+	return result
+}
+
+// corner is a vertex and the angle (in degrees) of the wedge there.
+type corner struct {
+	pt    gerber.Pt
+	angle float64
+}
+
+// closed reports whether the path ends where it starts.
+func closed(path []gerber.Pt) bool {
+	return len(path) > 3 && path[0] == path[len(path)-1]
+}
+
+// pathCorners returns the angles between the segments
+// at each vertex of the path.
+func pathCorners(path []gerber.Pt) []corner {
+	var result []corner
+	n := len(path)
+	for i := 0; i < n-1; i++ {
+		if i == 0 && !closed(path) {
+			continue
+		}
+		prev, next := (i+n-2)%(n-1), i+1
+		u, v := sub(path[prev], path[i]), sub(path[next], path[i])
+		if isZero(u) || isZero(v) {
+			continue
+		}
+		result = append(result, corner{pt: path[i], angle: angleBetween(u, v)})
+	}
+	return result
+}
+
+// regionCorners returns the angles of the empty wedges at the corners
+// of a region's contour: outside the region's outer contour, or within
+// one of its holes.
+func regionCorners(contour []gerber.Pt, hole bool) []corner {
+	var area float64
+	for i, p := range contour {
+		q := contour[(i+1)%len(contour)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	sign := 1.0 // counterclockwise
+	if area < 0 {
+		sign = -1
+	}
+	if hole {
+		sign = -sign // its inside is empty
+	}
+
+	var result []corner
+	n := len(contour)
+	for i, p := range contour {
+		u, v := sub(p, contour[(i+n-1)%n]), sub(contour[(i+1)%n], p)
+		if isZero(u) || isZero(v) {
+			continue
+		}
+		// The contour turns by this much (counterclockwise) here.
+		turn := math.Atan2(u[0]*v[1]-u[1]*v[0], u[0]*v[0]+u[1]*v[1])
+		result = append(result, corner{pt: p, angle: 180 + sign*turn*180/math.Pi})
+	}
+	return result
+}
+
+// leaving returns the direction in which the path leaves its end point.
+func leaving(path []gerber.Pt, end int) (gerber.Pt, bool) {
+	step := 1
+	if end > 0 {
+		step = -1
+	}
+	for i := end + step; i >= 0 && i < len(path); i += step {
+		if d := sub(path[i], path[end]); !isZero(d) {
+			return d, true
+		}
+	}
+	return gerber.Pt{}, false
+}
+
+// junction returns the directions in which the stroked path leaves
+// the point where it meets the end of another trace, whether the point
+// is at one of its ends, and whether they meet at all.
+func junction(f *gerber.Feature, pt gerber.Pt) (dirs []gerber.Pt, atEnd, ok bool) {
+	path := f.Path
+	best, bestSeg, bestT := math.Inf(1), 0, 0.0
+	for i := 1; i < len(path); i++ {
+		p1, p2 := path[i-1], path[i]
+		d := sub(p2, p1)
+		l2 := d[0]*d[0] + d[1]*d[1]
+		if l2 == 0 {
+			continue
+		}
+		t := math.Max(0, math.Min(1, ((pt[0]-p1[0])*d[0]+(pt[1]-p1[1])*d[1])/l2))
+		q := gerber.Pt{p1[0] + t*d[0], p1[1] + t*d[1]}
+		if dist := math.Hypot(pt[0]-q[0], pt[1]-q[1]); dist < best {
+			best, bestSeg, bestT = dist, i, t
+		}
+	}
+	if best > 0.5*f.Width {
+		return nil, false, false
+	}
+
+	p1, p2 := path[bestSeg-1], path[bestSeg]
+	// Meeting within a small fraction of the width
+	// of a vertex is meeting at the vertex.
+	l := math.Hypot(p2[0]-p1[0], p2[1]-p1[1])
+	vertex := -1
+	switch {
+	case bestT*l <= 1e-3*f.Width:
+		vertex = bestSeg - 1
+	case (1-bestT)*l <= 1e-3*f.Width:
+		vertex = bestSeg
+	default:
+		return []gerber.Pt{sub(p2, p1), sub(p1, p2)}, false, true
+	}
+
+	if closed(path) && (vertex == 0 || vertex == len(path)-1) {
+		a, _ := leaving(path, 0)
+		b, _ := leaving(path, len(path)-1)
+		return []gerber.Pt{a, b}, false, true
+	}
+	for _, end := range []int{0, len(path) - 1} {
+		if vertex == end {
+			d, _ := leaving(path, end)
+			return []gerber.Pt{d}, true, true
+		}
+	}
+	return []gerber.Pt{sub(path[vertex-1], path[vertex]), sub(path[vertex+1], path[vertex])}, false, true
+}
+
+// angleBetween returns the angle between the two directions in degrees.
+func angleBetween(u, v gerber.Pt) float64 {
+	return math.Abs(math.Atan2(u[0]*v[1]-u[1]*v[0], u[0]*v[0]+u[1]*v[1])) * 180 / math.Pi
+}
+
+func sub(a, b gerber.Pt) gerber.Pt {
+	return gerber.Pt{a[0] - b[0], a[1] - b[1]}
+}
+
+func isZero(v gerber.Pt) bool {
+	return v[0] == 0 && v[1] == 0
+}
@@ -0,0 +1,80 @@
+package drc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+func TestCheck_AcidTrap(t *testing.T) {
+	tan30 := math.Tan(math.Pi / 6)
+	notch := 1 / math.Tan(math.Pi/12) // deep enough for a 30° wedge
+	tests := []struct {
+		name       string
+		primitives []gerber.Primitive
+		want       []gerber.Pt // locations of the traps
+		wantAngle  float64
+	}{
+		{
+			name:       "right angle corner",
+			primitives: []gerber.Primitive{gerber.Trace([]gerber.Pt{{0, 0}, {5, 0}, {5, 5}}, gerber.CircleShape, 0.2)},
+		},
+		{
+			name:       "acute corner",
+			primitives: []gerber.Primitive{gerber.Trace([]gerber.Pt{{0, 0}, {5, 0}, {0, 5 * tan30}}, gerber.CircleShape, 0.2)},
+			want:       []gerber.Pt{{5, 0}},
+			wantAngle:  30,
+		},
+		{
+			name: "trace ending on another",
+			primitives: []gerber.Primitive{
+				gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.2),
+				gerber.Line(5, 0, 10, 5*tan30, gerber.CircleShape, 0.2),
+			},
+			want:      []gerber.Pt{{5, 0}},
+			wantAngle: 30,
+		},
+		{
+			name: "tee",
+			primitives: []gerber.Primitive{
+				gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.2),
+				gerber.Line(5, 0, 5, 5, gerber.CircleShape, 0.2),
+			},
+		},
+		{
+			name: "traces meeting end to end",
+			primitives: []gerber.Primitive{
+				gerber.Line(0, 0, 5, 0, gerber.CircleShape, 0.2),
+				gerber.Line(5, 0, 0, 5*tan30, gerber.CircleShape, 0.2),
+				gerber.Line(5, 0, 10, 0, gerber.CircleShape, 0.2),
+			},
+			want:      []gerber.Pt{{5, 0}},
+			wantAngle: 30,
+		},
+		{
+			name: "notched region",
+			primitives: []gerber.Primitive{gerber.Polygon(gerber.Pt{}, true,
+				[]gerber.Pt{{0, 0}, {10, 0}, {10, 5}, {6, 5}, {5, 5 - notch}, {4, 5}, {0, 5}}, 0)},
+			want:      []gerber.Pt{{5, 5 - notch}},
+			wantAngle: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gerber.New("test")
+			top := g.TopCopper()
+			top.Add(tt.primitives...)
+			vs := Check(g, &Rules{AcidTrap: 90}, nil)
+			if len(vs) != len(tt.want) {
+				t.Fatalf("Check = %v, want %v violations", vs, len(tt.want))
+			}
+			for i, v := range vs {
+				if v.Kind != AcidTrap || v.Location != tt.want[i] || math.Abs(v.Actual-tt.wantAngle) > eps {
+					t.Errorf("violation %v = %v, want %v° at %v", i, v, tt.wantAngle, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+// Package drc checks Gerber designs against the design rules of PCB
+// manufacturing: the clearance between copper of different nets, the
+// minimum trace width, the annular ring around each drilled hole, the
+// distance from copper to the board outline, silkscreen over solder
+// mask openings, slivers of solder mask and acid traps.
+//
+// All dimensions are in millimeters.
+package drc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// Rules are the minimum dimensions allowed in a design.
+// Zero-valued rules are not checked. Curves are measured once they
+// are flattened, so gaps may fall short of the rules by up to
+// gerber.DefaultTolerance without being reported.
+type Rules struct {
+	// Clearance is the minimum gap between copper of different nets.
+	Clearance float64
+	// TraceWidth is the minimum width of stroked copper.
+	TraceWidth float64
+	// AnnularRing is the minimum width of the copper ring
+	// around each plated hole on every copper layer.
+	AnnularRing float64
+	// OutlineClearance is the minimum distance from copper
+	// to the board outline.
+	OutlineClearance float64
+	// Drill is the minimum diameter of a drilled hole.
+	Drill float64
+	// SilkClearance is the minimum distance from silkscreen to the
+	// solder mask openings on the same side of the board.
+	SilkClearance float64
+	// MaskSliver is the minimum width of solder mask
+	// between two openings.
+	MaskSliver float64
+	// AcidTrap is the smallest angle (in degrees, unlike the other
+	// rules) allowed where copper traces meet or turn.
+	AcidTrap float64
+}
+
+// ProfileRules returns the rules of a manufacturer profile.
+func ProfileRules(p *gerber.Profile) *Rules {
+	return &Rules{
+		Clearance:        p.MinSpace,
+		TraceWidth:       p.MinTrace,
+		AnnularRing:      p.MinAnnularRing,
+		OutlineClearance: p.MinEdgeClearance,
+		Drill:            p.MinDrill,
+	}
+}
+
+// Nets maps primitives (as added to their layers) to the names of
+// their nets. Primitives that are missing from the map are on nets of
+// their own. See Check.
+type Nets map[gerber.Primitive]string
+
+// Kind represents the rule broken by a violation.
+type Kind int
+
+const (
+	// Clearance is a gap between copper of different nets (or between
+	// the turns of a single trace) that is too small.
+	Clearance Kind = iota
+	// TraceWidth is a stroked copper trace that is too narrow.
+	TraceWidth
+	// AnnularRing is a copper ring around a plated hole that is too narrow.
+	AnnularRing
+	// OutlineClearance is copper that is too close to the board outline.
+	OutlineClearance
+	// DrillSize is a drilled hole that is too small.
+	DrillSize
+	// SilkClearance is silkscreen that overlaps (or is too close to)
+	// an opening in the solder mask, where it would not print.
+	SilkClearance
+	// MaskSliver is a bridge of solder mask between two openings
+	// that is too narrow to stay in place.
+	MaskSliver
+	// AcidTrap is an acute angle between copper traces, which traps
+	// etchant and overetches the copper.
+	AcidTrap
+)
+
+// String returns the name of the rule.
+func (k Kind) String() string {
+	switch k {
+	case Clearance:
+		return "clearance"
+	case TraceWidth:
+		return "trace width"
+	case AnnularRing:
+		return "annular ring"
+	case OutlineClearance:
+		return "outline clearance"
+	case DrillSize:
+		return "drill size"
+	case SilkClearance:
+		return "silkscreen clearance"
+	case MaskSliver:
+		return "mask sliver"
+	case AcidTrap:
+		return "acid trap"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Violation represents a broken design rule.
+type Violation struct {
+	Kind Kind
+	// Layer is the layer on which the rule is broken.
+	Layer *gerber.Layer
+	// Location is the point where the rule is broken the worst.
+	Location gerber.Pt
+	// Actual is the measured dimension and Required is the rule.
+	// Actual is zero or negative for features that touch or overlap.
+	// Both are angles in degrees for acid traps.
+	Actual, Required float64
+	// Primitives are the offending primitives (such as both of
+	// the primitives that are too close together).
+	Primitives []gerber.Primitive
+	// Nets are the names of the nets of the primitives, where known.
+	Nets []string
+}
+
+// String returns a one-line description of the violation.
+func (v Violation) String() string {
+	unit := "mm"
+	if v.Kind == AcidTrap {
+		unit = "°"
+	}
+	s := fmt.Sprintf("%v on %v at (%.3f,%.3f): %.3f%v < %.3f%v", v.Kind, v.Layer.Filename, v.Location[0], v.Location[1], v.Actual, unit, v.Required, unit)
+	if len(v.Nets) > 0 {
+		s += " (" + strings.Join(v.Nets, ", ") + ")"
+	}
+	return s
+}
+
+// item is a primitive on a layer along with its geometry.
+type item struct {
+	index    int // of the primitive in its layer
+	p        gerber.Primitive
+	net      string
+	features []*gerber.Feature
+	mbb      gerber.MBB
+}
+
+func newItems(layer *gerber.Layer, nets Nets) []*item {
+	var items []*item
+	for i, p := range layer.Primitives {
+		features := gerber.Features(p)
+		if len(features) == 0 {
+			continue
+		}
+		it := &item{index: i, p: p, net: nets[p], features: features, mbb: features[0].MBB()}
+		for _, f := range features[1:] {
+			mbb := f.MBB()
+			it.mbb.Join(&mbb)
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// indexed returns the items by the indices of their primitives.
+func indexed(items []*item) map[int]*item {
+	byIndex := make(map[int]*item, len(items))
+	for _, it := range items {
+		byIndex[it.index] = it
+	}
+	return byIndex
+}
+
+// near returns the items of the layer whose primitives' bounding boxes
+// intersect the window grown by margin, using the layer's spatial index.
+func near(layer *gerber.Layer, byIndex map[int]*item, window gerber.MBB, margin float64) []*item {
+	window = gerber.MBB{
+		Min: gerber.Pt{window.Min[0] - margin, window.Min[1] - margin},
+		Max: gerber.Pt{window.Max[0] + margin, window.Max[1] + margin},
+	}
+	var result []*item
+	for _, i := range layer.Search(window) {
+		if it, ok := byIndex[i]; ok {
+			result = append(result, it)
+		}
+	}
+	return result
+}
+
+// gap returns the gap between the two bounding boxes
+// (which is negative where they overlap).
+func gap(a, b gerber.MBB) float64 {
+	return math.Max(math.Max(b.Min[0]-a.Max[0], a.Min[0]-b.Max[0]), math.Max(b.Min[1]-a.Max[1], a.Min[1]-b.Max[1]))
+}
+
+// distance returns the smallest gap between the features of the items
+// and the midpoint of the nearest points between which it is measured.
+func distance(a, b *item) (float64, gerber.Pt) {
+	d, loc := math.Inf(1), gerber.Pt{}
+	for _, f := range a.features {
+		for _, g := range b.features {
+			if v, p, q := f.Distance(g); v < d {
+				d, loc = v, midpoint(p, q)
+			}
+		}
+	}
+	return d, loc
+}
+
+func midpoint(a, b gerber.Pt) gerber.Pt {
+	return gerber.Pt{0.5 * (a[0] + b[0]), 0.5 * (a[1] + b[1])}
+}
+
+// netNames returns the known nets of the items.
+func netNames(items ...*item) []string {
+	var names []string
+	for _, it := range items {
+		if it.net != "" {
+			names = append(names, it.net)
+		}
+	}
+	return names
+}
+
+// Check checks the design against the rules and returns the violations,
+// ordered by layer and then by kind.
+//
+// Copper of different nets must be at least the clearance apart. Copper
+// whose net is unknown (when nets is nil or is missing the primitive) is
+// checked against all other copper, except that copper which touches it
+// is taken to be connected to it. Stroked traces are also checked
+// against themselves, such as the adjacent turns of a spiral.
+//
+// The primitives of the solder mask layers are the openings in the
+// mask, which the silkscreen on the same side must stay clear of.
+// See ClipSilkscreen for fixing silkscreen violations.
+func Check(g *gerber.Gerber, rules *Rules, nets Nets) []Violation {
+	var copper, drills, outlines []*gerber.Layer
+	for _, layer := range g.Layers {
+		switch {
+		case layer.Role.IsCopper():
+			copper = append(copper, layer)
+		case layer.Role == gerber.DrillRole:
+			drills = append(drills, layer)
+		case layer.Role == gerber.OutlineRole:
+			outlines = append(outlines, layer)
+		}
+	}
+
+	var edges []*item
+	for _, layer := range outlines {
+		for _, it := range newItems(layer, nil) {
+			// The board edge is the centerline of the outline.
+			for i, f := range it.features {
+				if !f.IsRegion() {
+					it.features[i] = &gerber.Feature{Path: f.Path}
+				}
+			}
+			edges = append(edges, it)
+		}
+	}
+	var holes []*item
+	drillItems := map[*gerber.Layer][]*item{}
+	for _, layer := range drills {
+		drillItems[layer] = newItems(layer, nil)
+		holes = append(holes, drillItems[layer]...)
+	}
+
+	items := map[*gerber.Layer][]*item{}
+	for _, layer := range copper {
+		items[layer] = newItems(layer, nets)
+	}
+
+	// openings are the solder mask openings by the role of their layer.
+	openings := map[gerber.LayerRole][]*item{}
+	maskItems := map[*gerber.Layer][]*item{}
+	for _, layer := range g.Layers {
+		if layer.Role == gerber.TopSolderMaskRole || layer.Role == gerber.BottomSolderMaskRole {
+			maskItems[layer] = newItems(layer, nil)
+			openings[layer.Role] = append(openings[layer.Role], maskItems[layer]...)
+		}
+	}
+
+	var result []Violation
+	for _, layer := range g.Layers {
+		switch layer.Role {
+		case gerber.DrillRole:
+			if rules.Drill > 0 {
+				result = append(result, checkDrillSize(layer, drillItems[layer], rules.Drill)...)
+			}
+		case gerber.TopSilkscreenRole, gerber.BottomSilkscreenRole:
+			if rules.SilkClearance > 0 {
+				result = append(result, checkSilkscreen(layer, openings[maskRole(layer.Role)], rules.SilkClearance)...)
+			}
+		case gerber.TopSolderMaskRole, gerber.BottomSolderMaskRole:
+			if rules.MaskSliver > 0 {
+				result = append(result, checkMaskSlivers(layer, maskItems[layer], rules.MaskSliver)...)
+			}
+		}
+		if !layer.Role.IsCopper() {
+			continue
+		}
+		if rules.Clearance > 0 {
+			result = append(result, checkClearance(layer, items[layer], rules.Clearance)...)
+		}
+		if rules.TraceWidth > 0 {
+			result = append(result, checkTraceWidth(layer, items[layer], rules.TraceWidth)...)
+		}
+		if rules.AnnularRing > 0 {
+			result = append(result, checkAnnularRing(layer, items, holes, rules.AnnularRing)...)
+		}
+		if rules.OutlineClearance > 0 {
+			result = append(result, checkOutline(layer, items[layer], edges, rules.OutlineClearance)...)
+		}
+		if rules.AcidTrap > 0 {
+			result = append(result, checkAcidTraps(layer, items[layer], rules.AcidTrap)...)
+		}
+	}
+	return result
+}
+
+// checkClearance checks the gaps between the copper of different nets.
+func checkClearance(layer *gerber.Layer, items []*item, clearance float64) []Violation {
+	byIndex := indexed(items)
+	var result []Violation
+	for _, a := range items {
+		for _, b := range near(layer, byIndex, a.mbb, clearance) {
+			if b.index <= a.index {
+				continue
+			}
+			known := a.net != "" && b.net != ""
+			if (known && a.net == b.net) || gap(a.mbb, b.mbb) >= clearance {
+				continue
+			}
+			d, loc := distance(a, b)
+			if d >= clearance-gerber.DefaultTolerance || (!known && d <= 0) {
+				continue
+			}
+			result = append(result, Violation{
+				Kind:       Clearance,
+				Layer:      layer,
+				Location:   loc,
+				Actual:     d,
+				Required:   clearance,
+				Primitives: []gerber.Primitive{a.p, b.p},
+				Nets:       netNames(a, b),
+			})
+		}
+
+		// Parts of the same trace must be apart unless they
+		// are close together along it, such as at a corner.
+		for _, f := range a.features {
+			d, p, q := f.SelfDistance(math.Pi * (f.Width + clearance))
+			if d >= clearance-gerber.DefaultTolerance {
+				continue
+			}
+			result = append(result, Violation{
+				Kind:       Clearance,
+				Layer:      layer,
+				Location:   midpoint(p, q),
+				Actual:     d,
+				Required:   clearance,
+				Primitives: []gerber.Primitive{a.p},
+				Nets:       netNames(a),
+			})
+		}
+	}
+	return result
+}
+
+// checkTraceWidth checks the width of the stroked copper,
+// reporting the narrowest stroke of each primitive.
+func checkTraceWidth(layer *gerber.Layer, items []*item, minWidth float64) []Violation {
+	var result []Violation
+	for _, it := range items {
+		var worst *gerber.Feature
+		for _, f := range it.features {
+			// Flashes (such as pads) are not traces.
+			if f.IsRegion() || len(f.Path) < 2 || f.Width >= minWidth {
+				continue
+			}
+			if worst == nil || f.Width < worst.Width {
+				worst = f
+			}
+		}
+		if worst == nil {
+			continue
+		}
+		n := len(worst.Path) / 2
+		result = append(result, Violation{
+			Kind:       TraceWidth,
+			Layer:      layer,
+			Location:   midpoint(worst.Path[n-1], worst.Path[n]),
+			Actual:     worst.Width,
+			Required:   minWidth,
+			Primitives: []gerber.Primitive{it.p},
+			Nets:       netNames(it),
+		})
+	}
+	return result
+}
+
+// checkAnnularRing checks the copper around each plated hole, being
+// one whose center lies within copper on any layer. The ring is
+// measured from the edge of the hole to the nearest edge of the copper
+// pad on the layer that surrounds it best.
+func checkAnnularRing(layer *gerber.Layer, items map[*gerber.Layer][]*item, holes []*item, minRing float64) []Violation {
+	// ring returns the annular ring of the hole on the layer
+	// and the copper forming it, if any.
+	byIndex := map[*gerber.Layer]map[int]*item{}
+	for l, v := range items {
+		byIndex[l] = indexed(v)
+	}
+	ring := func(layer *gerber.Layer, center gerber.Pt, radius float64) (float64, *item) {
+		best, bestItem := math.Inf(-1), (*item)(nil)
+		for _, it := range near(layer, byIndex[layer], gerber.MBB{Min: center, Max: center}, 0) {
+			for _, f := range it.features {
+				if d := f.EdgeDistance(center); d > best {
+					best, bestItem = d, it
+				}
+			}
+		}
+		return best - radius, bestItem
+	}
+
+	var result []Violation
+	for _, hole := range holes {
+		for _, f := range hole.features {
+			// Only drilled (flashed) holes are checked, not routed slots.
+			if f.IsRegion() || len(f.Path) != 1 {
+				continue
+			}
+			center, radius := f.Path[0], 0.5*f.Width
+			plated := false
+			for l := range items {
+				if r, _ := ring(l, center, radius); r > -radius {
+					plated = true
+					break
+				}
+			}
+			if !plated {
+				continue
+			}
+			r, pad := ring(layer, center, radius)
+			if r >= minRing {
+				continue
+			}
+			v := Violation{
+				Kind:       AnnularRing,
+				Layer:      layer,
+				Location:   center,
+				Actual:     math.Max(r, -radius),
+				Required:   minRing,
+				Primitives: []gerber.Primitive{hole.p},
+			}
+			if pad != nil {
+				v.Primitives = append(v.Primitives, pad.p)
+				v.Nets = netNames(pad)
+			}
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// checkOutline checks the distance from each copper primitive
+// to the board outline.
+func checkOutline(layer *gerber.Layer, items, edges []*item, clearance float64) []Violation {
+	var result []Violation
+	for _, it := range items {
+		d, loc, edge := math.Inf(1), gerber.Pt{}, (*item)(nil)
+		for _, e := range edges {
+			// Boards are cut around their outline, so the outline's
+			// bounding box cannot be used to skip the check.
+			if v, p := distance(it, e); v < d {
+				d, loc, edge = v, p, e
+			}
+		}
+		if d >= clearance-gerber.DefaultTolerance {
+			continue
+		}
+		result = append(result, Violation{
+			Kind:       OutlineClearance,
+			Layer:      layer,
+			Location:   loc,
+			Actual:     d,
+			Required:   clearance,
+			Primitives: []gerber.Primitive{it.p, edge.p},
+			Nets:       netNames(it),
+		})
+	}
+	return result
+}
+
+// checkDrillSize checks the diameter of each drilled hole (or slot).
+func checkDrillSize(layer *gerber.Layer, holes []*item, minDrill float64) []Violation {
+	var result []Violation
+	for _, hole := range holes {
+		for _, f := range hole.features {
+			if f.IsRegion() || f.Width >= minDrill {
+				continue
+			}
+			result = append(result, Violation{
+				Kind:       DrillSize,
+				Layer:      layer,
+				Location:   f.Path[0],
+				Actual:     f.Width,
+				Required:   minDrill,
+				Primitives: []gerber.Primitive{hole.p},
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,200 @@
+package drc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/coil"
+)
+
+const eps = 1e-3
+
+func TestCheck_Coil(t *testing.T) {
+	// The turns of the windings are slightly closer together than Gap
+	// near the center of the coil, where the spirals are the steepest.
+	rules := &Rules{Clearance: 0.127, TraceWidth: 0.127, AnnularRing: 0.1, OutlineClearance: 0.5}
+
+	g, err := coil.New(&coil.Config{Turns: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vs := Check(g, rules, nil); len(vs) != 0 {
+		t.Errorf("Check = %v, want no violations", vs)
+	}
+
+	// A mistyped gap brings the turns of the two windings too close.
+	g, err = coil.New(&coil.Config{Turns: 3, Gap: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs := Check(g, rules, nil)
+	if len(vs) == 0 {
+		t.Fatal("Check = no violations, want clearance violations")
+	}
+	for _, v := range vs {
+		if v.Kind != Clearance {
+			t.Errorf("unexpected violation: %v", v)
+			continue
+		}
+		if math.Abs(v.Actual-0.05) > 2*gerber.DefaultTolerance {
+			t.Errorf("%v: Actual = %v, want 0.05", v, v.Actual)
+		}
+	}
+}
+
+func TestCheck_Clearance(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	a := gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.2)
+	b := gerber.Line(0, 0.3, 10, 0.3, gerber.CircleShape, 0.2)   // 0.1 away from a
+	c := gerber.Line(5, -1, 5, 1, gerber.CircleShape, 0.2)       // crosses a and b
+	d := gerber.Circle(gerber.Pt{20, 0}, 1)                      // far away
+	e := gerber.Spiral(gerber.Pt{20, 10}, 1, 0.25, 2, 0.2, 0, 0) // 0.05 between turns
+	top.Add(a, b, c, d, e)
+	rules := &Rules{Clearance: 0.15}
+
+	tests := []struct {
+		name string
+		nets Nets
+		want [][]gerber.Primitive
+	}{
+		{
+			name: "no nets",
+			want: [][]gerber.Primitive{{a, b}, {e}},
+		},
+		{
+			name: "different nets",
+			nets: Nets{a: "A", b: "B", c: "A"},
+			want: [][]gerber.Primitive{{a, b}, {b, c}, {e}},
+		},
+		{
+			name: "same nets",
+			nets: Nets{a: "A", b: "A", c: "A"},
+			want: [][]gerber.Primitive{{e}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vs := Check(g, rules, tt.nets)
+			if len(vs) != len(tt.want) {
+				t.Fatalf("Check = %v, want %v violations", vs, len(tt.want))
+			}
+			for i, v := range vs {
+				if v.Kind != Clearance || v.Layer != top {
+					t.Errorf("violation %v = %v, want clearance on top copper", i, v)
+				}
+				if len(v.Primitives) != len(tt.want[i]) {
+					t.Fatalf("violation %v primitives = %v, want %v", i, v.Primitives, tt.want[i])
+				}
+				for j, p := range v.Primitives {
+					if p != tt.want[i][j] {
+						t.Errorf("violation %v primitive %v = %v, want %v", i, j, p, tt.want[i][j])
+					}
+				}
+			}
+			if v := vs[0]; v.Primitives[0] == a && (math.Abs(v.Actual-0.1) > eps || math.Abs(v.Location[1]-0.15) > eps) {
+				t.Errorf("violation 0 = %v, want 0.1 at y=0.15", v)
+			}
+			if v := vs[len(vs)-1]; math.Abs(v.Actual-0.05) > gerber.DefaultTolerance+eps {
+				t.Errorf("spiral violation = %v, want 0.05", v)
+			}
+		})
+	}
+}
+
+func TestCheck_TraceWidth(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	narrow := gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.1)
+	top.Add(
+		narrow,
+		gerber.Line(0, 5, 10, 5, gerber.CircleShape, 0.2),
+		gerber.Circle(gerber.Pt{20, 0}, 0.1), // a flash is not a trace
+		gerber.Polygon(gerber.Pt{}, true, []gerber.Pt{{0, 10}, {0.05, 10}, {0.05, 20}}, 0),
+	)
+
+	vs := Check(g, &Rules{TraceWidth: 0.15}, nil)
+	if len(vs) != 1 {
+		t.Fatalf("Check = %v, want 1 violation", vs)
+	}
+	want := Violation{Kind: TraceWidth, Layer: top, Location: gerber.Pt{5, 0}, Actual: 0.1, Required: 0.15}
+	if v := vs[0]; v.Kind != want.Kind || v.Layer != want.Layer || v.Location != want.Location ||
+		v.Actual != want.Actual || v.Required != want.Required || v.Primitives[0] != narrow {
+		t.Errorf("violation = %v, want %v", v, want)
+	}
+}
+
+func TestCheck_AnnularRing(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	bottom := g.BottomCopper()
+	drill := g.Drill()
+
+	// A good via, a via with a small pad on the bottom,
+	// an off-center drill and a non-plated hole.
+	top.Add(gerber.Circle(gerber.Pt{0, 0}, 0.6), gerber.Circle(gerber.Pt{5, 0}, 0.6), gerber.Circle(gerber.Pt{10, 0}, 0.6))
+	bottom.Add(gerber.Circle(gerber.Pt{0, 0}, 0.6), gerber.Circle(gerber.Pt{5, 0}, 0.4), gerber.Circle(gerber.Pt{10, 0}, 0.6))
+	drill.Add(
+		gerber.Circle(gerber.Pt{0, 0}, 0.3),
+		gerber.Circle(gerber.Pt{5, 0}, 0.3),
+		gerber.Circle(gerber.Pt{10.1, 0}, 0.3),
+		gerber.Circle(gerber.Pt{20, 0}, 3),
+	)
+
+	vs := Check(g, &Rules{AnnularRing: 0.1}, nil)
+	want := []struct {
+		layer    *gerber.Layer
+		location gerber.Pt
+		actual   float64
+	}{
+		{layer: top, location: gerber.Pt{10.1, 0}, actual: 0.05},
+		{layer: bottom, location: gerber.Pt{5, 0}, actual: 0.05},
+		{layer: bottom, location: gerber.Pt{10.1, 0}, actual: 0.05},
+	}
+	if len(vs) != len(want) {
+		t.Fatalf("Check = %v, want %v violations", vs, len(want))
+	}
+	for i, v := range vs {
+		if v.Kind != AnnularRing || v.Layer != want[i].layer || v.Location != want[i].location || math.Abs(v.Actual-want[i].actual) > eps {
+			t.Errorf("violation %v = %v, want %v", i, v, want[i])
+		}
+	}
+}
+
+func TestCheck_OutlineClearance(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	g.Outline().Add(gerber.Polygon(gerber.Pt{}, false, []gerber.Pt{{0, 0}, {20, 0}, {20, 20}, {0, 20}}, 0.1))
+	near := gerber.Line(1, 0.2, 1, 10, gerber.CircleShape, 0.2)
+	top.Add(near, gerber.Circle(gerber.Pt{10, 10}, 2))
+
+	vs := Check(g, &Rules{OutlineClearance: 0.3}, nil)
+	if len(vs) != 1 {
+		t.Fatalf("Check = %v, want 1 violation", vs)
+	}
+	if v := vs[0]; v.Kind != OutlineClearance || v.Primitives[0] != near || math.Abs(v.Actual-0.1) > eps ||
+		math.Abs(v.Location[0]-1) > eps || math.Abs(v.Location[1]-0.05) > eps {
+		t.Errorf("violation = %v, want 0.1 at (1,0.05)", v)
+	}
+}
+
+func TestCheck_DrillSize(t *testing.T) {
+	p, err := gerber.LookupProfile("jlcpcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := gerber.New("test")
+	small := gerber.Circle(gerber.Pt{5, 0}, 0.2)
+	g.Drill().Add(gerber.Circle(gerber.Pt{0, 0}, 0.4), small)
+
+	vs := Check(g, ProfileRules(p), nil)
+	if len(vs) != 1 {
+		t.Fatalf("Check = %v, want 1 violation", vs)
+	}
+	if v := vs[0]; v.Kind != DrillSize || v.Primitives[0] != small || v.Location != (gerber.Pt{5, 0}) ||
+		math.Abs(v.Actual-0.2) > eps || v.Required != p.MinDrill {
+		t.Errorf("violation = %v, want a 0.2 drill at (5,0)", v)
+	}
+}
@@ -0,0 +1,161 @@
+package drc
+
+import (
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// maskRole returns the role of the solder mask layer
+// on the same side of the board as the silkscreen role.
+func maskRole(silk gerber.LayerRole) gerber.LayerRole {
+	if silk == gerber.BottomSilkscreenRole {
+		return gerber.BottomSolderMaskRole
+	}
+	return gerber.TopSolderMaskRole
+}
+
+// clearArea is a feature of a ClearT primitive in a layer.
+type clearArea struct {
+	index int // of the primitive in its layer
+	f     *gerber.Feature
+}
+
+// clearAreas returns the areas of the layer that are cleared
+// by ClearT primitives.
+func clearAreas(layer *gerber.Layer) []clearArea {
+	var result []clearArea
+	for i, p := range layer.Primitives {
+		c, ok := p.(*gerber.ClearT)
+		if !ok {
+			continue
+		}
+		for _, f := range gerber.Features(c.Primitive) {
+			result = append(result, clearArea{index: i, f: f})
+		}
+	}
+	return result
+}
+
+// checkSilkscreen checks the distance from the silkscreen to the
+// solder mask openings. Silkscreen that is cleared (by a later ClearT
+// primitive) where it comes closest to an opening is not reported.
+func checkSilkscreen(layer *gerber.Layer, openings []*item, clearance float64) []Violation {
+	clears := clearAreas(layer)
+	cleared := func(it *item, pt gerber.Pt) bool {
+		for _, c := range clears {
+			if c.index > it.index && c.f.Contains(pt) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []Violation
+	for _, it := range newItems(layer, nil) {
+		for _, op := range openings {
+			if gap(it.mbb, op.mbb) >= clearance {
+				continue
+			}
+			d, loc := distance(it, op)
+			if d >= clearance-gerber.DefaultTolerance || cleared(it, loc) {
+				continue
+			}
+			result = append(result, Violation{
+				Kind:       SilkClearance,
+				Layer:      layer,
+				Location:   loc,
+				Actual:     d,
+				Required:   clearance,
+				Primitives: []gerber.Primitive{it.p, op.p},
+			})
+		}
+	}
+	return result
+}
+
+// checkMaskSlivers checks the width of the solder mask between
+// openings. Openings that touch or overlap leave no mask between them.
+func checkMaskSlivers(layer *gerber.Layer, openings []*item, minWidth float64) []Violation {
+	byIndex := indexed(openings)
+	var result []Violation
+	for _, a := range openings {
+		for _, b := range near(layer, byIndex, a.mbb, minWidth) {
+			if b.index <= a.index || gap(a.mbb, b.mbb) >= minWidth {
+				continue
+			}
+			d, loc := distance(a, b)
+			if d <= 0 || d >= minWidth-gerber.DefaultTolerance {
+				continue
+			}
+			result = append(result, Violation{
+				Kind:       MaskSliver,
+				Layer:      layer,
+				Location:   loc,
+				Actual:     d,
+				Required:   minWidth,
+				Primitives: []gerber.Primitive{a.p, b.p},
+			})
+		}
+	}
+	return result
+}
+
+// ClipSilkscreen clears the silkscreen around each solder mask opening
+// that the silkscreen on the same side of the board comes within the
+// clearance of, by adding ClearT primitives (of the opening enlarged by
+// the clearance) to the silkscreen layers. It returns the number of
+// openings that the silkscreen was cleared around.
+func ClipSilkscreen(g *gerber.Gerber, clearance float64) int {
+	openings := map[gerber.LayerRole][]*item{}
+	for _, layer := range g.Layers {
+		if layer.Role == gerber.TopSolderMaskRole || layer.Role == gerber.BottomSolderMaskRole {
+			openings[layer.Role] = append(openings[layer.Role], newItems(layer, nil)...)
+		}
+	}
+
+	var n int
+	for _, layer := range g.Layers {
+		if layer.Role != gerber.TopSilkscreenRole && layer.Role != gerber.BottomSilkscreenRole {
+			continue
+		}
+		silk := newItems(layer, nil)
+		var clears []gerber.Primitive
+		for _, op := range openings[maskRole(layer.Role)] {
+			within := false
+			for _, it := range silk {
+				if gap(it.mbb, op.mbb) >= clearance {
+					continue
+				}
+				if d, _ := distance(it, op); d < clearance {
+					within = true
+					break
+				}
+			}
+			if !within {
+				continue
+			}
+			for _, f := range op.features {
+				clears = append(clears, enlarge(f, clearance)...)
+			}
+			n++
+		}
+		for _, p := range clears {
+			layer.Add(gerber.Clear(p))
+		}
+	}
+	return n
+}
+
+// enlarge returns primitives covering the feature grown by d
+// on all sides. The holes of regions are covered too.
+func enlarge(f *gerber.Feature, d float64) []gerber.Primitive {
+	switch {
+	case f.IsRegion():
+		return []gerber.Primitive{
+			gerber.Polygon(gerber.Pt{}, true, f.Region, 0),
+			gerber.Polygon(gerber.Pt{}, false, f.Region, 2*d),
+		}
+	case len(f.Path) == 1:
+		return []gerber.Primitive{gerber.Circle(f.Path[0], f.Width+2*d)}
+	}
+	return []gerber.Primitive{gerber.Trace(f.Path, gerber.CircleShape, f.Width+2*d)}
+}
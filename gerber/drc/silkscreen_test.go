@@ -0,0 +1,64 @@
+package drc
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+func TestCheck_SilkClearance(t *testing.T) {
+	g := gerber.New("test")
+	g.TopSolderMask().Add(gerber.Circle(gerber.Pt{0, 0}, 1), gerber.Circle(gerber.Pt{10, 0}, 1))
+	silk := g.TopSilkscreen()
+	near := gerber.Line(-2, 0.7, 2, 0.7, gerber.CircleShape, 0.15) // 0.125 from the first pad
+	silk.Add(near, gerber.Line(8, 2, 12, 2, gerber.CircleShape, 0.15))
+	// The bottom silkscreen is not over the top mask.
+	g.BottomSilkscreen().Add(gerber.Line(-2, 0, 2, 0, gerber.CircleShape, 0.15))
+	rules := &Rules{SilkClearance: 0.2}
+
+	vs := Check(g, rules, nil)
+	if len(vs) != 1 {
+		t.Fatalf("Check = %v, want 1 violation", vs)
+	}
+	if v := vs[0]; v.Kind != SilkClearance || v.Layer != silk || v.Primitives[0] != near || math.Abs(v.Actual-0.125) > eps {
+		t.Errorf("violation = %v, want 0.125 on the top silkscreen", v)
+	}
+
+	if n := ClipSilkscreen(g, 0.2); n != 1 {
+		t.Errorf("ClipSilkscreen = %v, want 1", n)
+	}
+	if vs := Check(g, rules, nil); len(vs) != 0 {
+		t.Errorf("Check after ClipSilkscreen = %v, want no violations", vs)
+	}
+	var buf bytes.Buffer
+	if err := silk.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "%LPC*%"); got != 1 {
+		t.Errorf("silkscreen has %v clear areas, want 1", got)
+	}
+}
+
+func TestCheck_MaskSliver(t *testing.T) {
+	g := gerber.New("test")
+	mask := g.TopSolderMask()
+	a := gerber.Circle(gerber.Pt{0, 0}, 1)
+	b := gerber.Circle(gerber.Pt{1.05, 0}, 1) // a 0.05 sliver from a
+	mask.Add(a, b,
+		gerber.Circle(gerber.Pt{0, 5}, 1),
+		gerber.Circle(gerber.Pt{0.5, 5}, 1), // overlapping
+		gerber.Circle(gerber.Pt{10, 0}, 1),
+	)
+
+	vs := Check(g, &Rules{MaskSliver: 0.1}, nil)
+	if len(vs) != 1 {
+		t.Fatalf("Check = %v, want 1 violation", vs)
+	}
+	if v := vs[0]; v.Kind != MaskSliver || v.Layer != mask || v.Primitives[0] != a || v.Primitives[1] != b ||
+		math.Abs(v.Actual-0.05) > eps || math.Abs(v.Location[0]-0.525) > eps {
+		t.Errorf("violation = %v, want 0.05 at (0.525,0)", v)
+	}
+}
@@ -0,0 +1,100 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// drillHit is a drilled hole (or, if slot is set, a routed slot
+// from pt to end) of a single tool.
+type drillHit struct {
+	pt, end Pt
+	slot    bool
+}
+
+// WriteExcellon writes a drill layer as an Excellon drill file in
+// millimeters, with the number of decimal digits required by the
+// design's profile. Circles are drilled holes and lines are slots.
+func (l *Layer) WriteExcellon(w io.Writer) error {
+	decimals := 3
+	if l.g != nil && l.g.profile != nil && l.g.profile.ExcellonDecimals > 0 {
+		decimals = l.g.profile.ExcellonDecimals
+	}
+
+	hits, err := l.drillHits()
+	if err != nil {
+		return err
+	}
+	var diameters []float64
+	for d := range hits {
+		diameters = append(diameters, d)
+	}
+	sort.Float64s(diameters)
+	// Diameters that are written alike are drilled by the same tool.
+	var tools []string
+	toolHits := map[string][]drillHit{}
+	for _, d := range diameters {
+		tool := fmt.Sprintf("%.3f", d)
+		if _, ok := toolHits[tool]; !ok {
+			tools = append(tools, tool)
+		}
+		toolHits[tool] = append(toolHits[tool], hits[d]...)
+	}
+
+	coord := func(pt Pt) string {
+		return fmt.Sprintf("X%.*fY%.*f", decimals, pt[0], decimals, pt[1])
+	}
+	io.WriteString(w, "M48\n")
+	io.WriteString(w, "METRIC\n")
+	for i, tool := range tools {
+		fmt.Fprintf(w, "T%vC%v\n", i+1, tool)
+	}
+	io.WriteString(w, "%\n")
+	io.WriteString(w, "G90\n")
+	io.WriteString(w, "G05\n")
+	for i, tool := range tools {
+		fmt.Fprintf(w, "T%v\n", i+1)
+		for _, h := range toolHits[tool] {
+			if h.slot {
+				fmt.Fprintf(w, "%vG85%v\n", coord(h.pt), coord(h.end))
+				continue
+			}
+			fmt.Fprintf(w, "%v\n", coord(h.pt))
+		}
+	}
+	_, err = io.WriteString(w, "M30\n")
+	return err
+}
+
+// drillHits returns the holes and slots of a drill layer, keyed by
+// tool diameter, along with an error for the first of any primitives
+// that cannot be drilled.
+func (l *Layer) drillHits() (map[float64][]drillHit, error) {
+	hits := map[float64][]drillHit{}
+	var add func(p Primitive) error
+	add = func(p Primitive) error {
+		switch v := p.(type) {
+		case *CircleT:
+			hits[v.thickness] = append(hits[v.thickness], drillHit{pt: v.pt})
+		case *LineT:
+			hits[v.Thickness] = append(hits[v.Thickness], drillHit{pt: v.P1, end: v.P2, slot: true})
+		case Compound:
+			for _, c := range v.Primitives() {
+				if err := add(c); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("%T cannot be written to an Excellon drill file", p)
+		}
+		return nil
+	}
+	var err error
+	for _, p := range l.Primitives {
+		if e := add(p); e != nil && err == nil {
+			err = e
+		}
+	}
+	return hits, err
+}
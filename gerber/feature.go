@@ -0,0 +1,426 @@
+package gerber
+
+import (
+	"log"
+	"math"
+)
+
+// Feature is the flattened geometry of (part of) a primitive as it is
+// drawn on its layer: either a Path stroked with a circular aperture of
+// the given Width (a single point for a flash), or a filled Region with
+// Holes. Features are used for design rule checks and other analyses.
+type Feature struct {
+	Path  []Pt
+	Width float64
+
+	Region []Pt
+	Holes  [][]Pt
+
+	segs  []featureSeg // cached edges
+	index *rtree       // cached spatial index of the edges
+	mbb   *MBB         // cached minimum bounding box
+}
+
+// minEdgeIndex is the number of edges from which a feature indexes
+// them to find the distances to them.
+const minEdgeIndex = 32
+
+// featureSeg is a stroked segment (or a region edge, with r == 0).
+type featureSeg struct {
+	p1, p2 Pt
+	r      float64
+	mbb    MBB
+}
+
+// Features returns the geometry of the primitive with its curves
+// flattened. Compound primitives return the features of all of their
+// primitives. Rectangular apertures are approximated by circular ones,
+// and clear areas (such as the box of a Cleared label or a ClearT)
+// are ignored.
+// Unknown primitives are represented by their bounding boxes.
+func Features(p Primitive) []*Feature {
+	stroke := func(pts []Pt, width float64) []*Feature {
+		if len(pts) == 0 {
+			return nil
+		}
+		return []*Feature{{Path: pts, Width: width}}
+	}
+	region := func(pts []Pt, holes [][]Pt) []*Feature {
+		if len(pts) < 3 {
+			return nil
+		}
+		return []*Feature{{Region: openContour(pts), Holes: holes}}
+	}
+	offset := func(pts []Pt, offset Pt) []Pt {
+		result := make([]Pt, len(pts))
+		for i, pt := range pts {
+			result[i] = Pt{pt[0] + offset[0], pt[1] + offset[1]}
+		}
+		return result
+	}
+
+	switch v := p.(type) {
+	case *CircleT:
+		return stroke([]Pt{v.pt}, v.thickness)
+	case *LineT:
+		return stroke([]Pt{v.P1, v.P2}, v.Thickness)
+	case *ArcT:
+		return stroke(v.Flatten(), v.Thickness)
+	case *TraceT:
+		return stroke(v.Flatten(), v.Thickness)
+	case *BezierT:
+		if v.Filled {
+			return region(v.Flatten(), nil)
+		}
+		return stroke(v.Flatten(), v.Thickness)
+	case *SpiralT:
+		width := v.Width
+		if v.xf != nil {
+			width *= v.xf.ScaleFactor()
+		}
+		return stroke(v.Centerline(), width)
+	case *PolygonT:
+		var holes [][]Pt
+		for _, hole := range v.Holes {
+			holes = append(holes, openContour(offset(hole, v.Offset)))
+		}
+		if v.Filled {
+			return region(offset(v.Points, v.Offset), holes)
+		}
+		var result []*Feature
+		for _, c := range append([][]Pt{offset(v.Points, v.Offset)}, holes...) {
+			if len(c) > 0 && c[0] != c[len(c)-1] {
+				c = append(c, c[0])
+			}
+			result = append(result, stroke(c, v.Thickness)...)
+		}
+		return result
+	case *TextT:
+		return v.features()
+	case *LabelT:
+		switch v.Style {
+		case Knockout:
+			// The dark glyphs are holes in the box
+			// and their counters are islands within them.
+			box := v.Box() // renders the text
+			var holes [][]Pt
+			var islands []*Feature
+			for _, poly := range v.Text.Render.Polygons {
+				if poly.Dark {
+					holes = append(holes, openContour(poly.Pts))
+					continue
+				}
+				islands = append(islands, region(poly.Pts, nil)...)
+			}
+			return append(region(box, holes), islands...)
+		case Framed:
+			box := v.Box()
+			return append(v.Text.features(), stroke(append(box, box[0]), v.Thickness)...)
+		}
+		return v.Text.features()
+	case *MatrixCodeT:
+		var result []*Feature
+		for _, r := range v.Regions() {
+			result = append(result, region(r, nil)...)
+		}
+		return result
+	case *BarcodeT:
+		var result []*Feature
+		for _, r := range v.Regions() {
+			result = append(result, region(r, nil)...)
+		}
+		if v.Text != nil {
+			result = append(result, v.Text.features()...)
+		}
+		return result
+	case *ClearT:
+		return nil
+	case Compound:
+		var result []*Feature
+		for _, c := range v.Primitives() {
+			result = append(result, Features(c)...)
+		}
+		return result
+	}
+
+	mbb := p.MBB()
+	return region([]Pt{mbb.Min, {mbb.Max[0], mbb.Min[1]}, mbb.Max, {mbb.Min[0], mbb.Max[1]}}, nil)
+}
+
+// features returns the dark glyph polygons as regions,
+// each with the counters that it surrounds as holes.
+func (t *TextT) features() []*Feature {
+	if err := t.renderText(); err != nil {
+		log.Fatal(err)
+	}
+	polys := t.Render.Polygons
+	owner := map[int]*Feature{}
+	var result []*Feature
+	for i, poly := range polys {
+		if poly.Dark && len(poly.Pts) >= 3 {
+			f := &Feature{Region: openContour(poly.Pts)}
+			owner[i] = f
+			result = append(result, f)
+		}
+	}
+	for _, poly := range polys {
+		if poly.Dark || len(poly.Pts) == 0 {
+			continue
+		}
+		var best *Feature
+		bestArea := math.Inf(1)
+		for j, f := range owner {
+			if !pointInContour(poly.Pts[0], f.Region) {
+				continue
+			}
+			if area := math.Abs(signedArea(polys[j].Pts)); area < bestArea {
+				best, bestArea = f, area
+			}
+		}
+		if best != nil {
+			best.Holes = append(best.Holes, openContour(poly.Pts))
+		}
+	}
+	return result
+}
+
+// IsRegion reports whether the feature is a filled region
+// rather than a stroked path.
+func (f *Feature) IsRegion() bool {
+	return len(f.Region) > 0
+}
+
+// edges returns the stroked segments or region edges of the feature.
+func (f *Feature) edges() []featureSeg {
+	if f.segs != nil {
+		return f.segs
+	}
+	add := func(p1, p2 Pt, r float64) {
+		mbb := MBB{Min: Pt{math.Min(p1[0], p2[0]) - r, math.Min(p1[1], p2[1]) - r}, Max: Pt{math.Max(p1[0], p2[0]) + r, math.Max(p1[1], p2[1]) + r}}
+		f.segs = append(f.segs, featureSeg{p1: p1, p2: p2, r: r, mbb: mbb})
+	}
+	if f.IsRegion() {
+		for _, c := range append([][]Pt{f.Region}, f.Holes...) {
+			for i, pt := range c {
+				add(pt, c[(i+1)%len(c)], 0)
+			}
+		}
+		return f.segs
+	}
+	r := 0.5 * f.Width
+	if len(f.Path) == 1 {
+		add(f.Path[0], f.Path[0], r)
+	}
+	for i := 1; i < len(f.Path); i++ {
+		add(f.Path[i-1], f.Path[i], r)
+	}
+	return f.segs
+}
+
+// nearEdges returns the indices of the edges of the feature whose
+// bounding boxes may be within d of the box, in ascending order.
+func (f *Feature) nearEdges(mbb MBB, d float64) []int {
+	segs := f.edges()
+	if len(segs) < minEdgeIndex {
+		result := make([]int, len(segs))
+		for i := range result {
+			result[i] = i
+		}
+		return result
+	}
+	return f.edgeIndex().search(MBB{Min: Pt{mbb.Min[0] - d, mbb.Min[1] - d}, Max: Pt{mbb.Max[0] + d, mbb.Max[1] + d}})
+}
+
+// edgeIndex returns the spatial index of the edges of the feature.
+func (f *Feature) edgeIndex() *rtree {
+	if f.index == nil {
+		f.index = newRTree()
+		for i, s := range f.edges() {
+			f.index.insert(i, s.mbb)
+		}
+	}
+	return f.index
+}
+
+// closer updates the distance d and nearest points a and b if the
+// segments s and t are closer than d.
+func closer(s, t featureSeg, d *float64, a, b *Pt) {
+	// Skip the pairs that cannot be any closer.
+	gap := math.Max(math.Max(t.mbb.Min[0]-s.mbb.Max[0], s.mbb.Min[0]-t.mbb.Max[0]),
+		math.Max(t.mbb.Min[1]-s.mbb.Max[1], s.mbb.Min[1]-t.mbb.Max[1]))
+	if gap >= *d {
+		return
+	}
+	v, p, q := segmentDistance(s.p1, s.p2, t.p1, t.p2)
+	if v -= s.r + t.r; v < *d {
+		*d, *a, *b = v, towards(p, q, s.r), towards(q, p, t.r)
+	}
+}
+
+// MBB returns the minimum bounding box of the feature.
+func (f *Feature) MBB() MBB {
+	if f.mbb != nil {
+		return *f.mbb
+	}
+	for i, s := range f.edges() {
+		if i == 0 {
+			v := s.mbb
+			f.mbb = &v
+			continue
+		}
+		f.mbb.Join(&s.mbb)
+	}
+	if f.mbb == nil {
+		f.mbb = &MBB{}
+	}
+	return *f.mbb
+}
+
+// Contains reports whether the point lies within the feature.
+func (f *Feature) Contains(pt Pt) bool {
+	return f.EdgeDistance(pt) >= 0
+}
+
+// EdgeDistance returns the distance from the point to the nearest edge
+// of the feature: positive when the point lies within the feature and
+// negative when it lies outside.
+func (f *Feature) EdgeDistance(pt Pt) float64 {
+	d := math.Inf(1)
+	for _, s := range f.edges() {
+		v, _ := pointSegmentDistance(pt, s.p1, s.p2)
+		d = math.Min(d, v-s.r)
+	}
+	if !f.IsRegion() {
+		return -d
+	}
+	inside := pointInContour(pt, f.Region)
+	for _, hole := range f.Holes {
+		if pointInContour(pt, hole) {
+			inside = false
+		}
+	}
+	if inside {
+		return d
+	}
+	return -d
+}
+
+// Distance returns the gap between the edges of the two features, which
+// is zero or negative where they touch or overlap, along with the
+// nearest points a (on f) and b (on g) between which it is measured.
+func (f *Feature) Distance(g *Feature) (d float64, a, b Pt) {
+	d = math.Inf(1)
+	edges := g.edges()
+	for _, s := range f.edges() {
+		// Start from the nearest edge, so that few others are near enough.
+		if math.IsInf(d, 1) && len(edges) >= minEdgeIndex {
+			for _, j := range g.edgeIndex().nearest(s.p1, 1) {
+				closer(s, edges[j], &d, &a, &b)
+			}
+		}
+		for _, j := range g.nearEdges(s.mbb, d) {
+			closer(s, edges[j], &d, &a, &b)
+		}
+	}
+	if d <= 0 {
+		return d, a, b
+	}
+	// Either feature may lie entirely within a region of the other.
+	if pt := f.anyPoint(); g.IsRegion() && g.Contains(pt) {
+		return 0, pt, pt
+	}
+	if pt := g.anyPoint(); f.IsRegion() && f.Contains(pt) {
+		return 0, pt, pt
+	}
+	return d, a, b
+}
+
+// SelfDistance returns the smallest gap between the parts of a stroked
+// path (such as adjacent turns of a spiral) that are more than span
+// apart along the path, along with the nearest points between which
+// it is measured. It returns +Inf if there are no such parts.
+func (f *Feature) SelfDistance(span float64) (d float64, a, b Pt) {
+	d = math.Inf(1)
+	if f.IsRegion() {
+		return d, a, b
+	}
+	segs := f.edges()
+	// along[i] is the length of the path up to the start of segment i.
+	along := make([]float64, len(segs)+1)
+	for i, s := range segs {
+		along[i+1] = along[i] + math.Hypot(s.p2[0]-s.p1[0], s.p2[1]-s.p1[1])
+	}
+	closed := len(f.Path) > 2 && f.Path[0] == f.Path[len(f.Path)-1]
+	for i, s := range segs {
+		for _, j := range f.nearEdges(s.mbb, d) {
+			if j < i+2 {
+				continue
+			}
+			between := along[j] - along[i+1]
+			if closed {
+				between = math.Min(between, along[len(segs)]-along[j+1]+along[i])
+			}
+			if between <= span {
+				continue
+			}
+			closer(s, segs[j], &d, &a, &b)
+		}
+	}
+	return d, a, b
+}
+
+// anyPoint returns a point within the feature.
+func (f *Feature) anyPoint() Pt {
+	if f.IsRegion() {
+		return f.Region[0]
+	}
+	return f.Path[0]
+}
+
+// towards returns the point at distance d from p towards q.
+func towards(p, q Pt, d float64) Pt {
+	l := math.Hypot(q[0]-p[0], q[1]-p[1])
+	if l == 0 || d == 0 {
+		return p
+	}
+	return Pt{p[0] + d*(q[0]-p[0])/l, p[1] + d*(q[1]-p[1])/l}
+}
+
+// pointSegmentDistance returns the distance from pt to the segment
+// p1-p2 and the nearest point on the segment.
+func pointSegmentDistance(pt, p1, p2 Pt) (float64, Pt) {
+	dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+	var t float64
+	if l2 := dx*dx + dy*dy; l2 > 0 {
+		t = math.Max(0, math.Min(1, ((pt[0]-p1[0])*dx+(pt[1]-p1[1])*dy)/l2))
+	}
+	q := Pt{p1[0] + t*dx, p1[1] + t*dy}
+	return math.Hypot(pt[0]-q[0], pt[1]-q[1]), q
+}
+
+// segmentDistance returns the distance between the segments p1-p2 and
+// q1-q2 and the nearest points a (on p1-p2) and b (on q1-q2).
+func segmentDistance(p1, p2, q1, q2 Pt) (d float64, a, b Pt) {
+	orient := func(a, b, c Pt) float64 {
+		return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	}
+	d1, d2 := orient(q1, q2, p1), orient(q1, q2, p2)
+	d3, d4 := orient(p1, p2, q1), orient(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		t := d1 / (d1 - d2)
+		pt := Pt{p1[0] + t*(p2[0]-p1[0]), p1[1] + t*(p2[1]-p1[1])}
+		return 0, pt, pt
+	}
+	d, b = pointSegmentDistance(p1, q1, q2)
+	a = p1
+	if v, q := pointSegmentDistance(p2, q1, q2); v < d {
+		d, a, b = v, p2, q
+	}
+	if v, p := pointSegmentDistance(q1, p1, p2); v < d {
+		d, a, b = v, p, q1
+	}
+	if v, p := pointSegmentDistance(q2, p1, p2); v < d {
+		d, a, b = v, p, q2
+	}
+	return d, a, b
+}
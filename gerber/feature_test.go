@@ -0,0 +1,171 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	square := []Pt{{0, 0}, {4, 0}, {4, 4}, {0, 4}}
+	hole := []Pt{{1, 1}, {1, 3}, {3, 3}, {3, 1}}
+	tests := []struct {
+		name    string
+		p       Primitive
+		want    int
+		regions int
+	}{
+		{name: "circle", p: Circle(Pt{1, 2}, 0.5), want: 1},
+		{name: "line", p: Line(0, 0, 1, 1, CircleShape, 0.2), want: 1},
+		{name: "filled polygon with hole", p: PolygonWithHoles(Pt{}, square, [][]Pt{hole}), want: 1, regions: 1},
+		{name: "outlined polygon with hole", p: &PolygonT{Points: square, Thickness: 0.1, Holes: [][]Pt{hole}}, want: 2},
+		{name: "group", p: &ImageT{Polygons: []*PolygonT{Polygon(Pt{}, true, square, 0), Polygon(Pt{10, 0}, true, square, 0)}}, want: 2, regions: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Features(tt.p)
+			if len(got) != tt.want {
+				t.Fatalf("len(Features) = %v, want %v", len(got), tt.want)
+			}
+			var regions int
+			for _, f := range got {
+				if f.IsRegion() {
+					regions++
+				}
+			}
+			if regions != tt.regions {
+				t.Errorf("got %v regions, want %v", regions, tt.regions)
+			}
+		})
+	}
+}
+
+func TestFeature_EdgeDistance(t *testing.T) {
+	const eps = 1e-9
+	ring := Features(PolygonWithHoles(Pt{}, []Pt{{0, 0}, {4, 0}, {4, 4}, {0, 4}}, [][]Pt{{{1, 1}, {1, 3}, {3, 3}, {3, 1}}}))[0]
+	pad := Features(Circle(Pt{0, 0}, 2))[0]
+	tests := []struct {
+		name string
+		f    *Feature
+		pt   Pt
+		want float64
+	}{
+		{name: "within ring", f: ring, pt: Pt{0.5, 2}, want: 0.5},
+		{name: "in hole", f: ring, pt: Pt{2, 2}, want: -1},
+		{name: "outside ring", f: ring, pt: Pt{6, 2}, want: -2},
+		{name: "pad center", f: pad, pt: Pt{0, 0}, want: 1},
+		{name: "outside pad", f: pad, pt: Pt{3, 0}, want: -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.EdgeDistance(tt.pt); math.Abs(got-tt.want) > eps {
+				t.Errorf("EdgeDistance(%v) = %v, want %v", tt.pt, got, tt.want)
+			}
+			if got, want := tt.f.Contains(tt.pt), tt.want >= 0; got != want {
+				t.Errorf("Contains(%v) = %v, want %v", tt.pt, got, want)
+			}
+		})
+	}
+}
+
+func TestFeature_Distance(t *testing.T) {
+	const eps = 1e-9
+	region := Features(Polygon(Pt{}, true, []Pt{{0, 0}, {10, 0}, {10, 10}, {0, 10}}, 0))[0]
+	tests := []struct {
+		name string
+		p    Primitive
+		want float64
+		a, b Pt
+	}{
+		{name: "line beside region", p: Line(12, 2, 12, 8, CircleShape, 1), want: 1.5, a: Pt{10, 2}, b: Pt{11.5, 2}},
+		{name: "pad touching region", p: Circle(Pt{11, 5}, 2), want: 0},
+		{name: "line crossing region edge", p: Line(5, 5, 15, 5, CircleShape, 1), want: -0.5},
+		{name: "pad inside region", p: Circle(Pt{5, 5}, 1), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Features(tt.p)[0]
+			d, a, b := region.Distance(f)
+			if math.Abs(d-tt.want) > eps {
+				t.Errorf("Distance = %v, want %v", d, tt.want)
+			}
+			if tt.want > 0 && (math.Abs(a[0]-tt.a[0]) > eps || math.Abs(a[1]-tt.a[1]) > eps) {
+				t.Errorf("a = %v, want %v", a, tt.a)
+			}
+			if tt.want > 0 && (math.Abs(b[0]-tt.b[0]) > eps || math.Abs(b[1]-tt.b[1]) > eps) {
+				t.Errorf("b = %v, want %v", b, tt.b)
+			}
+			if d2, _, _ := f.Distance(region); math.Abs(d2-d) > eps {
+				t.Errorf("reversed Distance = %v, want %v", d2, d)
+			}
+		})
+	}
+}
+
+func TestFeature_SelfDistance(t *testing.T) {
+	const eps = 1e-9
+	// The turns of the spiral are 0.3 apart, leaving gaps of 0.1.
+	s := Spiral(Pt{}, 2, 0.3, 3, 0.2, 0, CounterClockwise)
+	f := Features(s)[0]
+	d, a, b := f.SelfDistance(1)
+	if math.Abs(d-0.1) > 2*DefaultTolerance {
+		t.Errorf("SelfDistance = %v, want 0.1", d)
+	}
+	if gap := math.Hypot(b[0]-a[0], b[1]-a[1]); math.Abs(gap-d) > eps {
+		t.Errorf("nearest points %v and %v are %v apart, want %v", a, b, gap, d)
+	}
+
+	// Along a straight trace, the gap is never less than the span.
+	straight := Features(Trace([]Pt{{0, 0}, {1, 0}, {2, 0}, {3, 0}}, CircleShape, 0.2))[0]
+	if d, _, _ := straight.SelfDistance(0.5); math.Abs(d-0.8) > eps {
+		t.Errorf("straight SelfDistance = %v, want 0.8", d)
+	}
+	if d, _, _ := straight.SelfDistance(1); !math.IsInf(d, 1) {
+		t.Errorf("straight SelfDistance = %v, want +Inf", d)
+	}
+}
+
+// bifilar returns the features of two interleaved spirals
+// with gaps of 0.15 between their turns.
+func bifilar(turns float64) (*Feature, *Feature) {
+	return Features(Spiral(Pt{}, 2, 0.6, turns, 0.15, 0, CounterClockwise))[0],
+		Features(Spiral(Pt{}, 2, 0.6, turns, 0.15, 180, CounterClockwise))[0]
+}
+
+func TestFeature_Distance_Indexed(t *testing.T) {
+	const eps = 1e-9
+	f, g := bifilar(20)
+	if len(f.edges()) < minEdgeIndex {
+		t.Fatalf("%v edges, want the spiral indexed", len(f.edges()))
+	}
+	// Every pair of edges gives the same distance.
+	want := math.Inf(1)
+	for _, s := range f.edges() {
+		for _, t := range g.edges() {
+			v, _, _ := segmentDistance(s.p1, s.p2, t.p1, t.p2)
+			want = math.Min(want, v-s.r-t.r)
+		}
+	}
+	if d, _, _ := f.Distance(g); math.Abs(d-want) > eps {
+		t.Errorf("Distance = %v, want %v", d, want)
+	}
+	if d, _, _ := f.SelfDistance(1); math.Abs(d-(0.6-0.15)) > 2*DefaultTolerance {
+		t.Errorf("SelfDistance = %v, want %v", d, 0.6-0.15)
+	}
+}
+
+func BenchmarkFeature_Distance(b *testing.B) {
+	f, g := bifilar(100)
+	for i := 0; i < b.N; i++ {
+		f.Distance(g)
+	}
+}
+
+func BenchmarkFeature_SelfDistance(b *testing.B) {
+	f, _ := bifilar(100)
+	for i := 0; i < b.N; i++ {
+		f.SelfDistance(1)
+	}
+}
@@ -0,0 +1,282 @@
+// Package footprints generates standard PCB land patterns (footprints)
+// as gerber Groups from IPC-7351 style component parameters.
+//
+// Each footprint places its pads on the copper layers, the matching
+// openings on the solder mask layers, its body outline and pin-1 marker
+// on the silkscreen, any holes on the drill layer, and its courtyard
+// on the TopCourtyardRole. All dimensions are in millimeters.
+package footprints
+
+import (
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+var (
+	// SilkWidth is the line width used for silkscreen outlines.
+	SilkWidth = 0.12
+	// SilkClearance is the minimum distance between silkscreen and pads.
+	SilkClearance = 0.2
+	// MaskExpansion is the amount by which solder mask openings
+	// exceed the size of their pads on every side.
+	MaskExpansion = 0.05
+	// CourtyardWidth is the line width used for courtyards.
+	CourtyardWidth = 0.05
+	// Pin1Diameter is the diameter of the silkscreen pin-1 marker dot.
+	Pin1Diameter = 0.4
+
+	// FabricationTolerance is the PCB fabrication tolerance (F).
+	FabricationTolerance = 0.1
+	// PlacementTolerance is the component placement tolerance (P).
+	PlacementTolerance = 0.05
+)
+
+// Footprint represents a generated land pattern.
+// Use (*gerber.Gerber).Place to add it to a design.
+type Footprint struct {
+	*gerber.Group
+	// Pads are the centers of the pads, in pin order (pin 1 first).
+	Pads []gerber.Pt
+	// Courtyard is the area reserved for the component.
+	Courtyard gerber.MBB
+}
+
+// Density represents an IPC-7351 land pattern density level.
+type Density int
+
+const (
+	// Most is density level A, for low component density (largest pads).
+	Most Density = iota
+	// Nominal is density level B, for moderate component density.
+	Nominal
+	// Least is density level C, for high component density (smallest pads).
+	Least
+)
+
+// Range represents a toleranced dimension in millimeters.
+type Range struct {
+	Min, Max float64
+}
+
+// Tol returns a Range of nominal +/- tolerance.
+func Tol(nominal, tolerance float64) Range {
+	return Range{Min: nominal - tolerance, Max: nominal + tolerance}
+}
+
+// Nom returns the nominal (mid-point) value of the range.
+func (r Range) Nom() float64 {
+	return 0.5 * (r.Min + r.Max)
+}
+
+// tol returns the total tolerance of the range.
+func (r Range) tol() float64 {
+	return r.Max - r.Min
+}
+
+// fillets are the IPC-7351 solder joint goals for a lead type,
+// plus the courtyard excess.
+type fillets struct {
+	toe, heel, side, courtyard float64
+}
+
+var (
+	chipFillets = map[Density]fillets{
+		Most:    {toe: 0.55, heel: 0, side: 0.05, courtyard: 0.5},
+		Nominal: {toe: 0.35, heel: 0, side: 0, courtyard: 0.25},
+		Least:   {toe: 0.15, heel: 0, side: -0.05, courtyard: 0.1},
+	}
+	gullWingFillets = map[Density]fillets{
+		Most:    {toe: 0.55, heel: 0.45, side: 0.05, courtyard: 0.5},
+		Nominal: {toe: 0.35, heel: 0.35, side: 0.03, courtyard: 0.25},
+		Least:   {toe: 0.15, heel: 0.25, side: 0.01, courtyard: 0.1},
+	}
+	noLeadFillets = map[Density]fillets{
+		Most:    {toe: 0.4, heel: 0, side: -0.04, courtyard: 0.5},
+		Nominal: {toe: 0.3, heel: 0, side: -0.04, courtyard: 0.25},
+		Least:   {toe: 0.2, heel: 0, side: -0.04, courtyard: 0.1},
+	}
+)
+
+// land computes the IPC-7351 land pattern for two opposing rows of
+// terminals. span is the overall terminal-to-terminal length (L),
+// term is the terminal (lead foot) length (T) and width is the
+// terminal width (W). It returns the outer extent Z, the inner gap G
+// and the pad width X.
+func land(span, term, width Range, f fillets) (z, g, x float64) {
+	rms := func(tol float64) float64 {
+		return math.Sqrt(tol*tol + FabricationTolerance*FabricationTolerance + PlacementTolerance*PlacementTolerance)
+	}
+
+	z = span.Min + 2*f.toe + rms(span.tol())
+
+	sMin := span.Min - 2*term.Max
+	sMax := span.Max - 2*term.Min
+	sTol := math.Sqrt(span.tol()*span.tol() + 2*term.tol()*term.tol())
+	sMax -= 0.5 * ((sMax - sMin) - sTol)
+	g = sMax - 2*f.heel - rms(sTol)
+	if g < 0 {
+		g = 0
+	}
+
+	x = width.Min + 2*f.side + rms(width.tol())
+	return z, g, x
+}
+
+// builder assembles a footprint.
+type builder struct {
+	fp   *Footprint
+	pads []gerber.MBB // pad extents, used to keep silkscreen clear
+}
+
+func newBuilder(name string) *builder {
+	return &builder{fp: &Footprint{Group: gerber.NewGroup(name)}}
+}
+
+// rect returns a closed, filled rectangle of size (w,h) centered at c.
+func rect(c gerber.Pt, w, h float64) *gerber.PolygonT {
+	hw, hh := 0.5*w, 0.5*h
+	return gerber.Polygon(c, true, []gerber.Pt{{-hw, -hh}, {hw, -hh}, {hw, hh}, {-hw, hh}, {-hw, -hh}}, 0)
+}
+
+// smdPad adds a rectangular surface mount pad of size (w,h) centered at c.
+func (b *builder) smdPad(c gerber.Pt, w, h float64) {
+	b.fp.Add(gerber.TopCopperRole, rect(c, w, h))
+	b.fp.Add(gerber.TopSolderMaskRole, rect(c, w+2*MaskExpansion, h+2*MaskExpansion))
+	b.fp.Pads = append(b.fp.Pads, c)
+	b.pads = append(b.pads, gerber.MBB{
+		Min: gerber.Pt{c[0] - 0.5*w, c[1] - 0.5*h},
+		Max: gerber.Pt{c[0] + 0.5*w, c[1] + 0.5*h},
+	})
+}
+
+// thPad adds a plated through-hole pad of diameter d with a hole
+// of diameter drill, centered at c. Square pads are used for pin 1.
+func (b *builder) thPad(c gerber.Pt, d, drill float64, square bool) {
+	md := d + 2*MaskExpansion
+	for _, role := range []gerber.LayerRole{gerber.TopCopperRole, gerber.BottomCopperRole} {
+		if square {
+			b.fp.Add(role, rect(c, d, d))
+		} else {
+			b.fp.Add(role, gerber.Circle(c, d))
+		}
+	}
+	for _, role := range []gerber.LayerRole{gerber.TopSolderMaskRole, gerber.BottomSolderMaskRole} {
+		if square {
+			b.fp.Add(role, rect(c, md, md))
+		} else {
+			b.fp.Add(role, gerber.Circle(c, md))
+		}
+	}
+	b.fp.Add(gerber.DrillRole, gerber.Circle(c, drill))
+	b.fp.Pads = append(b.fp.Pads, c)
+	b.pads = append(b.pads, gerber.MBB{
+		Min: gerber.Pt{c[0] - 0.5*d, c[1] - 0.5*d},
+		Max: gerber.Pt{c[0] + 0.5*d, c[1] + 0.5*d},
+	})
+}
+
+// silkBox adds the outline of the body to the silkscreen,
+// leaving out any portions that would be too close to a pad.
+func (b *builder) silkBox(body gerber.MBB) {
+	ll, ur := body.Min, body.Max
+	b.silkLine(gerber.Pt{ll[0], ll[1]}, gerber.Pt{ur[0], ll[1]})
+	b.silkLine(gerber.Pt{ll[0], ur[1]}, gerber.Pt{ur[0], ur[1]})
+	b.silkLine(gerber.Pt{ll[0], ll[1]}, gerber.Pt{ll[0], ur[1]})
+	b.silkLine(gerber.Pt{ur[0], ll[1]}, gerber.Pt{ur[0], ur[1]})
+}
+
+// silkLine adds a horizontal or vertical silkscreen line from p1 to p2,
+// leaving out any portions that would be too close to a pad.
+func (b *builder) silkLine(p1, p2 gerber.Pt) {
+	// axis is the coordinate that varies along the line.
+	axis, other := 0, 1
+	if p1[0] == p2[0] {
+		axis, other = 1, 0
+	}
+	lo, hi := math.Min(p1[axis], p2[axis]), math.Max(p1[axis], p2[axis])
+	keep := [][2]float64{{lo, hi}}
+
+	margin := SilkClearance + 0.5*SilkWidth
+	for _, pad := range b.pads {
+		if p1[other] <= pad.Min[other]-margin || p1[other] >= pad.Max[other]+margin {
+			continue
+		}
+		cutLo, cutHi := pad.Min[axis]-margin, pad.Max[axis]+margin
+		var next [][2]float64
+		for _, k := range keep {
+			if cutHi <= k[0] || cutLo >= k[1] {
+				next = append(next, k)
+				continue
+			}
+			if cutLo > k[0] {
+				next = append(next, [2]float64{k[0], cutLo})
+			}
+			if cutHi < k[1] {
+				next = append(next, [2]float64{cutHi, k[1]})
+			}
+		}
+		keep = next
+	}
+
+	for _, k := range keep {
+		if k[1]-k[0] < SilkWidth {
+			continue
+		}
+		var s, e gerber.Pt
+		s[axis], s[other] = k[0], p1[other]
+		e[axis], e[other] = k[1], p1[other]
+		b.fp.Add(gerber.TopSilkscreenRole, gerber.Line(s[0], s[1], e[0], e[1], gerber.CircleShape, SilkWidth))
+	}
+}
+
+// pin1At adds a pin-1 marker dot to the silkscreen at c.
+func (b *builder) pin1At(c gerber.Pt) {
+	b.fp.Add(gerber.TopSilkscreenRole, gerber.Circle(c, Pin1Diameter))
+}
+
+// pin1 adds a pin-1 marker dot to the silkscreen next to pin 1,
+// offset in the direction (dx,dy) away from the pad.
+func (b *builder) pin1(dx, dy float64) {
+	pad := b.pads[0]
+	c := gerber.Pt{0.5 * (pad.Min[0] + pad.Max[0]), 0.5 * (pad.Min[1] + pad.Max[1])}
+	offset := SilkClearance + 0.5*Pin1Diameter
+	switch {
+	case dx < 0:
+		c[0] = pad.Min[0] - offset
+	case dx > 0:
+		c[0] = pad.Max[0] + offset
+	}
+	switch {
+	case dy < 0:
+		c[1] = pad.Min[1] - offset
+	case dy > 0:
+		c[1] = pad.Max[1] + offset
+	}
+	b.pin1At(c)
+}
+
+// finish adds the courtyard surrounding the body and all pads
+// (plus the given excess) and returns the footprint.
+func (b *builder) finish(body gerber.MBB, excess float64) *Footprint {
+	cy := body
+	for i := range b.pads {
+		cy.Join(&b.pads[i])
+	}
+	// Round the courtyard out to a 0.01mm grid.
+	cy.Min[0] = math.Floor(100*(cy.Min[0]-excess)) / 100
+	cy.Min[1] = math.Floor(100*(cy.Min[1]-excess)) / 100
+	cy.Max[0] = math.Ceil(100*(cy.Max[0]+excess)) / 100
+	cy.Max[1] = math.Ceil(100*(cy.Max[1]+excess)) / 100
+	b.fp.Courtyard = cy
+
+	ll, ur := cy.Min, cy.Max
+	b.fp.Add(gerber.TopCourtyardRole, gerber.Polygon(gerber.Pt{0, 0}, false,
+		[]gerber.Pt{ll, {ur[0], ll[1]}, ur, {ll[0], ur[1]}}, CourtyardWidth))
+	return b.fp
+}
+
+// box returns a box of size (w,h) centered on the origin.
+func box(w, h float64) gerber.MBB {
+	return gerber.MBB{Min: gerber.Pt{-0.5 * w, -0.5 * h}, Max: gerber.Pt{0.5 * w, 0.5 * h}}
+}
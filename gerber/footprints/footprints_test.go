@@ -0,0 +1,130 @@
+package footprints
+
+import (
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+func mustFootprint(t *testing.T) func(fp *Footprint, err error) *Footprint {
+	return func(fp *Footprint, err error) *Footprint {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+}
+
+func TestFootprints(t *testing.T) {
+	must := mustFootprint(t)
+	tests := []struct {
+		name     string
+		fp       *Footprint
+		wantPads int
+		wantTHT  bool
+	}{
+		{name: "0402", fp: must(Chip("0402", Nominal)), wantPads: 2},
+		{name: "0603", fp: must(Chip("0603", Nominal)), wantPads: 2},
+		{name: "0805", fp: must(Chip("0805", Most)), wantPads: 2},
+		{name: "1206", fp: must(Chip("1206", Least)), wantPads: 2},
+		{name: "SOIC-8", fp: must(SOIC(8, Nominal)), wantPads: 8},
+		{name: "SOIC-16", fp: must(SOIC(16, Nominal)), wantPads: 16},
+		{name: "SOT-23", fp: SOT23(Nominal), wantPads: 3},
+		{name: "QFN-16", fp: must(QFN(16, 0.65, 3, 1.7, Nominal)), wantPads: 17},
+		{name: "QFN-32", fp: must(QFN(32, 0.5, 5, 3.45, Nominal)), wantPads: 33},
+		{name: "PinHeader-1x4", fp: must(PinHeader(1, 4)), wantPads: 4, wantTHT: true},
+		{name: "PinHeader-2x5", fp: must(PinHeader(2, 5)), wantPads: 10, wantTHT: true},
+		{name: "TO-220", fp: TO220(), wantPads: 3, wantTHT: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.fp.Pads); got != tt.wantPads {
+				t.Errorf("len(Pads) = %v, want %v", got, tt.wantPads)
+			}
+
+			copper := tt.fp.Flatten(gerber.TopCopperRole, gerber.Identity())
+			if got := len(copper); got != tt.wantPads {
+				t.Fatalf("len(TopCopper) = %v, want %v", got, tt.wantPads)
+			}
+			if got := len(tt.fp.Flatten(gerber.TopSolderMaskRole, gerber.Identity())); got != tt.wantPads {
+				t.Errorf("len(TopSolderMask) = %v, want %v", got, tt.wantPads)
+			}
+			if got := len(tt.fp.Flatten(gerber.TopCourtyardRole, gerber.Identity())); got != 1 {
+				t.Errorf("len(TopCourtyard) = %v, want 1", got)
+			}
+			drills := len(tt.fp.Flatten(gerber.DrillRole, gerber.Identity()))
+			if tt.wantTHT && drills != tt.wantPads {
+				t.Errorf("len(Drill) = %v, want %v", drills, tt.wantPads)
+			}
+			if !tt.wantTHT && drills != 0 {
+				t.Errorf("len(Drill) = %v, want 0", drills)
+			}
+
+			// Pads must be within the courtyard and must not overlap each other.
+			cy := tt.fp.Courtyard
+			for i, p := range copper {
+				mbb := p.MBB()
+				if mbb.Min[0] < cy.Min[0] || mbb.Min[1] < cy.Min[1] || mbb.Max[0] > cy.Max[0] || mbb.Max[1] > cy.Max[1] {
+					t.Errorf("pad %v %v is outside courtyard %v", i+1, mbb, cy)
+				}
+				for j := i + 1; j < len(copper); j++ {
+					other := copper[j].MBB()
+					if mbb.Min[0] < other.Max[0] && other.Min[0] < mbb.Max[0] && mbb.Min[1] < other.Max[1] && other.Min[1] < mbb.Max[1] {
+						t.Errorf("pad %v %v overlaps pad %v %v", i+1, mbb, j+1, other)
+					}
+				}
+			}
+
+			// Silkscreen must stay clear of the pads.
+			for _, s := range tt.fp.Flatten(gerber.TopSilkscreenRole, gerber.Identity()) {
+				smbb := s.MBB()
+				for i, p := range copper {
+					mbb := p.MBB()
+					if smbb.Min[0] < mbb.Max[0] && mbb.Min[0] < smbb.Max[0] && smbb.Min[1] < mbb.Max[1] && mbb.Min[1] < smbb.Max[1] {
+						t.Errorf("silkscreen %v overlaps pad %v %v", smbb, i+1, mbb)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestChip_0603(t *testing.T) {
+	const eps = 0.15
+	fp, err := Chip("0603", Nominal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Compare against the commonly-used 0603 nominal land pattern:
+	// 0.8mm x 0.95mm pads on 1.65mm centers.
+	pad := fp.Flatten(gerber.TopCopperRole, gerber.Identity())[1].MBB()
+	if w := pad.Max[0] - pad.Min[0]; w < 0.8-eps || w > 0.8+eps {
+		t.Errorf("pad width = %v, want about 0.8", w)
+	}
+	if h := pad.Max[1] - pad.Min[1]; h < 0.95-eps || h > 0.95+eps {
+		t.Errorf("pad height = %v, want about 0.95", h)
+	}
+	if x := fp.Pads[1][0]; x < 0.825-eps || x > 0.825+eps {
+		t.Errorf("pad center = %v, want about 0.825", x)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := Chip("9999", Nominal); err == nil {
+		t.Error("Chip(9999) = nil error, want error")
+	}
+	if _, err := SOIC(7, Nominal); err == nil {
+		t.Error("SOIC(7) = nil error, want error")
+	}
+	if _, err := QFN(10, 0.5, 3, 1, Nominal); err == nil {
+		t.Error("QFN(10) = nil error, want error")
+	}
+	if _, err := QFN(64, 0.5, 3, 1, Nominal); err == nil {
+		t.Error("QFN(64) on 3mm body = nil error, want error")
+	}
+	if _, err := PinHeader(0, 1); err == nil {
+		t.Error("PinHeader(0,1) = nil error, want error")
+	}
+}
@@ -0,0 +1,154 @@
+package footprints
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// chipBody represents the body and terminal dimensions of a
+// rectangular chip component.
+type chipBody struct {
+	length, width, term Range
+}
+
+// chipSizes are the standard chip resistor and capacitor sizes,
+// keyed by their imperial size codes.
+var chipSizes = map[string]chipBody{
+	"0402": {length: Tol(1.0, 0.05), width: Tol(0.5, 0.05), term: Range{0.15, 0.35}},
+	"0603": {length: Tol(1.6, 0.15), width: Tol(0.8, 0.15), term: Range{0.2, 0.5}},
+	"0805": {length: Tol(2.0, 0.2), width: Tol(1.25, 0.2), term: Range{0.25, 0.75}},
+	"1206": {length: Tol(3.2, 0.2), width: Tol(1.6, 0.2), term: Range{0.25, 0.75}},
+}
+
+// Chip returns the land pattern for a two-terminal chip resistor
+// or capacitor with the given imperial size code
+// ("0402", "0603", "0805" or "1206").
+// Pin 1 is on the left.
+func Chip(size string, density Density) (*Footprint, error) {
+	c, ok := chipSizes[size]
+	if !ok {
+		return nil, fmt.Errorf("unknown chip size %q", size)
+	}
+
+	z, g, x := land(c.length, c.term, c.width, chipFillets[density])
+	padW, padX := 0.5*(z-g), 0.25*(z+g)
+
+	b := newBuilder("CHIP-" + size)
+	b.smdPad(gerber.Pt{-padX, 0}, padW, x)
+	b.smdPad(gerber.Pt{padX, 0}, padW, x)
+
+	// Silkscreen lines run along the long sides of the body,
+	// outside of the pads.
+	body := box(c.length.Nom(), c.width.Nom())
+	hy := math.Max(body.Max[1], 0.5*x+SilkClearance+0.5*SilkWidth)
+	b.silkLine(gerber.Pt{body.Min[0], -hy}, gerber.Pt{body.Max[0], -hy})
+	b.silkLine(gerber.Pt{body.Min[0], hy}, gerber.Pt{body.Max[0], hy})
+	return b.finish(body, chipFillets[density].courtyard), nil
+}
+
+// SOIC returns the land pattern for a small-outline IC with the given
+// (even) number of pins on a 1.27mm pitch and a 3.9mm wide body.
+// Pin 1 is at the top left and pins are numbered counter-clockwise.
+func SOIC(pins int, density Density) (*Footprint, error) {
+	if pins < 4 || pins%2 != 0 {
+		return nil, fmt.Errorf("SOIC must have an even number of pins >= 4, got %v", pins)
+	}
+	const pitch = 1.27
+	f := gullWingFillets[density]
+	z, g, x := land(Range{5.8, 6.2}, Range{0.4, 1.27}, Range{0.31, 0.51}, f)
+	padW, padX := 0.5*(z-g), 0.25*(z+g)
+
+	b := newBuilder(fmt.Sprintf("SOIC-%v", pins))
+	n := pins / 2
+	top := 0.5 * float64(n-1) * pitch
+	for i := 0; i < n; i++ {
+		b.smdPad(gerber.Pt{-padX, top - float64(i)*pitch}, padW, x)
+	}
+	for i := n - 1; i >= 0; i-- {
+		b.smdPad(gerber.Pt{padX, top - float64(i)*pitch}, padW, x)
+	}
+
+	body := box(3.9, float64(n)*pitch-0.18)
+	b.silkBox(body)
+	b.pin1(-1, 0)
+	return b.finish(body, f.courtyard), nil
+}
+
+// SOT23 returns the land pattern for a 3-pin SOT-23 package.
+// Pins 1 and 2 are at the bottom left and right; pin 3 is at the top.
+func SOT23(density Density) *Footprint {
+	const pitch = 0.95
+	f := gullWingFillets[density]
+	z, g, x := land(Range{2.1, 2.64}, Range{0.3, 0.6}, Range{0.3, 0.5}, f)
+	padH, padY := 0.5*(z-g), 0.25*(z+g)
+
+	b := newBuilder("SOT-23")
+	b.smdPad(gerber.Pt{-pitch, -padY}, x, padH)
+	b.smdPad(gerber.Pt{pitch, -padY}, x, padH)
+	b.smdPad(gerber.Pt{0, padY}, x, padH)
+
+	body := box(2.9, 1.3)
+	b.silkBox(body)
+	b.pin1(-1, 0)
+	return b.finish(body, f.courtyard)
+}
+
+// qfnCornerGap is the minimum copper gap between QFN corner pads
+// on adjacent sides.
+const qfnCornerGap = 0.15
+
+// QFN returns the land pattern for a square quad flat no-lead package
+// with the given number of pins (a multiple of 4), pin pitch, body size
+// and exposed (thermal) pad size. If exposedPad is zero, no exposed pad
+// is generated; otherwise it is the last pad.
+// Pin 1 is at the top of the left side and pins are numbered
+// counter-clockwise.
+func QFN(pins int, pitch, body, exposedPad float64, density Density) (*Footprint, error) {
+	if pins < 4 || pins%4 != 0 {
+		return nil, fmt.Errorf("QFN must have a multiple of 4 pins, got %v", pins)
+	}
+	n := pins / 4
+	if float64(n)*pitch >= body {
+		return nil, fmt.Errorf("QFN pins (%v at %vmm pitch) do not fit on a %vmm body", pins, pitch, body)
+	}
+	if exposedPad >= body {
+		return nil, fmt.Errorf("QFN exposed pad (%vmm) does not fit on a %vmm body", exposedPad, body)
+	}
+
+	f := noLeadFillets[density]
+	z, g, x := land(Tol(body, 0.1), Range{0.3, 0.5}, Range{0.36 * pitch, 0.6 * pitch}, f)
+	first := 0.5 * float64(n-1) * pitch
+	// Shorten the pads if needed to keep them clear of the corner
+	// pads of the adjacent sides.
+	if minGap := 2 * (first + 0.5*x + qfnCornerGap); g < minGap {
+		g = minGap
+	}
+	if g >= z {
+		return nil, fmt.Errorf("QFN pins (%v at %vmm pitch) leave no room for pads on a %vmm body", pins, pitch, body)
+	}
+	padL, padC := 0.5*(z-g), 0.25*(z+g)
+
+	b := newBuilder(fmt.Sprintf("QFN-%v", pins))
+	for i := 0; i < n; i++ { // left side, top to bottom
+		b.smdPad(gerber.Pt{-padC, first - float64(i)*pitch}, padL, x)
+	}
+	for i := 0; i < n; i++ { // bottom side, left to right
+		b.smdPad(gerber.Pt{-first + float64(i)*pitch, -padC}, x, padL)
+	}
+	for i := 0; i < n; i++ { // right side, bottom to top
+		b.smdPad(gerber.Pt{padC, -first + float64(i)*pitch}, padL, x)
+	}
+	for i := 0; i < n; i++ { // top side, right to left
+		b.smdPad(gerber.Pt{first - float64(i)*pitch, padC}, x, padL)
+	}
+	if exposedPad > 0 {
+		b.smdPad(gerber.Pt{0, 0}, exposedPad, exposedPad)
+	}
+
+	bodyBox := box(body, body)
+	b.silkBox(bodyBox)
+	b.pin1(-1, 0)
+	return b.finish(bodyBox, f.courtyard), nil
+}
@@ -0,0 +1,60 @@
+package footprints
+
+import (
+	"fmt"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// PinHeader returns the land pattern for a 2.54mm pitch pin header with
+// the given number of rows and columns. Pin 1 (with a square pad) is at
+// the top left, and pins are numbered down each column in turn, which
+// matches the usual odd/even numbering of dual-row headers.
+func PinHeader(rows, cols int) (*Footprint, error) {
+	if rows < 1 || cols < 1 {
+		return nil, fmt.Errorf("pin header must have at least one row and column, got %vx%v", rows, cols)
+	}
+	const (
+		pitch = 2.54
+		padD  = 1.7
+		drill = 1.0
+	)
+
+	b := newBuilder(fmt.Sprintf("PinHeader-%vx%v", rows, cols))
+	left := -0.5 * float64(cols-1) * pitch
+	top := 0.5 * float64(rows-1) * pitch
+	for c := 0; c < cols; c++ {
+		for r := 0; r < rows; r++ {
+			pt := gerber.Pt{left + float64(c)*pitch, top - float64(r)*pitch}
+			b.thPad(pt, padD, drill, c == 0 && r == 0)
+		}
+	}
+
+	body := box(float64(cols)*pitch, float64(rows)*pitch)
+	b.silkBox(body)
+	b.pin1At(gerber.Pt{body.Min[0] - SilkClearance - 0.5*Pin1Diameter, top})
+	return b.finish(body, 0.5), nil
+}
+
+// TO220 returns the land pattern for a vertically-mounted 3-pin TO-220
+// package. The pins are in a row along X with pin 1 (square) on the left
+// and the metal tab toward +Y.
+func TO220() *Footprint {
+	const (
+		pitch = 2.54
+		padD  = 2.0
+		drill = 1.1
+		tab   = 1.27
+	)
+
+	b := newBuilder("TO-220")
+	for i := 0; i < 3; i++ {
+		b.thPad(gerber.Pt{float64(i-1) * pitch, 0}, padD, drill, i == 0)
+	}
+
+	body := gerber.MBB{Min: gerber.Pt{-5.0, -1.9}, Max: gerber.Pt{5.0, 2.7}}
+	b.silkBox(body)
+	b.silkLine(gerber.Pt{body.Min[0], body.Max[1] - tab}, gerber.Pt{body.Max[0], body.Max[1] - tab})
+	b.pin1At(gerber.Pt{body.Min[0] - SilkClearance - 0.5*Pin1Diameter, 0})
+	return b.finish(body, 0.5)
+}
@@ -3,6 +3,7 @@ package gerber
 
 import (
 	"archive/zip"
+	"io"
 	"os"
 	"sync"
 )
@@ -13,9 +14,14 @@ type Gerber struct {
 	FilenamePrefix string
 	// Layers represents the layers making up the Gerber design.
 	Layers []*Layer
+	// BlockApertures causes placed groups to be written as block
+	// apertures (%AB) that are flashed at each placement rather than
+	// being flattened into their individual primitives.
+	BlockApertures bool
 
-	mu  sync.Mutex // protects mbb against multiple requests
-	mbb *MBB       // cached minimum bounding box
+	profile *Profile   // optional manufacturer profile
+	mu      sync.Mutex // protects mbb against multiple requests
+	mbb     *MBB       // cached minimum bounding box
 }
 
 // New returns a new Gerber design.
@@ -40,14 +46,14 @@ func (g *Gerber) WriteGerber() error {
 		if err != nil {
 			return err
 		}
-		if err := layer.WriteGerber(f); err != nil {
+		if err := layer.write(f); err != nil {
 			return err
 		}
 		w, err := os.Create(layer.Filename)
 		if err != nil {
 			return err
 		}
-		if err := layer.WriteGerber(w); err != nil {
+		if err := layer.write(w); err != nil {
 			return err
 		}
 		if err := w.Close(); err != nil {
@@ -57,6 +63,22 @@ func (g *Gerber) WriteGerber() error {
 	return zw.Close()
 }
 
+// WriteZip writes all the Gerber layers into a ZIP file,
+// without writing them to their respective files.
+func (g *Gerber) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, layer := range g.Layers {
+		f, err := zw.Create(layer.Filename)
+		if err != nil {
+			return err
+		}
+		if err := layer.write(f); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
 // MBB returns the minimum bounding box of the design in millimeters.
 func (g *Gerber) MBB() MBB {
 	g.mu.Lock()
@@ -0,0 +1,306 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Group represents a reusable sub-design (a component) made up of
+// primitives on one or more layers. Groups may be nested and may be
+// placed into a design many times with different transformations.
+//
+// Groups should be fully built before they are placed, since each
+// placement caches its flattened primitives.
+type Group struct {
+	// Name identifies the group.
+	Name string
+
+	primitives map[LayerRole][]Primitive
+	children   []groupChild
+	mbb        *MBB // cached minimum bounding box
+}
+
+// groupChild represents a nested group and its placement.
+type groupChild struct {
+	group *Group
+	xf    Transform
+}
+
+// NewGroup returns a new, empty group.
+func NewGroup(name string) *Group {
+	return &Group{
+		Name:       name,
+		primitives: map[LayerRole][]Primitive{},
+	}
+}
+
+// Add adds primitives to the group for the layer with the given role.
+func (g *Group) Add(role LayerRole, primitives ...Primitive) {
+	g.primitives[role] = append(g.primitives[role], primitives...)
+	g.mbb = nil
+}
+
+// AddGroup nests a child group within this group using the
+// provided transformation.
+func (g *Group) AddGroup(child *Group, xf Transform) {
+	g.children = append(g.children, groupChild{group: child, xf: xf})
+	g.mbb = nil
+}
+
+// Roles returns the sorted roles of all layers used by the group
+// or any of its nested groups.
+func (g *Group) Roles() []LayerRole {
+	seen := map[LayerRole]bool{}
+	g.collectRoles(seen)
+	var roles []LayerRole
+	for role := range seen {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(a, b int) bool { return roles[a] < roles[b] })
+	return roles
+}
+
+func (g *Group) collectRoles(seen map[LayerRole]bool) {
+	for role, primitives := range g.primitives {
+		if len(primitives) > 0 {
+			seen[role] = true
+		}
+	}
+	for _, c := range g.children {
+		c.group.collectRoles(seen)
+	}
+}
+
+// Flatten returns all the primitives of the group (including nested
+// groups) for the layer with the given role, transformed by xf.
+func (g *Group) Flatten(role LayerRole, xf Transform) []Primitive {
+	var result []Primitive
+	for _, p := range g.primitives[role] {
+		if xf.IsIdentity() {
+			result = append(result, p)
+			continue
+		}
+		result = append(result, p.Transform(xf))
+	}
+	for _, c := range g.children {
+		result = append(result, c.group.Flatten(role, c.xf.Then(xf))...)
+	}
+	return result
+}
+
+// MBB returns the minimum bounding box of the group (on all layers)
+// in millimeters.
+func (g *Group) MBB() MBB {
+	if g.mbb != nil {
+		return *g.mbb
+	}
+	for _, role := range g.Roles() {
+		for _, p := range g.Flatten(role, Identity()) {
+			v := p.MBB()
+			if g.mbb == nil {
+				g.mbb = &v
+				continue
+			}
+			g.mbb.Join(&v)
+		}
+	}
+	if g.mbb == nil { // no primitives
+		g.mbb = &MBB{}
+	}
+	return *g.mbb
+}
+
+// Instance returns a primitive representing the group's primitives
+// for the layer with the given role, placed with the transformation xf.
+func (g *Group) Instance(role LayerRole, xf Transform) *InstanceT {
+	return &InstanceT{
+		Group: g,
+		Role:  role,
+		Xf:    xf,
+	}
+}
+
+// Place adds an instance of the group to every layer of the design
+// whose role is used by the group. Roles used by the group that
+// have no corresponding layer in the design are ignored.
+func (g *Gerber) Place(group *Group, xf Transform) {
+	for _, role := range group.Roles() {
+		for _, layer := range g.Layers {
+			if layer.Role == role {
+				layer.Add(group.Instance(role, xf))
+			}
+		}
+	}
+	g.mu.Lock()
+	g.mbb = nil
+	g.mu.Unlock()
+}
+
+// InstanceT represents a placed group on a single layer and satisfies
+// the Primitive and Compound interfaces.
+type InstanceT struct {
+	Group *Group
+	Role  LayerRole
+	Xf    Transform
+
+	primitives []Primitive // cached flattened primitives
+	mbb        *MBB        // cached minimum bounding box
+}
+
+// Primitives returns the flattened and transformed primitives
+// making up this instance.
+func (i *InstanceT) Primitives() []Primitive {
+	if i.primitives == nil {
+		i.primitives = i.Group.Flatten(i.Role, i.Xf)
+	}
+	return i.primitives
+}
+
+// WriteGerber returns an error because the primitives of an instance
+// each need their own aperture, so it can only be written as part of
+// a Layer, which flashes it as a block aperture or writes each of its
+// primitives in turn.
+func (i *InstanceT) WriteGerber(w io.Writer, apertureIndex int) error {
+	return fmt.Errorf("instance of group %q can only be written as part of a layer", i.Group.Name)
+}
+
+// Aperture returns nil for InstanceT because the apertures of
+// its primitives are used instead.
+func (i *InstanceT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (i *InstanceT) MBB() MBB {
+	if i.mbb != nil {
+		return *i.mbb
+	}
+	for _, p := range i.Primitives() {
+		v := p.MBB()
+		if i.mbb == nil {
+			i.mbb = &v
+			continue
+		}
+		i.mbb.Join(&v)
+	}
+	if i.mbb == nil { // no primitives
+		i.mbb = &MBB{}
+	}
+	return *i.mbb
+}
+
+// Transform returns a transformed copy of the instance.
+func (i *InstanceT) Transform(xf Transform) Primitive {
+	return i.Group.Instance(i.Role, i.Xf.Then(xf))
+}
+
+// blockID returns a unique ID for the block aperture of this instance.
+func (i *InstanceT) blockID() string {
+	return fmt.Sprintf("%p:%v", i.Group, i.Role)
+}
+
+// flash writes the instance as a flash of the block aperture
+// with the given index. It returns false (and writes nothing) if
+// the instance's transformation can not be represented in Gerber.
+func (i *InstanceT) flash(w io.Writer, blockIndex int) bool {
+	mirror, rotation, scale, ok := i.Xf.similarity()
+	if !ok {
+		return false
+	}
+	if mirror {
+		io.WriteString(w, "%LMX*%\n")
+	}
+	if rotation != 0 {
+		fmt.Fprintf(w, "%%LR%0.5f*%%\n", rotation)
+	}
+	if scale != 1 {
+		fmt.Fprintf(w, "%%LS%0.5f*%%\n", scale)
+	}
+	fmt.Fprintf(w, "G54D%d*\n", blockIndex)
+	fmt.Fprintf(w, "X%06dY%06dD03*\n", int(0.5+sf*i.Xf.C), int(0.5+sf*i.Xf.F))
+	if mirror {
+		io.WriteString(w, "%LMN*%\n")
+	}
+	if rotation != 0 {
+		io.WriteString(w, "%LR0*%\n")
+	}
+	if scale != 1 {
+		io.WriteString(w, "%LS1*%\n")
+	}
+	return true
+}
+
+// similarity decomposes the linear part of the transformation
+// into an optional mirroring of X, followed by a uniform scaling
+// and a counter-clockwise rotation (in degrees), which is the
+// order in which Gerber applies %LM, %LS and %LR.
+// ok is false if the transformation has shear or non-uniform scaling.
+func (t Transform) similarity() (mirror bool, rotation, scale float64, ok bool) {
+	const eps = 1e-9
+	a, b, d, e := t.A, t.B, t.D, t.E
+	if t.Det() < 0 {
+		mirror = true
+		a, d = -a, -d
+	}
+	scale = math.Hypot(a, d)
+	if scale < eps || math.Abs(e-a) > eps*scale || math.Abs(b+d) > eps*scale {
+		return false, 0, 0, false
+	}
+	rotation = 180.0 * math.Atan2(d, a) / math.Pi
+	if math.Abs(rotation) < eps {
+		rotation = 0
+	}
+	if math.Abs(scale-1) < eps {
+		scale = 1
+	}
+	return mirror, rotation, scale, true
+}
+
+// writeBlocks writes the block apertures (%AB) used by the layer's
+// instances, numbering them starting with firstIndex.
+// It returns a map of block IDs to aperture indices.
+// Block apertures are only used when the Gerber design requests them.
+func (l *Layer) writeBlocks(w io.Writer, firstIndex int) map[string]int {
+	if l.g == nil || !l.g.BlockApertures {
+		return nil
+	}
+	blocks := map[string]int{}
+	for _, p := range l.Primitives {
+		v, ok := p.(*InstanceT)
+		if !ok {
+			continue
+		}
+		id := v.blockID()
+		if _, ok := blocks[id]; ok {
+			continue
+		}
+		if _, _, _, ok := v.Xf.similarity(); !ok {
+			continue
+		}
+		index := firstIndex + len(blocks)
+		blocks[id] = index
+		fmt.Fprintf(w, "%%ABD%d*%%\n", index)
+		for _, bp := range v.Group.Flatten(v.Role, Identity()) {
+			l.writePrimitive(w, bp, nil)
+		}
+		io.WriteString(w, "%AB*%\n")
+	}
+	return blocks
+}
+
+// addBlockApertures adds the apertures needed by the untransformed
+// contents of any block apertures to the layer.
+func (l *Layer) addBlockApertures() {
+	if l.g == nil || !l.g.BlockApertures {
+		return
+	}
+	for _, p := range l.Primitives {
+		if v, ok := p.(*InstanceT); ok {
+			for _, bp := range v.Group.Flatten(v.Role, Identity()) {
+				l.addAperture(bp)
+			}
+		}
+	}
+}
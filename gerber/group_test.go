@@ -0,0 +1,153 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestInstanceT_Primitive(t *testing.T) {
+	var p Compound = &InstanceT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Compound.
+		t.Errorf("InstanceT does not implement the Compound interface")
+	}
+}
+
+func testGroup() *Group {
+	pad := NewGroup("pad")
+	pad.Add(TopCopperRole, Circle(Pt{0, 0}, 2))
+	pad.Add(DrillRole, Circle(Pt{0, 0}, 1))
+
+	g := NewGroup("pair")
+	g.AddGroup(pad, Translate(-5, 0))
+	g.AddGroup(pad, Translate(5, 0))
+	g.Add(TopCopperRole, Line(-5, 0, 5, 0, CircleShape, 0.5))
+	return g
+}
+
+func TestGroup_MBB(t *testing.T) {
+	g := testGroup()
+	want := MBB{Min: Pt{-6, -1}, Max: Pt{6, 1}}
+	if got := g.MBB(); got != want {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+
+	roles := g.Roles()
+	if len(roles) != 2 || roles[0] != DrillRole || roles[1] != TopCopperRole {
+		t.Errorf("Roles = %v, want [Drill TopCopper]", roles)
+	}
+
+	if got, want := len(g.Flatten(TopCopperRole, Identity())), 3; got != want {
+		t.Errorf("len(Flatten(TopCopperRole)) = %v, want %v", got, want)
+	}
+}
+
+func TestInstanceT_WriteGerber(t *testing.T) {
+	// An instance's pads and traces need their own apertures,
+	// which only a layer can provide.
+	i := testGroup().Instance(TopCopperRole, Identity())
+	var buf bytes.Buffer
+	if err := i.WriteGerber(&buf, 12); err == nil {
+		t.Errorf("WriteGerber = nil error, want error:\n%v", buf.String())
+	}
+}
+
+func TestGerber_Place(t *testing.T) {
+	const eps = 1e-9
+	g := New("test")
+	top := g.TopCopper()
+	drill := g.Drill()
+	grp := testGroup()
+	g.Place(grp, Translate(10, 10))
+	g.Place(grp, Rotate(90).Then(Translate(-10, -10)))
+
+	if got, want := len(top.Primitives), 2; got != want {
+		t.Fatalf("len(top.Primitives) = %v, want %v", got, want)
+	}
+	if got, want := len(drill.Primitives), 2; got != want {
+		t.Fatalf("len(drill.Primitives) = %v, want %v", got, want)
+	}
+	if got, want := len(top.Apertures), 2; got != want {
+		t.Errorf("len(top.Apertures) = %v, want %v", got, want)
+	}
+
+	want := MBB{Min: Pt{-11, -16}, Max: Pt{16, 11}}
+	got := g.MBB()
+	if math.Abs(got.Min[0]-want.Min[0]) > eps || math.Abs(got.Min[1]-want.Min[1]) > eps ||
+		math.Abs(got.Max[0]-want.Max[0]) > eps || math.Abs(got.Max[1]-want.Max[1]) > eps {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+}
+
+func TestLayer_WriteGerber_Groups(t *testing.T) {
+	tests := []struct {
+		name           string
+		blockApertures bool
+		xf             Transform
+		want           []string
+		notWant        []string
+	}{
+		{
+			name: "flattened",
+			xf:   Translate(10, 10),
+			want: []string{
+				"X5000000Y10000000D02*\nX5000000Y10000000D01*\n",
+				"X15000000Y10000000D02*\nX15000000Y10000000D01*\n",
+			},
+			notWant: []string{"%AB"},
+		},
+		{
+			name:           "block aperture",
+			blockApertures: true,
+			xf:             Translate(10, 10),
+			want: []string{
+				"%ABD14*%\n",
+				"%AB*%\n",
+				"G54D14*\nX10000000Y10000000D03*\n",
+			},
+			notWant: []string{"%LR", "%LM"},
+		},
+		{
+			name:           "rotated and mirrored block aperture",
+			blockApertures: true,
+			xf:             MirrorX().Then(Rotate(90)).Then(Translate(10, 10)),
+			want: []string{
+				"%ABD14*%\n",
+				"%LMX*%\n%LR90.00000*%\nG54D14*\nX10000000Y10000000D03*\n%LMN*%\n%LR0*%\n",
+			},
+		},
+		{
+			name:           "sheared block aperture is flattened",
+			blockApertures: true,
+			xf:             Scale(2, 1),
+			notWant:        []string{"D03*"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			g.BlockApertures = tt.blockApertures
+			top := g.TopCopper()
+			g.Place(testGroup(), tt.xf)
+
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("WriteGerber missing %q:\n%v", want, got)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("WriteGerber unexpectedly contains %q:\n%v", notWant, got)
+				}
+			}
+		})
+	}
+}
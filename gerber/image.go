@@ -0,0 +1,338 @@
+package gerber
+
+import (
+	"image"
+	"io"
+	"math"
+	"sort"
+)
+
+// ImageMode represents the way a thresholded image is converted
+// into regions.
+type ImageMode int
+
+const (
+	// TraceContours traces the outline of each dark area (with any
+	// holes) into a single polygon.
+	TraceContours ImageMode = iota
+	// Scanlines emits a rectangle for each run of dark pixels
+	// in each row.
+	Scanlines
+)
+
+// ImageOpts provides options for converting an image into regions.
+type ImageOpts struct {
+	Mode ImageMode
+	// Threshold is the luminance (from 0 to 1) below which a pixel
+	// is dark. Zero means 0.5. Transparent pixels are light.
+	Threshold float64
+	// Invert makes the light pixels dark and vice versa.
+	Invert bool
+	// Dither applies Floyd-Steinberg error diffusion before
+	// thresholding, rendering grays as halftones.
+	Dither bool
+	// DPI resamples the image to the given resolution (in dots per
+	// inch) at its final size. Zero means the image's own pixels.
+	DPI float64
+}
+
+// ImageT represents an image converted into filled regions and
+// satisfies the Primitive and Compound interfaces.
+type ImageT struct {
+	Polygons []*PolygonT
+
+	mbb *MBB // cached minimum bounding box
+}
+
+// Image returns a primitive made of the dark pixels of the image,
+// scaled to the given width with its lower-left corner at origin.
+// All dimensions are in millimeters.
+func Image(img image.Image, origin Pt, width float64, opts *ImageOpts) *ImageT {
+	if opts == nil {
+		opts = &ImageOpts{}
+	}
+	dark := opts.bitmap(img, width)
+	result := &ImageT{}
+	if len(dark) == 0 || len(dark[0]) == 0 {
+		return result
+	}
+
+	// Convert from pixels (with y up) to millimeters.
+	s := width / float64(len(dark[0]))
+	toMM := func(pts []Pt) []Pt {
+		result := make([]Pt, len(pts))
+		for i, pt := range pts {
+			result[i] = Pt{origin[0] + s*pt[0], origin[1] + s*pt[1]}
+		}
+		return result
+	}
+
+	if opts.Mode == Scanlines {
+		rows := len(dark)
+		for y, row := range dark {
+			for _, r := range mergeModules([][]bool{row}) {
+				y0, y1 := float64(rows-y-1), float64(rows-y)
+				rect := []Pt{{float64(r.x0), y0}, {float64(r.x1), y0}, {float64(r.x1), y1}, {float64(r.x0), y1}}
+				result.Polygons = append(result.Polygons, Polygon(Pt{}, true, toMM(rect), 0))
+			}
+		}
+		return result
+	}
+
+	for _, c := range traceBitmap(dark) {
+		var holes [][]Pt
+		for _, hole := range c.holes {
+			holes = append(holes, toMM(hole))
+		}
+		result.Polygons = append(result.Polygons, PolygonWithHoles(Pt{}, toMM(c.outer), holes))
+	}
+	return result
+}
+
+// bitmap returns the thresholded (and possibly resampled and dithered)
+// image as rows of dark pixels from top to bottom.
+func (o *ImageOpts) bitmap(img image.Image, width float64) [][]bool {
+	b := img.Bounds()
+	cols, rows := b.Dx(), b.Dy()
+	if cols == 0 || rows == 0 {
+		return nil
+	}
+	if o.DPI > 0 {
+		n := int(math.Round(width / 25.4 * o.DPI))
+		rows = int(math.Round(float64(n) * float64(rows) / float64(cols)))
+		cols = n
+		if cols < 1 || rows < 1 {
+			return nil
+		}
+	}
+
+	// Sample the luminance of each (resampled) pixel over white.
+	lum := make([][]float64, rows)
+	for y := range lum {
+		lum[y] = make([]float64, cols)
+		sy := b.Min.Y + int((float64(y)+0.5)*float64(b.Dy())/float64(rows))
+		for x := range lum[y] {
+			sx := b.Min.X + int((float64(x)+0.5)*float64(b.Dx())/float64(cols))
+			r, g, bl, a := img.At(sx, sy).RGBA()
+			l := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl) + float64(0xffff-a)) / 0xffff
+			if o.Invert {
+				l = 1 - l
+			}
+			lum[y][x] = l
+		}
+	}
+
+	threshold := o.Threshold
+	if threshold <= 0 {
+		threshold = 0.5
+	}
+	dark := make([][]bool, rows)
+	for y := range dark {
+		dark[y] = make([]bool, cols)
+		for x := range dark[y] {
+			l := lum[y][x]
+			dark[y][x] = l < threshold
+			if !o.Dither {
+				continue
+			}
+			// Diffuse the error to the unvisited neighbors.
+			e := l - 1
+			if dark[y][x] {
+				e = l
+			}
+			diffuse := func(x, y int, f float64) {
+				if x >= 0 && x < cols && y < rows {
+					lum[y][x] += e * f
+				}
+			}
+			diffuse(x+1, y, 7.0/16)
+			diffuse(x-1, y+1, 3.0/16)
+			diffuse(x, y+1, 5.0/16)
+			diffuse(x+1, y+1, 1.0/16)
+		}
+	}
+	return dark
+}
+
+// tracedContour is an outer contour with its holes, in pixel units
+// with the y axis pointing up.
+type tracedContour struct {
+	outer []Pt
+	holes [][]Pt
+}
+
+// traceBitmap traces the boundaries between the dark and light pixels.
+// Dark areas touching only at a corner are traced separately.
+func traceBitmap(dark [][]bool) []tracedContour {
+	rows, cols := len(dark), len(dark[0])
+	isDark := func(x, y int) bool {
+		return x >= 0 && x < cols && y >= 0 && y < rows && dark[y][x]
+	}
+
+	// Collect the boundary edges of the dark pixels, directed with the
+	// dark pixel on their left (in y-up coordinates), so that the outer
+	// contours run counter-clockwise and holes clockwise.
+	type vertex [2]int
+	edges := map[vertex][]vertex{}
+	addEdge := func(a, b vertex) {
+		edges[a] = append(edges[a], b)
+	}
+	for y := 0; y < rows; y++ {
+		yb, yt := rows-y-1, rows-y
+		for x := 0; x < cols; x++ {
+			if !dark[y][x] {
+				continue
+			}
+			if !isDark(x, y+1) {
+				addEdge(vertex{x, yb}, vertex{x + 1, yb})
+			}
+			if !isDark(x+1, y) {
+				addEdge(vertex{x + 1, yb}, vertex{x + 1, yt})
+			}
+			if !isDark(x, y-1) {
+				addEdge(vertex{x + 1, yt}, vertex{x, yt})
+			}
+			if !isDark(x-1, y) {
+				addEdge(vertex{x, yt}, vertex{x, yb})
+			}
+		}
+	}
+
+	// Link the edges into loops. Where two dark pixels touch at a
+	// corner, take the left turn to keep hugging the same pixel. Loops
+	// are only started from vertices with a single outgoing edge (every
+	// loop has at least one, such as its lowest-leftmost vertex) so that
+	// the turns are chosen consistently all the way around.
+	starts := make([]vertex, 0, len(edges))
+	for v := range edges {
+		starts = append(starts, v)
+	}
+	sort.Slice(starts, func(i, j int) bool { // for a deterministic output
+		if starts[i][1] != starts[j][1] {
+			return starts[i][1] < starts[j][1]
+		}
+		return starts[i][0] < starts[j][0]
+	})
+	var loops [][]Pt
+	for _, start := range starts {
+		if len(edges[start]) != 1 {
+			continue
+		}
+		var loop []Pt
+		prev, cur := start, start
+		for {
+			next := edges[cur]
+			best := 0
+			if len(next) > 1 {
+				dx, dy := cur[0]-prev[0], cur[1]-prev[1]
+				for i, n := range next {
+					if dx*(n[1]-cur[1])-dy*(n[0]-cur[0]) > 0 {
+						best = i
+					}
+				}
+			}
+			n := next[best]
+			edges[cur] = append(next[:best:best], next[best+1:]...)
+			loop = append(loop, Pt{float64(cur[0]), float64(cur[1])})
+			prev, cur = cur, n
+			if cur == start {
+				break
+			}
+		}
+		loops = append(loops, simplifyLoop(loop))
+	}
+
+	// Assign each hole to the smallest outer contour containing it.
+	var result []tracedContour
+	var holes [][]Pt
+	for _, loop := range loops {
+		if signedArea(loop) > 0 {
+			result = append(result, tracedContour{outer: loop})
+		} else {
+			holes = append(holes, loop)
+		}
+	}
+	for _, hole := range holes {
+		// The center of the dark pixel to the left of the first edge.
+		a, b := hole[0], hole[1]
+		l := math.Hypot(b[0]-a[0], b[1]-a[1])
+		dx, dy := 0.5*(b[0]-a[0])/l, 0.5*(b[1]-a[1])/l
+		pt := Pt{a[0] + dx - dy, a[1] + dy + dx}
+		best, bestArea := -1, math.Inf(1)
+		for i, c := range result {
+			if area := signedArea(c.outer); area < bestArea && pointInContour(pt, c.outer) {
+				best, bestArea = i, area
+			}
+		}
+		if best >= 0 {
+			result[best].holes = append(result[best].holes, hole)
+		}
+	}
+	return result
+}
+
+// simplifyLoop removes the vertices of the closed loop
+// that lie on a straight line between their neighbors.
+func simplifyLoop(loop []Pt) []Pt {
+	n := len(loop)
+	var result []Pt
+	for i, pt := range loop {
+		prev, next := loop[(i+n-1)%n], loop[(i+1)%n]
+		if (pt[0]-prev[0])*(next[1]-pt[1])-(pt[1]-prev[1])*(next[0]-pt[0]) != 0 {
+			result = append(result, pt)
+		}
+	}
+	return result
+}
+
+// Primitives returns the polygons making up the image.
+func (i *ImageT) Primitives() []Primitive {
+	result := make([]Primitive, len(i.Polygons))
+	for j, p := range i.Polygons {
+		result[j] = p
+	}
+	return result
+}
+
+// WriteGerber writes the polygons to the Gerber file.
+func (i *ImageT) WriteGerber(w io.Writer, apertureIndex int) error {
+	for _, p := range i.Polygons {
+		if err := p.WriteGerber(w, apertureIndex); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Aperture returns nil for ImageT because it uses the default aperture.
+func (i *ImageT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box of the dark areas of the image.
+func (i *ImageT) MBB() MBB {
+	if i.mbb != nil {
+		return *i.mbb
+	}
+	for _, p := range i.Polygons {
+		v := p.MBB()
+		if i.mbb == nil {
+			i.mbb = &v
+			continue
+		}
+		i.mbb.Join(&v)
+	}
+	if i.mbb == nil { // no dark pixels
+		i.mbb = &MBB{}
+	}
+	return *i.mbb
+}
+
+// Transform returns a transformed copy of the image.
+func (i *ImageT) Transform(xf Transform) Primitive {
+	n := &ImageT{}
+	for _, p := range i.Polygons {
+		n.Polygons = append(n.Polygons, p.Transform(xf).(*PolygonT))
+	}
+	return n
+}
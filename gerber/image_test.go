@@ -0,0 +1,201 @@
+package gerber
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestImageT_Primitive(t *testing.T) {
+	var p Primitive = &ImageT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("ImageT does not implement the Primitive interface")
+	}
+}
+
+// bitmapImage returns a gray image from rows of '#' (black)
+// and '.' (white) pixels.
+func bitmapImage(rows ...string) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x, c := range row {
+			if c == '.' {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+// imageContains reports whether any of the image's polygons contains pt.
+func imageContains(im *ImageT, pt Pt) bool {
+	for _, p := range im.Polygons {
+		if p.Contains(pt) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImage(t *testing.T) {
+	ring := bitmapImage(
+		"######",
+		"#....#",
+		"#.##.#",
+		"#.##.#",
+		"#....#",
+		"######",
+	)
+
+	contours := Image(ring, Pt{10, 20}, 12, nil)
+	if got := len(contours.Polygons); got != 2 {
+		t.Fatalf("contours: len(Polygons) = %v, want 2 (a ring and an island)", got)
+	}
+	var holes int
+	for _, p := range contours.Polygons {
+		holes += len(p.Holes)
+	}
+	if holes != 1 {
+		t.Errorf("contours: %v holes, want 1", holes)
+	}
+
+	scanlines := Image(ring, Pt{10, 20}, 12, &ImageOpts{Mode: Scanlines})
+	if got, want := len(scanlines.Polygons), 1+2+3+3+2+1; got != want {
+		t.Errorf("scanlines: len(Polygons) = %v, want %v", got, want)
+	}
+
+	for _, im := range []*ImageT{contours, scanlines} {
+		want := MBB{Min: Pt{10, 20}, Max: Pt{22, 32}}
+		if got := im.MBB(); got != want {
+			t.Errorf("MBB = %v, want %v", got, want)
+		}
+		// Each pixel is 2mm square, with row 0 at the top.
+		for y := 0; y < 6; y++ {
+			for x := 0; x < 6; x++ {
+				pt := Pt{10 + 2*float64(x) + 1, 32 - 2*float64(y) - 1}
+				want := ring.GrayAt(x, y).Y == 0
+				if got := imageContains(im, pt); got != want {
+					t.Errorf("pixel (%v,%v) dark = %v, want %v", x, y, got, want)
+				}
+			}
+		}
+		// Each region is closed by drawing back to its start.
+		var buf bytes.Buffer
+		if err := im.WriteGerber(&buf, 11); err != nil {
+			t.Fatal(err)
+		}
+		if n := checkRegions(t, buf.String()); n != len(im.Polygons) {
+			t.Errorf("WriteGerber wrote %v regions, want %v", n, len(im.Polygons))
+		}
+	}
+}
+
+func TestImage_Trace(t *testing.T) {
+	tests := []struct {
+		name         string
+		img          *image.Gray
+		wantPolygons int
+	}{
+		{
+			name:         "diagonal pixels are separate",
+			img:          bitmapImage("#.", ".#"),
+			wantPolygons: 2,
+		},
+		{
+			name:         "diagonal holes",
+			img:          bitmapImage("####", "#.##", "##.#", "####"),
+			wantPolygons: 1,
+		},
+		{
+			name: "random",
+			img: func() *image.Gray {
+				r := rand.New(rand.NewSource(1))
+				img := image.NewGray(image.Rect(0, 0, 40, 30))
+				for i := range img.Pix {
+					if r.Intn(2) == 0 {
+						img.Pix[i] = 255
+					}
+				}
+				return img
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.img.Bounds()
+			im := Image(tt.img, Pt{0, 0}, float64(b.Dx()), nil)
+			if tt.wantPolygons > 0 && len(im.Polygons) != tt.wantPolygons {
+				t.Errorf("len(Polygons) = %v, want %v", len(im.Polygons), tt.wantPolygons)
+			}
+			var merged [][]Pt
+			for _, p := range im.Polygons {
+				merged = append(merged, p.cutIns())
+			}
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					pt := Pt{float64(x) + 0.5, float64(b.Dy()-y) - 0.5}
+					want := tt.img.GrayAt(x, y).Y == 0
+					if got := imageContains(im, pt); got != want {
+						t.Fatalf("pixel (%v,%v) dark = %v, want %v", x, y, got, want)
+					}
+					// The regions written with cut-ins must agree.
+					var got bool
+					for _, contour := range merged {
+						got = got || pointInContour(pt, contour)
+					}
+					if got != want {
+						t.Fatalf("pixel (%v,%v) dark with cut-ins = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestImage_Options(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 20, 20))
+	for i := range gray.Pix {
+		gray.Pix[i] = 100 // a luminance of about 0.39
+	}
+	darkArea := func(im *ImageT) float64 {
+		var area float64
+		for _, p := range im.Polygons {
+			area += math.Abs(signedArea(p.Points))
+			for _, hole := range p.Holes {
+				area -= math.Abs(signedArea(hole))
+			}
+		}
+		return area
+	}
+
+	if got := darkArea(Image(gray, Pt{}, 20, nil)); got != 400 {
+		t.Errorf("threshold 0.5: dark area = %v, want 400", got)
+	}
+	if got := darkArea(Image(gray, Pt{}, 20, &ImageOpts{Threshold: 0.3})); got != 0 {
+		t.Errorf("threshold 0.3: dark area = %v, want 0", got)
+	}
+	if got := darkArea(Image(gray, Pt{}, 20, &ImageOpts{Invert: true})); got != 0 {
+		t.Errorf("inverted: dark area = %v, want 0", got)
+	}
+	dithered := darkArea(Image(gray, Pt{}, 20, &ImageOpts{Dither: true, Mode: Scanlines}))
+	if want := 400 * (1 - 100.0/255); math.Abs(dithered-want) > 20 {
+		t.Errorf("dithered: dark area = %v, want about %v", dithered, want)
+	}
+
+	// Resampling at 10 DPI over 25.4mm gives 10x10 pixels.
+	im := Image(bitmapImage("#.", ".#"), Pt{}, 25.4, &ImageOpts{DPI: 10, Mode: Scanlines})
+	if got := len(im.Polygons); got != 10 {
+		t.Errorf("resampled: len(Polygons) = %v, want 10", got)
+	}
+
+	// Transparent pixels are light.
+	clear := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	if got := len(Image(clear, Pt{}, 4, nil).Polygons); got != 0 {
+		t.Errorf("transparent: len(Polygons) = %v, want 0", got)
+	}
+}
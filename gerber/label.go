@@ -0,0 +1,168 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+)
+
+// LabelStyle represents the way a label's box is drawn around its text.
+type LabelStyle int
+
+const (
+	// Knockout draws a filled box with the text cleared out of it,
+	// such as white-on-black silkscreen labels.
+	Knockout LabelStyle = iota
+	// Framed draws the text within an outlined frame.
+	Framed
+	// Cleared clears a box around the text before drawing it, so that
+	// the text stands clear of anything drawn earlier in the layer,
+	// such as layer numbers within a copper pour.
+	Cleared
+)
+
+// String returns the name of the style.
+func (s LabelStyle) String() string {
+	switch s {
+	case Knockout:
+		return "knockout"
+	case Framed:
+		return "framed"
+	case Cleared:
+		return "cleared"
+	}
+	return fmt.Sprintf("LabelStyle(%d)", int(s))
+}
+
+// LabelT represents text within a (rounded) box and satisfies the
+// Primitive interface.
+type LabelT struct {
+	Text  *TextT
+	Style LabelStyle
+	// Margin is the space between the text and the box. Margin and
+	// Radius are in the text's own coordinates, so the box is rotated
+	// with the text. Text along a path gets a box around its MBB.
+	Margin float64
+	// Radius rounds the corners of the box. Zero means square corners.
+	Radius float64
+	// Thickness is the line width of a Framed label's frame.
+	Thickness float64
+
+	mbb *MBB // cached minimum bounding box
+}
+
+// Label returns a label primitive surrounding the text with a box of
+// the given style and margin. Framed labels have a 0.15mm wide frame.
+// All dimensions are in millimeters.
+func Label(text *TextT, style LabelStyle, margin float64) *LabelT {
+	return &LabelT{
+		Text:      text,
+		Style:     style,
+		Margin:    margin,
+		Thickness: 0.15,
+	}
+}
+
+// Box returns the closed contour of the label's box.
+func (l *LabelT) Box() []Pt {
+	t := l.Text
+	if err := t.renderText(); err != nil {
+		log.Fatal(err)
+	}
+	if t.baseline != nil {
+		return roundedRect(t.Render.MBB, l.Margin, l.Radius)
+	}
+	xf := Identity()
+	if t.angle != 0 {
+		xf = RotateAbout(Pt{t.x, t.y}, t.angle)
+	}
+	if t.xf != nil {
+		xf = xf.Then(*t.xf)
+	}
+	return xf.transformPts(roundedRect(t.local, l.Margin, l.Radius))
+}
+
+// roundedRect returns the counter-clockwise contour of the box
+// expanded by margin, with its corners rounded to radius.
+func roundedRect(box MBB, margin, radius float64) []Pt {
+	x0, y0 := box.Min[0]-margin, box.Min[1]-margin
+	x1, y1 := box.Max[0]+margin, box.Max[1]+margin
+	radius = math.Min(radius, 0.5*math.Min(x1-x0, y1-y0))
+	if radius <= 0 {
+		return []Pt{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+	}
+	n := int(math.Ceil(0.5 * math.Pi / segmentAngle(radius, DefaultTolerance)))
+	corners := []Pt{{x1 - radius, y0 + radius}, {x1 - radius, y1 - radius}, {x0 + radius, y1 - radius}, {x0 + radius, y0 + radius}}
+	var pts []Pt
+	for i, c := range corners {
+		a0 := -0.5*math.Pi + 0.5*math.Pi*float64(i)
+		for j := 0; j <= n; j++ {
+			sin, cos := math.Sincos(a0 + 0.5*math.Pi*float64(j)/float64(n))
+			pts = append(pts, Pt{c[0] + radius*cos, c[1] + radius*sin})
+		}
+	}
+	return pts
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (l *LabelT) WriteGerber(w io.Writer, apertureIndex int) error {
+	box := Polygon(Pt{}, true, l.Box(), 0)
+	switch l.Style {
+	case Knockout:
+		box.WriteGerber(w, apertureIndex)
+		return l.Text.writePolygons(w, true)
+	case Framed:
+		if err := l.Text.writePolygons(w, false); err != nil {
+			return err
+		}
+		box.Filled, box.Thickness = false, l.Thickness
+		return box.WriteGerber(w, apertureIndex)
+	case Cleared:
+		io.WriteString(w, "%LPC*%\n")
+		box.WriteGerber(w, apertureIndex)
+		io.WriteString(w, "%LPD*%\n")
+		return l.Text.writePolygons(w, false)
+	}
+	return fmt.Errorf("unknown label style %v", l.Style)
+}
+
+// Aperture returns the aperture of a Framed label's frame,
+// and nil otherwise because the box is a region.
+func (l *LabelT) Aperture() *Aperture {
+	if l.Style != Framed {
+		return nil
+	}
+	return &Aperture{
+		Shape: CircleShape,
+		Size:  l.Thickness,
+	}
+}
+
+// MBB returns the minimum bounding box of the label.
+func (l *LabelT) MBB() MBB {
+	if l.mbb != nil {
+		return *l.mbb
+	}
+	l.mbb = &MBB{}
+	*l.mbb = l.Text.MBB()
+	for _, pt := range l.Box() {
+		l.mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	if l.Style == Framed {
+		l.mbb.Min[0] -= 0.5 * l.Thickness
+		l.mbb.Min[1] -= 0.5 * l.Thickness
+		l.mbb.Max[0] += 0.5 * l.Thickness
+		l.mbb.Max[1] += 0.5 * l.Thickness
+	}
+	return *l.mbb
+}
+
+// Transform returns a transformed copy of the label.
+func (l *LabelT) Transform(xf Transform) Primitive {
+	n := *l
+	n.Text = l.Text.Transform(xf).(*TextT)
+	n.Thickness *= xf.ScaleFactor()
+	n.mbb = nil
+	return &n
+}
@@ -0,0 +1,141 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLabelT_Primitive(t *testing.T) {
+	var p Primitive = &LabelT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("LabelT does not implement the Primitive interface")
+	}
+}
+
+func TestLabelT_WriteGerber(t *testing.T) {
+	tests := []struct {
+		style      LabelStyle
+		wantPrefix string
+		wantSuffix string
+		wantCount  map[string]int
+	}{
+		{
+			style:      Knockout,
+			wantPrefix: "G54D11*\nG36*\n",
+			wantSuffix: "G37*\n",
+			// The box, then the glyphs cleared with their counters restored.
+			wantCount: map[string]int{"G36*": 1 + 3*2},
+		},
+		{
+			style:      Framed,
+			wantPrefix: "G54D11*\nG36*\n",
+			wantSuffix: "D01*\n",
+			wantCount:  map[string]int{"G36*": 3 * 2, "G54D12*": 1},
+		},
+		{
+			style:      Cleared,
+			wantPrefix: "%LPC*%\nG54D11*\nG36*\n",
+			wantSuffix: "%LPD*%\n",
+			wantCount:  map[string]int{"G36*": 1 + 3*2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style.String(), func(t *testing.T) {
+			l := Label(Text(0, 0, 1, "018", "freeserif", 12, &Center), tt.style, 1)
+			var buf bytes.Buffer
+			if err := l.WriteGerber(&buf, 12); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if !strings.HasPrefix(got, tt.wantPrefix) || !strings.HasSuffix(got, tt.wantSuffix) {
+				t.Errorf("WriteGerber =\n%v\nwant prefix %q and suffix %q", got, tt.wantPrefix, tt.wantSuffix)
+			}
+			if strings.Count(got, "%LPC*%") != strings.Count(got, "%LPD*%") {
+				t.Errorf("WriteGerber does not restore the dark polarity:\n%v", got)
+			}
+			for s, want := range tt.wantCount {
+				if n := strings.Count(got, s); n < want {
+					t.Errorf("count(%q) = %v, want at least %v", s, n, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelT_WriteGerber_Box(t *testing.T) {
+	for _, style := range []LabelStyle{Knockout, Cleared} {
+		for _, radius := range []float64{0, 0.5} {
+			l := Label(Text(0, 0, 1, "018", "freeserif", 12, &Center), style, 1)
+			l.Radius = radius
+			var buf bytes.Buffer
+			if err := l.WriteGerber(&buf, 12); err != nil {
+				t.Fatal(err)
+			}
+			// The box is the first region, closed by drawing back to its start.
+			got := buf.String()
+			box := got[strings.Index(got, "G36*"):]
+			box = box[:strings.Index(box, "G37*")+len("G37*")]
+			if n := checkRegions(t, box); n != 1 {
+				t.Errorf("%v label with radius %v: box has %v regions, want 1", style, radius, n)
+			}
+		}
+	}
+}
+
+func TestLabelT_MBB(t *testing.T) {
+	const eps = 1e-9
+	text := Text(10, 20, 1, "018", "freeserif", 12, &Center)
+	tm := text.MBB()
+
+	l := Label(text, Knockout, 1)
+	l.Radius = 0.5
+	got := l.MBB()
+	want := MBB{Min: Pt{tm.Min[0] - 1, tm.Min[1] - 1}, Max: Pt{tm.Max[0] + 1, tm.Max[1] + 1}}
+	for i := 0; i < 2; i++ {
+		if math.Abs(got.Min[i]-want.Min[i]) > eps || math.Abs(got.Max[i]-want.Max[i]) > eps {
+			t.Errorf("MBB = %v, want %v", got, want)
+			break
+		}
+	}
+
+	l.Style, l.mbb = Framed, nil
+	got = l.MBB()
+	if math.Abs(got.Min[0]-(want.Min[0]-0.075)) > eps || math.Abs(got.Max[1]-(want.Max[1]+0.075)) > eps {
+		t.Errorf("framed MBB = %v, want %v expanded by 0.075", got, want)
+	}
+}
+
+func TestLabelT_Box(t *testing.T) {
+	const eps = 1e-9
+	text := TextRotated(0, 0, 1, 90, "018", "freeserif", 12, &Center)
+	l := Label(text, Knockout, 1)
+	l.Radius = 0.5
+	box := l.Box()
+
+	// The box is rotated along with the text.
+	tm := text.MBB()
+	mbb := MBB{Min: box[0], Max: box[0]}
+	for _, pt := range box {
+		mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	for i := 0; i < 2; i++ {
+		if math.Abs(mbb.Min[i]-(tm.Min[i]-1)) > eps || math.Abs(mbb.Max[i]-(tm.Max[i]+1)) > eps {
+			t.Errorf("Box MBB = %v, want %v expanded by 1", mbb, tm)
+			break
+		}
+	}
+	// The corners are rounded.
+	corner := Pt{mbb.Max[0], mbb.Max[1]}
+	for _, pt := range box {
+		if math.Hypot(pt[0]-corner[0], pt[1]-corner[1]) < 0.5*(math.Sqrt2-1)-eps {
+			t.Errorf("box point %v is not rounded off from corner %v", pt, corner)
+		}
+	}
+	if signedArea(box) <= 0 {
+		t.Errorf("box is not counter-clockwise")
+	}
+}
@@ -4,12 +4,52 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 )
 
+// LayerRole identifies the purpose of a layer within a design.
+type LayerRole string
+
+const (
+	// TopCopperRole is the role of the top copper layer.
+	TopCopperRole LayerRole = "TopCopper"
+	// TopSolderMaskRole is the role of the top solder mask layer.
+	TopSolderMaskRole LayerRole = "TopSolderMask"
+	// TopSilkscreenRole is the role of the top silkscreen layer.
+	TopSilkscreenRole LayerRole = "TopSilkscreen"
+	// BottomCopperRole is the role of the bottom copper layer.
+	BottomCopperRole LayerRole = "BottomCopper"
+	// BottomSolderMaskRole is the role of the bottom solder mask layer.
+	BottomSolderMaskRole LayerRole = "BottomSolderMask"
+	// BottomSilkscreenRole is the role of the bottom silkscreen layer.
+	BottomSilkscreenRole LayerRole = "BottomSilkscreen"
+	// DrillRole is the role of the drill layer.
+	DrillRole LayerRole = "Drill"
+	// OutlineRole is the role of the board outline layer.
+	OutlineRole LayerRole = "Outline"
+	// TopCourtyardRole is the role of the top component courtyards.
+	// Courtyards are used for placement only and are not manufactured,
+	// so no Gerber layer is generated for them.
+	TopCourtyardRole LayerRole = "TopCourtyard"
+)
+
+// LayerNRole returns the role of the layer-n copper layer
+// of a multi-layer design.
+func LayerNRole(n int) LayerRole {
+	return LayerRole(fmt.Sprintf("Layer%v", n))
+}
+
+// IsCopper reports whether the role is that of a copper layer.
+func (r LayerRole) IsCopper() bool {
+	return r == TopCopperRole || r == BottomCopperRole || strings.HasPrefix(string(r), "Layer")
+}
+
 // Layer represents a printed circuit board layer.
 type Layer struct {
 	// Filename is the filename of the Gerber layer.
 	Filename string
+	// Role identifies the purpose of the layer.
+	Role LayerRole
 	// Primitives represents the collection of primitives.
 	Primitives []Primitive
 	// Apertures represents the apertures used in the layer.
@@ -18,48 +58,105 @@ type Layer struct {
 	// apertureMap maps an aperture to its index in the Apertures slice.
 	apertureMap map[string]int
 	// g is the root Gerber object.
-	g   *Gerber
-	mbb *MBB // cached minimum bounding box
+	g     *Gerber
+	mbb   *MBB   // cached minimum bounding box
+	index *rtree // spatial index of the primitives, once searched
 }
 
 // Add adds primitives to a layer.
 // It generates new apertures as necessary.
 func (l *Layer) Add(primitives ...Primitive) {
 	for _, p := range primitives {
-		a := p.Aperture()
-		if a == nil {
-			continue // use the default layer
-		}
-		id := a.ID()
-		if _, ok := l.apertureMap[id]; ok {
-			continue
+		l.addAperture(p)
+	}
+	if l.index != nil && l.index.size == len(l.Primitives) {
+		for i, p := range primitives {
+			l.index.insert(len(l.Primitives)+i, p.MBB())
 		}
-		l.apertureMap[id] = len(l.Apertures)
-		l.Apertures = append(l.Apertures, a)
 	}
 	l.Primitives = append(l.Primitives, primitives...)
 }
 
+// addAperture adds the aperture of the primitive (or the apertures
+// of all the primitives making up a Compound) to the layer.
+func (l *Layer) addAperture(p Primitive) {
+	if c, ok := p.(Compound); ok {
+		for _, v := range c.Primitives() {
+			l.addAperture(v)
+		}
+		return
+	}
+	a := p.Aperture()
+	if a == nil {
+		return // use the default layer
+	}
+	id := a.ID()
+	if _, ok := l.apertureMap[id]; ok {
+		return
+	}
+	l.apertureMap[id] = len(l.Apertures)
+	l.Apertures = append(l.Apertures, a)
+}
+
+// write writes a layer to its corresponding file in the format
+// required by the design's profile.
+func (l *Layer) write(w io.Writer) error {
+	if l.Role == DrillRole && l.g != nil && l.g.profile != nil && l.g.profile.Excellon {
+		return l.WriteExcellon(w)
+	}
+	return l.WriteGerber(w)
+}
+
 // WriteGerber writes a layer to its corresponding Gerber layer file.
+// Coordinates are written with the number of decimal digits
+// required by the design's profile.
 func (l *Layer) WriteGerber(w io.Writer) error {
+	if d := l.g.gerberDecimals(); d != 6 {
+		w = &formatWriter{w: w, decimals: d}
+	}
 	io.WriteString(w, "%FSLAX36Y36*%\n")
 	io.WriteString(w, "%MOMM*%\n")
 	io.WriteString(w, "%LPD*%\n")
 
+	l.addBlockApertures()
 	io.WriteString(w, "%ADD11C,0.00100*%\n")
 	for i, a := range l.Apertures {
 		a.WriteGerber(w, 12+i)
 	}
 
+	blocks := l.writeBlocks(w, 12+len(l.Apertures))
+
 	for _, p := range l.Primitives {
-		ai := l.apertureMap[p.Aperture().ID()]
-		p.WriteGerber(w, 12+ai)
+		if err := l.writePrimitive(w, p, blocks); err != nil {
+			return err
+		}
 	}
 
 	io.WriteString(w, "M02*\n")
 	return nil
 }
 
+// writePrimitive writes a single primitive to the Gerber file.
+// Compound primitives are either flashed as block apertures
+// or are flattened into their individual primitives.
+func (l *Layer) writePrimitive(w io.Writer, p Primitive, blocks map[string]int) error {
+	if v, ok := p.(*InstanceT); ok {
+		if bi, ok := blocks[v.blockID()]; ok && v.flash(w, bi) {
+			return nil
+		}
+	}
+	if c, ok := p.(Compound); ok {
+		for _, v := range c.Primitives() {
+			if err := l.writePrimitive(w, v, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ai := l.apertureMap[p.Aperture().ID()]
+	return p.WriteGerber(w, 12+ai)
+}
+
 // MBB returns the minimum bounding box of the layer in millimeters.
 func (l *Layer) MBB() MBB {
 	if l.mbb != nil {
@@ -81,10 +178,14 @@ func (l *Layer) MBB() MBB {
 	return *l.mbb
 }
 
-func (g *Gerber) makeLayer(extension string) *Layer {
+// makeLayer adds a layer with the role to the design, named
+// with the filename extension of the design's profile.
+func (g *Gerber) makeLayer(role LayerRole) *Layer {
 	layer := &Layer{
-		Filename:    g.FilenamePrefix + "." + extension,
+		Filename:    g.FilenamePrefix + "." + g.extension(role),
+		Role:        role,
 		apertureMap: map[string]int{"default": -1},
+		g:           g,
 	}
 	g.Layers = append(g.Layers, layer)
 	return layer
@@ -93,53 +194,53 @@ func (g *Gerber) makeLayer(extension string) *Layer {
 // TopCopper adds a top copper layer to the design
 // and returns the layer.
 func (g *Gerber) TopCopper() *Layer {
-	return g.makeLayer("gtl")
+	return g.makeLayer(TopCopperRole)
 }
 
 // TopSolderMask adds a top solder mask layer to the design
 // and returns the layer.
 func (g *Gerber) TopSolderMask() *Layer {
-	return g.makeLayer("gts")
+	return g.makeLayer(TopSolderMaskRole)
 }
 
 // TopSilkscreen adds a top silkscreen layer to the design
 // and returns the layer.
 func (g *Gerber) TopSilkscreen() *Layer {
-	return g.makeLayer("gto")
+	return g.makeLayer(TopSilkscreenRole)
 }
 
 // BottomCopper adds a bottom copper layer to the design
 // and returns the layer.
 func (g *Gerber) BottomCopper() *Layer {
-	return g.makeLayer("gbl")
+	return g.makeLayer(BottomCopperRole)
 }
 
 // BottomSolderMask adds a bottom solder mask layer to the design
 // and returns the layer.
 func (g *Gerber) BottomSolderMask() *Layer {
-	return g.makeLayer("gbs")
+	return g.makeLayer(BottomSolderMaskRole)
 }
 
 // BottomSilkscreen adds a bottom silkscreen layer to the design
 // and returns the layer.
 func (g *Gerber) BottomSilkscreen() *Layer {
-	return g.makeLayer("gbo")
+	return g.makeLayer(BottomSilkscreenRole)
 }
 
 // LayerN adds a layer-n copper layer to a multi-layer design
 // and returns the layer.
 func (g *Gerber) LayerN(n int) *Layer {
-	return g.makeLayer(fmt.Sprintf("g%vl", n))
+	return g.makeLayer(LayerNRole(n))
 }
 
 // Drill adds a drill layer to the design
 // and returns the layer.
 func (g *Gerber) Drill() *Layer {
-	return g.makeLayer("xln")
+	return g.makeLayer(DrillRole)
 }
 
 // Outline adds an outline layer to the design
 // and returns the layer.
 func (g *Gerber) Outline() *Layer {
-	return g.makeLayer("gko")
+	return g.makeLayer(OutlineRole)
 }
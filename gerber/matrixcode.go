@@ -0,0 +1,219 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/qr"
+)
+
+// QRLevel represents the error correction level of a QR code.
+type QRLevel = qr.ErrorCorrectionLevel
+
+const (
+	QRLevelL = qr.L // recovers 7% of the data
+	QRLevelM = qr.M // recovers 15% of the data
+	QRLevelQ = qr.Q // recovers 25% of the data
+	QRLevelH = qr.H // recovers 30% of the data
+)
+
+// MatrixCodeT represents a 2D matrix barcode (a QR code or Data Matrix)
+// and satisfies the Primitive interface. Its dark modules are written
+// as filled regions, with horizontally and vertically adjacent modules
+// merged into rectangles.
+type MatrixCodeT struct {
+	Center     Pt
+	ModuleSize float64
+	// QuietZone is the width of the empty border (in modules) that
+	// must surround the symbol. It is included in the MBB.
+	QuietZone int
+	Content   string
+
+	modules [][]bool   // [row][col] where row 0 is the top of the symbol
+	xf      *Transform // optional transformation
+	mbb     *MBB       // cached minimum bounding box
+}
+
+// QRCode returns a QR code primitive centered at center that encodes
+// the content with the given error correction level. It has the
+// standard quiet zone of 4 modules.
+// All dimensions are in millimeters.
+func QRCode(center Pt, content string, level QRLevel, moduleSize float64) (*MatrixCodeT, error) {
+	bc, err := qr.Encode(content, level, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("qr: %v", err)
+	}
+	return matrixCode(center, bc, moduleSize, 4), nil
+}
+
+// DataMatrix returns an (ECC 200) Data Matrix primitive centered at
+// center that encodes the content. It has the standard quiet zone
+// of 1 module.
+// All dimensions are in millimeters.
+func DataMatrix(center Pt, content string, moduleSize float64) (*MatrixCodeT, error) {
+	bc, err := datamatrix.Encode(content)
+	if err != nil {
+		return nil, fmt.Errorf("datamatrix: %v", err)
+	}
+	return matrixCode(center, bc, moduleSize, 1), nil
+}
+
+func matrixCode(center Pt, bc barcode.Barcode, moduleSize float64, quietZone int) *MatrixCodeT {
+	b := bc.Bounds()
+	modules := make([][]bool, b.Dy())
+	for y := range modules {
+		modules[y] = make([]bool, b.Dx())
+		for x := range modules[y] {
+			r, _, _, _ := bc.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			modules[y][x] = r < 0x8000
+		}
+	}
+	return &MatrixCodeT{
+		Center:     center,
+		ModuleSize: moduleSize,
+		QuietZone:  quietZone,
+		Content:    bc.Content(),
+		modules:    modules,
+	}
+}
+
+// Size returns the number of rows and columns of modules in the symbol,
+// not including the quiet zone.
+func (m *MatrixCodeT) Size() (rows, cols int) {
+	if len(m.modules) == 0 {
+		return 0, 0
+	}
+	return len(m.modules), len(m.modules[0])
+}
+
+// Dark reports whether the module at the given row (from the top)
+// and column (from the left) is dark.
+func (m *MatrixCodeT) Dark(row, col int) bool {
+	return m.modules[row][col]
+}
+
+// point returns the point at (x,y) modules from the top-left
+// corner of the symbol (within its quiet zone).
+func (m *MatrixCodeT) point(x, y float64) Pt {
+	rows, cols := m.Size()
+	pt := Pt{
+		m.Center[0] + (x-0.5*float64(cols))*m.ModuleSize,
+		m.Center[1] + (0.5*float64(rows)-y)*m.ModuleSize,
+	}
+	if m.xf != nil {
+		return m.xf.Apply(pt)
+	}
+	return pt
+}
+
+// Regions returns the closed contours of the filled regions
+// making up the dark modules of the symbol.
+func (m *MatrixCodeT) Regions() [][]Pt {
+	var regions [][]Pt
+	for _, r := range mergeModules(m.modules) {
+		regions = append(regions, []Pt{
+			m.point(float64(r.x0), float64(r.y1)),
+			m.point(float64(r.x1), float64(r.y1)),
+			m.point(float64(r.x1), float64(r.y0)),
+			m.point(float64(r.x0), float64(r.y0)),
+		})
+	}
+	return regions
+}
+
+// moduleRect is a rectangle of dark modules spanning
+// columns [x0,x1) and rows [y0,y1).
+type moduleRect struct {
+	x0, y0, x1, y1 int
+}
+
+// mergeModules merges the dark modules into rectangles: first into
+// horizontal runs, then stacking identical runs in consecutive rows.
+func mergeModules(modules [][]bool) []moduleRect {
+	var done []moduleRect
+	open := map[[2]int]*moduleRect{} // keyed by run columns
+	for y, row := range modules {
+		next := map[[2]int]*moduleRect{}
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+				continue
+			}
+			x0 := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			key := [2]int{x0, x}
+			if r, ok := open[key]; ok {
+				r.y1 = y + 1
+				next[key] = r
+				delete(open, key)
+				continue
+			}
+			next[key] = &moduleRect{x0: x0, y0: y, x1: x, y1: y + 1}
+		}
+		for _, r := range open {
+			done = append(done, *r)
+		}
+		open = next
+	}
+	for _, r := range open {
+		done = append(done, *r)
+	}
+	// Sort for a deterministic output, top to bottom and left to right.
+	sort.Slice(done, func(i, j int) bool {
+		if done[i].y0 != done[j].y0 {
+			return done[i].y0 < done[j].y0
+		}
+		return done[i].x0 < done[j].x0
+	})
+	return done
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (m *MatrixCodeT) WriteGerber(w io.Writer, apertureIndex int) error {
+	for _, region := range m.Regions() {
+		Polygon(Pt{}, true, region, 0).WriteGerber(w, apertureIndex)
+	}
+	return nil
+}
+
+// Aperture returns nil for MatrixCodeT because it uses the default aperture.
+func (m *MatrixCodeT) Aperture() *Aperture {
+	return nil
+}
+
+// MBB returns the minimum bounding box of the symbol,
+// including its quiet zone.
+func (m *MatrixCodeT) MBB() MBB {
+	if m.mbb != nil {
+		return *m.mbb
+	}
+	rows, cols := m.Size()
+	q := float64(m.QuietZone)
+	corners := []Pt{
+		m.point(-q, -q),
+		m.point(float64(cols)+q, -q),
+		m.point(float64(cols)+q, float64(rows)+q),
+		m.point(-q, float64(rows)+q),
+	}
+	m.mbb = &MBB{Min: corners[0], Max: corners[0]}
+	for _, pt := range corners[1:] {
+		m.mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	return *m.mbb
+}
+
+// Transform returns a transformed copy of the symbol.
+func (m *MatrixCodeT) Transform(xf Transform) Primitive {
+	n := *m
+	n.mbb = nil
+	if m.xf != nil {
+		xf = m.xf.Then(xf)
+	}
+	n.xf = &xf
+	return &n
+}
@@ -0,0 +1,160 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMatrixCodeT_Primitive(t *testing.T) {
+	var p Primitive = &MatrixCodeT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("MatrixCodeT does not implement the Primitive interface")
+	}
+}
+
+// regionArea returns the total area of the (non-overlapping) regions.
+func regionArea(regions [][]Pt) float64 {
+	var area float64
+	for _, region := range regions {
+		area += math.Abs(signedArea(region))
+	}
+	return area
+}
+
+func TestQRCode(t *testing.T) {
+	const eps = 1e-9
+	q, err := QRCode(Pt{10, 20}, "HELLO WORLD", QRLevelM, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, cols := q.Size()
+	if rows != 21 || cols != 21 {
+		t.Fatalf("Size = %v, %v, want 21, 21 (version 1)", rows, cols)
+	}
+	// The finder patterns: a dark 7x7 ring around a dark 3x3 square.
+	for _, corner := range [][2]int{{0, 0}, {0, 14}, {14, 0}} {
+		for r := 0; r < 7; r++ {
+			for c := 0; c < 7; c++ {
+				ring := r == 0 || r == 6 || c == 0 || c == 6
+				center := r >= 2 && r <= 4 && c >= 2 && c <= 4
+				if got := q.Dark(corner[0]+r, corner[1]+c); got != (ring || center) {
+					t.Fatalf("finder at %v: module (%v,%v) = %v", corner, r, c, got)
+				}
+			}
+		}
+	}
+
+	// The MBB includes the 4-module quiet zone.
+	want := MBB{Min: Pt{10 - 0.25*29, 20 - 0.25*29}, Max: Pt{10 + 0.25*29, 20 + 0.25*29}}
+	got := q.MBB()
+	for i := 0; i < 2; i++ {
+		if math.Abs(got.Min[i]-want.Min[i]) > eps || math.Abs(got.Max[i]-want.Max[i]) > eps {
+			t.Errorf("MBB = %v, want %v", got, want)
+			break
+		}
+	}
+
+	// The regions cover exactly the dark modules.
+	var dark int
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if q.Dark(r, c) {
+				dark++
+			}
+		}
+	}
+	regions := q.Regions()
+	if area, want := regionArea(regions), 0.25*float64(dark); math.Abs(area-want) > eps {
+		t.Errorf("region area = %v, want %v", area, want)
+	}
+	if len(regions) >= dark {
+		t.Errorf("%v regions for %v dark modules, want them merged", len(regions), dark)
+	}
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			pt := Pt{10 + 0.5*(float64(c)-10), 20 - 0.5*(float64(r)-10)}
+			var in bool
+			for _, region := range regions {
+				in = in || pointInContour(pt, region)
+			}
+			if in != q.Dark(r, c) {
+				t.Fatalf("module (%v,%v) covered = %v, want %v", r, c, in, q.Dark(r, c))
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := q.WriteGerber(&buf, 11); err != nil {
+		t.Fatal(err)
+	}
+	// Each module region is closed by drawing back to its start.
+	if n := checkRegions(t, buf.String()); n != len(regions) {
+		t.Errorf("WriteGerber wrote %v regions, want %v", n, len(regions))
+	}
+
+	// Higher error correction levels need larger symbols.
+	h, err := QRCode(Pt{}, "HELLO WORLD", QRLevelH, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ := h.Size(); rows <= 21 {
+		t.Errorf("level H Size = %v, want more than 21", rows)
+	}
+}
+
+func TestDataMatrix(t *testing.T) {
+	const eps = 1e-9
+	d, err := DataMatrix(Pt{0, 0}, "123456", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, cols := d.Size()
+	if rows != 10 || cols != 10 {
+		t.Fatalf("Size = %v, %v, want 10, 10", rows, cols)
+	}
+	// The finder pattern: solid left and bottom edges,
+	// alternating top and right edges.
+	for i := 0; i < 10; i++ {
+		if !d.Dark(i, 0) || !d.Dark(9, i) {
+			t.Errorf("finder edge is not solid at %v", i)
+		}
+		if d.Dark(0, i) != (i%2 == 0) || d.Dark(9-i, 9) != (i%2 == 0) {
+			t.Errorf("timing edge is wrong at %v", i)
+		}
+	}
+
+	want := MBB{Min: Pt{-6, -6}, Max: Pt{6, 6}}
+	if got := d.MBB(); math.Abs(got.Min[0]-want.Min[0]) > eps || math.Abs(got.Max[1]-want.Max[1]) > eps {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteGerber(&buf, 11); err != nil {
+		t.Fatal(err)
+	}
+	if n, want := checkRegions(t, buf.String()), len(d.Regions()); n != want {
+		t.Errorf("WriteGerber wrote %v regions, want %v", n, want)
+	}
+}
+
+func TestMatrixCodeT_Transform(t *testing.T) {
+	const eps = 1e-9
+	d, err := DataMatrix(Pt{0, 0}, "123456", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := d.Transform(Rotate(90).Then(Translate(10, 0))).(*MatrixCodeT)
+	want := MBB{Min: Pt{4, -6}, Max: Pt{16, 6}}
+	mbb := got.MBB()
+	for i := 0; i < 2; i++ {
+		if math.Abs(mbb.Min[i]-want.Min[i]) > eps || math.Abs(mbb.Max[i]-want.Max[i]) > eps {
+			t.Errorf("MBB = %v, want %v", mbb, want)
+			break
+		}
+	}
+	if area := regionArea(got.Regions()); math.Abs(area-regionArea(d.Regions())) > eps {
+		t.Errorf("transformed region area = %v, want %v", area, regionArea(d.Regions()))
+	}
+}
@@ -0,0 +1,615 @@
+package netlist
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// ozThickness is the thickness in millimeters of copper
+// weighing one ounce per square foot.
+const ozThickness = 0.0348
+
+// Copper describes the copper and the stack-up of a board for electrical
+// analysis. Zero-valued fields take the documented defaults, as does a
+// nil *Copper.
+type Copper struct {
+	// Weight is the copper weight in ounces per square foot
+	// (default 1, which is 0.0348mm thick).
+	Weight float64
+	// Resistivity is the resistivity of the copper in ohm meters
+	// (default 1.72e-8, that of annealed copper at 20°C).
+	Resistivity float64
+	// BoardThickness is the distance in millimeters from the top to the
+	// bottom copper layer (default 1.6). Inner layers are evenly spaced
+	// between them.
+	BoardThickness float64
+	// Filaments is the largest number of straight filaments that a path
+	// is divided into to find its inductance (default 4000). The time
+	// taken grows with its square, and coils need about ten per turn
+	// (see Path.Inductance).
+	Filaments int
+}
+
+func (c *Copper) withDefaults() Copper {
+	var v Copper
+	if c != nil {
+		v = *c
+	}
+	if v.Weight <= 0 {
+		v.Weight = 1
+	}
+	if v.Resistivity <= 0 {
+		v.Resistivity = 1.72e-8
+	}
+	if v.BoardThickness <= 0 {
+		v.BoardThickness = 1.6
+	}
+	if v.Filaments <= 0 {
+		v.Filaments = defaultFilaments
+	}
+	return v
+}
+
+// thickness returns the thickness of the copper in millimeters.
+func (c Copper) thickness() float64 {
+	return c.Weight * ozThickness
+}
+
+// resistance returns the DC resistance in ohms of a trace
+// of the length and width (in millimeters).
+func (c Copper) resistance(length, width float64) float64 {
+	return 1e3 * c.Resistivity * length / (width * c.thickness())
+}
+
+// Report is the result of the electrical analysis of a net.
+type Report struct {
+	Net *Net
+	// Terminals are the ends of the net that are the furthest
+	// apart electrically, preferring pads to the ends of traces.
+	Terminals [2]*Conductor
+	// Route is the path of least resistance between the terminals.
+	Route *Path
+	// Length is the length in millimeters of the traces along Route.
+	Length float64
+	// Resistance is the DC resistance in ohms between the terminals,
+	// through all of the parallel paths between them.
+	Resistance float64
+	// Inductance is the self inductance in henries of Route.
+	Inductance float64
+}
+
+// String returns a one-line summary of the report.
+func (r *Report) String() string {
+	return fmt.Sprintf("%v: length %.2fmm, resistance %.4fΩ, inductance %.3fµH", r.Net.Name, r.Length, r.Resistance, 1e6*r.Inductance)
+}
+
+// Analyze reports on each of the nets in turn.
+func (nl *Netlist) Analyze(c *Copper) []*Report {
+	var result []*Report
+	for _, net := range nl.Nets {
+		result = append(result, net.Analyze(c))
+	}
+	return result
+}
+
+// Analyze reports on the net between its terminals, being those of its
+// ends that are the furthest apart electrically: the terminal pads of
+// a coil, for example. Nets with branches have more ends than that.
+func (n *Net) Analyze(c *Copper) *Report {
+	cu := c.withDefaults()
+	ct := n.circuit(cu)
+	a, b := ct.terminals()
+	r := &Report{Net: n, Terminals: [2]*Conductor{ct.rep[a], ct.rep[b]}}
+	r.Route = ct.route(a, b)
+	r.Length = r.Route.Length()
+	r.Resistance, _ = ct.resistance(a, b)
+	r.Inductance = r.Route.Inductance(c)
+	return r
+}
+
+// Resistance returns the DC resistance in ohms of the net between two
+// of its primitives, such as pads. Traces are measured from their start.
+func (n *Net) Resistance(a, b gerber.Primitive, c *Copper) (float64, error) {
+	ct := n.circuit(c.withDefaults())
+	na, nb, err := ct.nodes(a, b)
+	if err != nil {
+		return 0, err
+	}
+	return ct.resistance(na, nb)
+}
+
+// Route returns the path of least resistance through the net between
+// two of its primitives, such as pads. Traces are measured from their
+// start.
+func (n *Net) Route(a, b gerber.Primitive, c *Copper) (*Path, error) {
+	ct := n.circuit(c.withDefaults())
+	na, nb, err := ct.nodes(a, b)
+	if err != nil {
+		return nil, err
+	}
+	if dist, _ := ct.shortest(na); math.IsInf(dist[nb], 1) {
+		return nil, errors.New("the primitives are not connected by traces")
+	}
+	return ct.route(na, nb), nil
+}
+
+// wire is a stroked conductor, along whose path the current flows.
+type wire struct {
+	c     *Conductor
+	cum   []float64 // the length of the path up to each of its points
+	at    []float64 // the positions along the path where others join it
+	nodes []int     // the node at each position in at
+}
+
+func newWire(c *Conductor) *wire {
+	path := c.Feature.Path
+	w := &wire{c: c, cum: make([]float64, len(path))}
+	for i := 1; i < len(path); i++ {
+		w.cum[i] = w.cum[i-1] + math.Hypot(path[i][0]-path[i-1][0], path[i][1]-path[i-1][1])
+	}
+	w.at = []float64{0, w.length()}
+	return w
+}
+
+func (w *wire) length() float64 {
+	return w.cum[len(w.cum)-1]
+}
+
+// point returns the point at position s along the path.
+func (w *wire) point(s float64) gerber.Pt {
+	path := w.c.Feature.Path
+	i := sort.SearchFloat64s(w.cum, s)
+	switch {
+	case i == 0:
+		return path[0]
+	case i == len(path):
+		return path[len(path)-1]
+	}
+	t := (s - w.cum[i-1]) / (w.cum[i] - w.cum[i-1])
+	return gerber.Pt{path[i-1][0] + t*(path[i][0]-path[i-1][0]), path[i-1][1] + t*(path[i][1]-path[i-1][1])}
+}
+
+// nearest returns the position along the path nearest to the point.
+func (w *wire) nearest(pt gerber.Pt) float64 {
+	path := w.c.Feature.Path
+	best, bestS := math.Inf(1), 0.0
+	for i := 1; i < len(path); i++ {
+		p1, p2 := path[i-1], path[i]
+		l := w.cum[i] - w.cum[i-1]
+		if l == 0 {
+			continue
+		}
+		t := math.Max(0, math.Min(1, ((pt[0]-p1[0])*(p2[0]-p1[0])+(pt[1]-p1[1])*(p2[1]-p1[1]))/(l*l)))
+		d := math.Hypot(p1[0]+t*(p2[0]-p1[0])-pt[0], p1[1]+t*(p2[1]-p1[1])-pt[1])
+		if d < best {
+			best, bestS = d, w.cum[i-1]+t*l
+		}
+	}
+	return bestS
+}
+
+// node returns the node at the position along the wire.
+func (w *wire) node(s float64) int {
+	i := sort.SearchFloat64s(w.at, s)
+	if i == len(w.at) || (i > 0 && s-w.at[i-1] < w.at[i]-s) {
+		i--
+	}
+	return w.nodes[i]
+}
+
+// sub returns the part of the path between two positions along it,
+// in the order given.
+func (w *wire) sub(s1, s2 float64) []gerber.Pt {
+	lo, hi := math.Min(s1, s2), math.Max(s1, s2)
+	pts := []gerber.Pt{w.point(lo)}
+	for i, s := range w.cum {
+		if s > lo && s < hi {
+			pts = append(pts, w.c.Feature.Path[i])
+		}
+	}
+	pts = append(pts, w.point(hi))
+	if s1 > s2 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	return pts
+}
+
+// resistor is the part of a wire between two nodes.
+type resistor struct {
+	a, b   int
+	r      float64
+	w      *wire
+	s1, s2 float64 // the positions along the wire of a and b
+}
+
+// circuit is a net as a network of resistors. Its nodes are the places
+// where the wires are joined, with all of the copper joined together
+// by pads, vias and other (non-stroked) copper being a single node.
+type circuit struct {
+	cu    Copper
+	z     map[*gerber.Layer]float64
+	conds map[gerber.Primitive]int // the node of each primitive
+	rep   []*Conductor             // a conductor at each node
+	edges []resistor
+	adj   [][]int // the edges at each node
+}
+
+// isWire reports whether the current flows along the conductor.
+func isWire(c *Conductor) bool {
+	return c.Layer.Role != gerber.DrillRole && !c.Feature.IsRegion() && len(c.Feature.Path) > 1
+}
+
+// joinable reports whether the conductors may be joined,
+// being on the same layer or one of them being a hole.
+func joinable(a, b *Conductor) bool {
+	return a.Layer == b.Layer || a.Layer.Role == gerber.DrillRole || b.Layer.Role == gerber.DrillRole
+}
+
+func (n *Net) circuit(cu Copper) *circuit {
+	var parent []int
+	newNode := func() int {
+		parent = append(parent, len(parent))
+		return len(parent) - 1
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	var wires []*wire
+	wireOf := map[*Conductor]*wire{}
+	var bodies []*Conductor
+	bodyNode := map[*Conductor]int{}
+	for _, c := range n.Conductors {
+		if isWire(c) {
+			w := newWire(c)
+			wires = append(wires, w)
+			wireOf[c] = w
+			continue
+		}
+		bodies = append(bodies, c)
+		bodyNode[c] = newNode()
+	}
+
+	// ref is a position along a wire or (if w is nil) a body's node.
+	type ref struct {
+		w    *wire
+		s    float64
+		node int
+	}
+	var joins [][2]ref
+	for _, w := range wires {
+		for _, s := range []float64{0, w.length()} {
+			e := w.point(s)
+			for _, c := range n.Conductors {
+				if c == w.c || !joinable(c, w.c) || c.Feature.EdgeDistance(e) < -touch {
+					continue
+				}
+				if cw := wireOf[c]; cw != nil {
+					cs := cw.nearest(e)
+					cw.at = append(cw.at, cs)
+					joins = append(joins, [2]ref{{w: w, s: s}, {w: cw, s: cs}})
+					continue
+				}
+				joins = append(joins, [2]ref{{w: w, s: s}, {node: bodyNode[c]}})
+			}
+		}
+	}
+	for i, a := range bodies {
+		for _, b := range bodies[i+1:] {
+			if joinable(a, b) && connected(a.Feature, b.Feature) {
+				joins = append(joins, [2]ref{{node: bodyNode[a]}, {node: bodyNode[b]}})
+			}
+		}
+	}
+
+	for _, w := range wires {
+		sort.Float64s(w.at)
+		at := w.at[:1]
+		for _, s := range w.at[1:] {
+			if s-at[len(at)-1] > touch {
+				at = append(at, s)
+			}
+		}
+		w.at = at
+		for range w.at {
+			w.nodes = append(w.nodes, newNode())
+		}
+	}
+	resolve := func(r ref) int {
+		if r.w == nil {
+			return r.node
+		}
+		return r.w.node(r.s)
+	}
+	for _, j := range joins {
+		a, b := find(resolve(j[0])), find(resolve(j[1]))
+		parent[a] = b
+	}
+
+	// Number the joined nodes in turn.
+	ct := &circuit{cu: cu, z: n.nl.heights(cu), conds: map[gerber.Primitive]int{}}
+	ids := map[int]int{}
+	id := func(i int) int {
+		r := find(i)
+		if v, ok := ids[r]; ok {
+			return v
+		}
+		ids[r] = len(ct.rep)
+		ct.rep = append(ct.rep, nil)
+		ct.adj = append(ct.adj, nil)
+		return ids[r]
+	}
+	for _, c := range bodies {
+		i := id(bodyNode[c])
+		// Prefer pads to holes as terminals.
+		if r := ct.rep[i]; r == nil || (r.Layer.Role == gerber.DrillRole && c.Layer.Role != gerber.DrillRole) {
+			ct.rep[i] = c
+		}
+		if _, ok := ct.conds[c.Primitive]; !ok {
+			ct.conds[c.Primitive] = i
+		}
+	}
+	for _, w := range wires {
+		for k, s := range w.at {
+			i := id(w.nodes[k])
+			if ct.rep[i] == nil {
+				ct.rep[i] = w.c
+			}
+			if k > 0 {
+				if j := id(w.nodes[k-1]); j != i {
+					ct.adj[i] = append(ct.adj[i], len(ct.edges))
+					ct.adj[j] = append(ct.adj[j], len(ct.edges))
+					ct.edges = append(ct.edges, resistor{a: j, b: i, r: cu.resistance(s-w.at[k-1], w.c.Feature.Width), w: w, s1: w.at[k-1], s2: s})
+				}
+			}
+		}
+		if _, ok := ct.conds[w.c.Primitive]; !ok {
+			ct.conds[w.c.Primitive] = id(w.nodes[0])
+		}
+	}
+	return ct
+}
+
+// heights returns the height of each copper layer
+// above the bottom copper layer.
+func (nl *Netlist) heights(cu Copper) map[*gerber.Layer]float64 {
+	z := map[*gerber.Layer]float64{}
+	for i, layer := range nl.copper {
+		if len(nl.copper) > 1 {
+			z[layer] = cu.BoardThickness * float64(len(nl.copper)-1-i) / float64(len(nl.copper)-1)
+		}
+	}
+	return z
+}
+
+// nodes returns the nodes of the primitives.
+func (ct *circuit) nodes(a, b gerber.Primitive) (int, int, error) {
+	na, ok := ct.conds[a]
+	if !ok {
+		return 0, 0, fmt.Errorf("%T is not on the net", a)
+	}
+	nb, ok := ct.conds[b]
+	if !ok {
+		return 0, 0, fmt.Errorf("%T is not on the net", b)
+	}
+	return na, nb, nil
+}
+
+// shortest returns the resistance along the path of least resistance
+// from the node to each other node, and the last edge of each path.
+func (ct *circuit) shortest(from int) ([]float64, []int) {
+	dist := make([]float64, len(ct.rep))
+	prev := make([]int, len(ct.rep))
+	for i := range dist {
+		dist[i], prev[i] = math.Inf(1), -1
+	}
+	dist[from] = 0
+	q := &nodeQueue{{node: from}}
+	for q.Len() > 0 {
+		v := heap.Pop(q).(queued)
+		if v.dist > dist[v.node] {
+			continue
+		}
+		for _, e := range ct.adj[v.node] {
+			r := ct.edges[e]
+			next := r.a
+			if next == v.node {
+				next = r.b
+			}
+			if d := v.dist + r.r; d < dist[next] {
+				dist[next], prev[next] = d, e
+				heap.Push(q, queued{node: next, dist: d})
+			}
+		}
+	}
+	return dist, prev
+}
+
+// terminals returns the two nodes furthest apart along the paths
+// of least resistance (exactly so if the net has no loops).
+func (ct *circuit) terminals() (int, int) {
+	farthest := func(from int) int {
+		dist, _ := ct.shortest(from)
+		best := from
+		for i, d := range dist {
+			if !math.IsInf(d, 1) && d > dist[best] {
+				best = i
+			}
+		}
+		return best
+	}
+	if len(ct.rep) == 0 {
+		return 0, 0
+	}
+	a := farthest(0)
+	return a, farthest(a)
+}
+
+// route returns the path of least resistance from one node to another.
+func (ct *circuit) route(from, to int) *Path {
+	_, prev := ct.shortest(from)
+	var edges []int
+	for v := to; v != from && prev[v] >= 0; {
+		e := prev[v]
+		edges = append(edges, e)
+		if ct.edges[e].a == v {
+			v = ct.edges[e].b
+		} else {
+			v = ct.edges[e].a
+		}
+	}
+
+	// Walk the edges from the start, joining the pieces of
+	// the wires through the pads and vias between them.
+	p := &Path{}
+	var last *[3]float64
+	v := from
+	for i := len(edges) - 1; i >= 0; i-- {
+		r := ct.edges[edges[i]]
+		s1, s2 := r.s1, r.s2
+		if r.a == v {
+			v = r.b
+		} else {
+			s1, s2 = s2, s1
+			v = r.a
+		}
+		z, width := ct.z[r.w.c.Layer], r.w.c.Feature.Width
+		for j, pt := range r.w.sub(s1, s2) {
+			q := [3]float64{pt[0], pt[1], z}
+			switch {
+			case j > 0:
+				p.Segments = append(p.Segments, Segment{P1: *last, P2: q, Width: width})
+			case last != nil && *last != q:
+				p.Segments = append(p.Segments, Segment{P1: *last, P2: q, Width: width, Joint: true})
+			}
+			last = &q
+		}
+	}
+	return p
+}
+
+// resistance returns the resistance between two nodes by nodal analysis.
+func (ct *circuit) resistance(a, b int) (float64, error) {
+	if a == b {
+		return 0, nil
+	}
+	// Number the nodes connected to a, other than b (the ground).
+	index := map[int]int{a: 0}
+	queue := []int{a}
+	found := false
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, e := range ct.adj[v] {
+			r := ct.edges[e]
+			for _, next := range []int{r.a, r.b} {
+				if next == b {
+					found = true
+					continue
+				}
+				if _, ok := index[next]; !ok {
+					index[next] = len(index)
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	if !found {
+		return math.Inf(1), errors.New("the primitives are not connected by traces")
+	}
+
+	// Solve G v = i for a current of one amp into a.
+	n := len(index)
+	g := make([][]float64, n)
+	for i := range g {
+		g[i] = make([]float64, n+1)
+	}
+	g[0][n] = 1
+	for _, r := range ct.edges {
+		ia, okA := index[r.a]
+		ib, okB := index[r.b]
+		c := 1 / r.r
+		if okA {
+			g[ia][ia] += c
+		}
+		if okB {
+			g[ib][ib] += c
+		}
+		if okA && okB {
+			g[ia][ib] -= c
+			g[ib][ia] -= c
+		}
+	}
+	v, err := solve(g)
+	if err != nil {
+		return 0, err
+	}
+	return v[0], nil
+}
+
+// solve solves the augmented system of linear equations
+// by Gaussian elimination with partial pivoting.
+func solve(m [][]float64) ([]float64, error) {
+	n := len(m)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(m[row][col]) > math.Abs(m[pivot][col]) {
+				pivot = row
+			}
+		}
+		if m[pivot][col] == 0 {
+			return nil, errors.New("singular circuit")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		for row := col + 1; row < n; row++ {
+			f := m[row][col] / m[col][col]
+			if f == 0 {
+				continue
+			}
+			for k := col; k <= n; k++ {
+				m[row][k] -= f * m[col][k]
+			}
+		}
+	}
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := m[row][n]
+		for k := row + 1; k < n; k++ {
+			sum -= m[row][k] * x[k]
+		}
+		x[row] = sum / m[row][row]
+	}
+	return x, nil
+}
+
+// queued is a node to visit in a shortest path search.
+type queued struct {
+	node int
+	dist float64
+}
+
+// nodeQueue is a priority queue of the nearest nodes first.
+type nodeQueue []queued
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(queued)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	v := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return v
+}
@@ -0,0 +1,113 @@
+package netlist
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/coil"
+)
+
+// near reports whether got is within the relative tolerance of want.
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance*math.Abs(want)
+}
+
+func TestNet_Resistance(t *testing.T) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	a, b := gerber.Circle(gerber.Pt{0, 0}, 1), gerber.Circle(gerber.Pt{100, 0}, 1)
+	top.Add(a, b, gerber.Line(0, 0, 100, 0, gerber.CircleShape, 0.5))
+	other := gerber.Circle(gerber.Pt{0, 10}, 1)
+	top.Add(other)
+
+	nl := Extract(g, nil)
+	net := nl.NetOf(a)
+	// 100mm of 0.5mm trace in 1oz (0.0348mm) copper.
+	want := 1.72e-8 * 100e-3 / (0.5e-3 * 0.0348e-3)
+	if got, err := net.Resistance(a, b, nil); err != nil || !near(got, want, 1e-9) {
+		t.Errorf("Resistance = %v, %v, want %v", got, err, want)
+	}
+	if got, err := net.Resistance(a, b, &Copper{Weight: 2}); err != nil || !near(got, want/2, 1e-9) {
+		t.Errorf("Resistance(2oz) = %v, %v, want %v", got, err, want/2)
+	}
+	if _, err := net.Resistance(a, other, nil); err == nil {
+		t.Error("Resistance to another net = nil error, want error")
+	}
+
+	// The same trace on the bottom layer, joined to the first at both
+	// ends by vias, halves the resistance.
+	bottom := g.BottomCopper()
+	drill := g.Drill()
+	for _, x := range []float64{0, 100} {
+		bottom.Add(gerber.Circle(gerber.Pt{x, 0}, 1))
+		drill.Add(gerber.Circle(gerber.Pt{x, 0}, 0.5))
+	}
+	bottom.Add(gerber.Line(0, 0, 100, 0, gerber.CircleShape, 0.5))
+	net = Extract(g, nil).NetOf(a)
+	if got, err := net.Resistance(a, b, nil); err != nil || !near(got, want/2, 1e-9) {
+		t.Errorf("Resistance(parallel) = %v, %v, want %v", got, err, want/2)
+	}
+	route, err := net.Route(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := route.Length(); !near(got, 100, 1e-9) {
+		t.Errorf("Route length = %v, want 100", got)
+	}
+}
+
+func TestNet_Resistance_Branch(t *testing.T) {
+	// A trace ending part of the way along another joins it there.
+	g := gerber.New("test")
+	top := g.TopCopper()
+	a, b, c := gerber.Circle(gerber.Pt{0, 0}, 1), gerber.Circle(gerber.Pt{100, 0}, 1), gerber.Circle(gerber.Pt{40, 50}, 1)
+	top.Add(a, b, c, gerber.Line(0, 0, 100, 0, gerber.CircleShape, 0.5), gerber.Line(40, 50, 40, 0, gerber.CircleShape, 0.5))
+
+	net := Extract(g, nil).NetOf(a)
+	per := 1.72e-8 * 1e-3 / (0.5e-3 * 0.0348e-3) // per millimeter
+	if got, err := net.Resistance(a, c, nil); err != nil || !near(got, 90*per, 1e-9) {
+		t.Errorf("Resistance(a, c) = %v, %v, want %v", got, err, 90*per)
+	}
+
+	r := net.Analyze(nil)
+	if p, q := r.Terminals[0].Primitive, r.Terminals[1].Primitive; !(p == b && q == c || p == c && q == b) {
+		t.Errorf("Terminals = %v and %v, want the pads at (100,0) and (40,50)", p.MBB(), q.MBB())
+	}
+	if !near(r.Length, 110, 1e-9) || !near(r.Resistance, 110*per, 1e-9) {
+		t.Errorf("Analyze = %v, want length 110", r)
+	}
+}
+
+func TestNetlist_Analyze(t *testing.T) {
+	g, err := coil.New(&coil.Config{Turns: 5, Layers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := Extract(g, nil).Analyze(nil)
+	if len(reports) != 1 {
+		t.Fatalf("Analyze = %v reports, want 1", len(reports))
+	}
+	r := reports[0]
+	for _, c := range r.Terminals {
+		if isWire(c) || c.Layer.Role == gerber.DrillRole {
+			t.Errorf("terminal %T on %v, want a pad", c.Primitive, c.Layer.Role)
+		}
+	}
+	// A series coil has a single path between its pads.
+	if got := r.Route.Resistance(nil); !near(r.Resistance, got, 1e-6) {
+		t.Errorf("Resistance = %v, want that of the route, %v", r.Resistance, got)
+	}
+	var joints int
+	for _, s := range r.Route.Segments {
+		if s.Joint && s.P1[2] != s.P2[2] {
+			joints++
+		}
+	}
+	if joints < 3 {
+		t.Errorf("route passes between layers %v times, want at least 3", joints)
+	}
+	if r.Length <= 0 || r.Inductance <= 0 {
+		t.Errorf("Analyze = %v, want a positive length and inductance", r)
+	}
+}
@@ -0,0 +1,254 @@
+package netlist
+
+import (
+	"math"
+)
+
+const (
+	// mu0 is the permeability of free space in henries per meter.
+	mu0 = 4e-7 * math.Pi
+	// defaultFilaments is the default largest number of straight
+	// filaments that a path is simplified to for numerical integration,
+	// enough for a few hundred turns.
+	defaultFilaments = 4000
+	// maxSplits limits the subdivision of nearby filaments.
+	maxSplits = 4
+)
+
+// Segment is a straight piece of a Path.
+type Segment struct {
+	// P1 and P2 are the ends of the segment: their x and y coordinates
+	// and their height above the bottom copper layer, in millimeters.
+	P1, P2 [3]float64
+	// Width is the width of the trace.
+	Width float64
+	// Joint marks the segments that join the pieces of a path through
+	// pads and vias. Their resistance is neglected.
+	Joint bool
+}
+
+func (s Segment) length() float64 {
+	return dist3(s.P1, s.P2)
+}
+
+// Path is the centerline of a route of current through the copper,
+// in three dimensions.
+type Path struct {
+	Segments []Segment
+}
+
+// Length returns the length in millimeters of the traces along
+// the path, excluding its joints.
+func (p *Path) Length() float64 {
+	var sum float64
+	for _, s := range p.Segments {
+		if !s.Joint {
+			sum += s.length()
+		}
+	}
+	return sum
+}
+
+// Resistance returns the DC resistance in ohms of the traces
+// along the path, excluding its joints.
+func (p *Path) Resistance(c *Copper) float64 {
+	cu := c.withDefaults()
+	var sum float64
+	for _, s := range p.Segments {
+		if !s.Joint {
+			sum += cu.resistance(s.length(), s.Width)
+		}
+	}
+	return sum
+}
+
+// Inductance returns the self inductance in henries of the path, as if
+// its ends were joined by a return path of no inductance.
+//
+// It is found by numerical integration of Neumann's formula over the
+// path's centerline, with the distance between points never less than
+// the geometric mean distance of the trace's rectangular cross section,
+// 0.2235 of its width plus thickness.
+//
+// The path is first simplified to at most c.Filaments straight
+// filaments, and the time taken grows with the square of their number:
+// the default takes several seconds for a 400-turn coil. Coils need
+// about ten filaments per turn to come within a few percent of their
+// inductance. With fewer, the filaments cut across the turns and the
+// inductance is underestimated: by a fifth with five per turn, and by
+// three quarters with three.
+func (p *Path) Inductance(c *Copper) float64 {
+	cu := c.withDefaults()
+	fs := p.filaments(cu, cu.Filaments)
+	var sum float64
+	for i, a := range fs {
+		sum += a.self()
+		for _, b := range fs[i+1:] {
+			sum += 2 * neumann(a, b, (a.gmd+b.gmd)/2, maxSplits)
+		}
+	}
+	return 1e-3 * mu0 / (4 * math.Pi) * sum
+}
+
+// MutualInductance returns the mutual inductance in henries between
+// two paths, such as those of two windings of a transformer.
+func MutualInductance(p, q *Path, c *Copper) float64 {
+	cu := c.withDefaults()
+	fp, fq := p.filaments(cu, cu.Filaments), q.filaments(cu, cu.Filaments)
+	var sum float64
+	for _, a := range fp {
+		for _, b := range fq {
+			sum += neumann(a, b, (a.gmd+b.gmd)/2, maxSplits)
+		}
+	}
+	return 1e-3 * mu0 / (4 * math.Pi) * sum
+}
+
+// Wheeler returns the inductance in henries of a planar circular spiral
+// of the number of turns and inner and outer radii (in millimeters),
+// by Wheeler's formula for a flat spiral, L = N²a²/(8a+11c) µH
+// for a mean radius a and a radial depth c in inches.
+func Wheeler(turns, rIn, rOut float64) float64 {
+	a := (rIn + rOut) / 2 / 25.4
+	c := (rOut - rIn) / 25.4
+	return 1e-6 * turns * turns * a * a / (8*a + 11*c)
+}
+
+// CurrentSheet returns the inductance in henries of a planar circular
+// spiral of the number of turns and inner and outer radii (in
+// millimeters), by the current sheet approximation of Mohan et al.
+func CurrentSheet(turns, rIn, rOut float64) float64 {
+	dAvg := 1e-3 * (rIn + rOut)
+	fill := (rOut - rIn) / (rOut + rIn)
+	return mu0 * turns * turns * dAvg / 2 * (math.Log(2.46/fill) + 0.2*fill*fill)
+}
+
+// filament is a straight filament of current.
+type filament struct {
+	p1, p2 [3]float64
+	gmd    float64
+}
+
+// self returns the integral of Neumann's formula of the filament with
+// itself, in millimeters.
+func (f filament) self() float64 {
+	l, g := dist3(f.p1, f.p2), f.gmd
+	if l == 0 {
+		return 0
+	}
+	h := math.Hypot(l, g)
+	return 2 * (l*math.Log((l+h)/g) - h + g)
+}
+
+// neumann returns the integral of Neumann's formula between the
+// filaments, in millimeters, splitting them where they are close.
+func neumann(a, b filament, gmd float64, splits int) float64 {
+	da, db := sub3(a.p2, a.p1), sub3(b.p2, b.p1)
+	dot := da[0]*db[0] + da[1]*db[1] + da[2]*db[2]
+	if dot == 0 {
+		return 0
+	}
+	la, lb := length3(da), length3(db)
+	d := dist3(mid3(a.p1, a.p2), mid3(b.p1, b.p2))
+	if splits == 0 || d > 3*math.Max(la, lb) {
+		return dot / math.Hypot(d, gmd)
+	}
+	if la < lb {
+		a, b = b, a
+	}
+	m := mid3(a.p1, a.p2)
+	return neumann(filament{a.p1, m, a.gmd}, b, gmd, splits-1) + neumann(filament{m, a.p2, a.gmd}, b, gmd, splits-1)
+}
+
+// filaments returns the path as straight filaments of current, joining
+// segments that are nearly in line until there are at most n of them.
+func (p *Path) filaments(cu Copper, n int) []filament {
+	if len(p.Segments) == 0 {
+		return nil
+	}
+	pts := [][3]float64{p.Segments[0].P1}
+	for _, s := range p.Segments {
+		pts = append(pts, s.P2)
+	}
+	gmd := func(i int) float64 {
+		return 0.2235 * (p.Segments[i].Width + cu.thickness())
+	}
+
+	var keep []int
+	for tolerance := 1e-4; ; tolerance *= 2 {
+		if keep = simplify(pts, tolerance); len(keep)-1 <= n {
+			break
+		}
+	}
+	result := make([]filament, 0, len(keep)-1)
+	for k := 1; k < len(keep); k++ {
+		result = append(result, filament{p1: pts[keep[k-1]], p2: pts[keep[k]], gmd: gmd(keep[k-1])})
+	}
+	return result
+}
+
+// simplify returns the indices of the points to keep, in order, so
+// that the points between each pair of them are within the tolerance
+// of the line between them. Each line is extended by doubling its reach
+// and then bisecting, so long runs of nearly straight points stay cheap.
+func simplify(pts [][3]float64, tolerance float64) []int {
+	within := func(i, j int) bool {
+		for k := i + 1; k < j; k++ {
+			if lineDistance(pts[k], pts[i], pts[j]) > tolerance {
+				return false
+			}
+		}
+		return true
+	}
+	last := len(pts) - 1
+	keep := []int{0}
+	for i := 0; i < last; {
+		// lo is within the tolerance, and hi is beyond it (or the end).
+		lo, hi := i+1, i+2
+		for hi <= last && within(i, hi) {
+			lo, hi = hi, i+2*(hi-i)
+		}
+		if hi > last {
+			if hi = last; within(i, last) {
+				lo, hi = last, last+1
+			}
+		}
+		for hi-lo > 1 {
+			if mid := (lo + hi) / 2; within(i, mid) {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		keep = append(keep, lo)
+		i = lo
+	}
+	return keep
+}
+
+// lineDistance returns the distance from the point to the line segment.
+func lineDistance(pt, a, b [3]float64) float64 {
+	ab, ap := sub3(b, a), sub3(pt, a)
+	l2 := ab[0]*ab[0] + ab[1]*ab[1] + ab[2]*ab[2]
+	if l2 == 0 {
+		return length3(ap)
+	}
+	t := math.Max(0, math.Min(1, (ap[0]*ab[0]+ap[1]*ab[1]+ap[2]*ab[2])/l2))
+	return length3([3]float64{ap[0] - t*ab[0], ap[1] - t*ab[1], ap[2] - t*ab[2]})
+}
+
+func sub3(a, b [3]float64) [3]float64 {
+	return [3]float64{a[0] - b[0], a[1] - b[1], a[2] - b[2]}
+}
+
+func mid3(a, b [3]float64) [3]float64 {
+	return [3]float64{(a[0] + b[0]) / 2, (a[1] + b[1]) / 2, (a[2] + b[2]) / 2}
+}
+
+func length3(v [3]float64) float64 {
+	return math.Sqrt(v[0]*v[0] + v[1]*v[1] + v[2]*v[2])
+}
+
+func dist3(a, b [3]float64) float64 {
+	return length3(sub3(a, b))
+}
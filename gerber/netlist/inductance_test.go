@@ -0,0 +1,91 @@
+package netlist
+
+import (
+	"math"
+	"testing"
+)
+
+// loop returns a circular loop of the radius at the height.
+func loop(r, z, width float64) *Path {
+	const n = 720
+	p := &Path{}
+	for i := 0; i < n; i++ {
+		a1, a2 := 2*math.Pi*float64(i)/n, 2*math.Pi*float64(i+1)/n
+		p.Segments = append(p.Segments, Segment{
+			P1:    [3]float64{r * math.Cos(a1), r * math.Sin(a1), z},
+			P2:    [3]float64{r * math.Cos(a2), r * math.Sin(a2), z},
+			Width: width,
+		})
+	}
+	return p
+}
+
+// spiral returns an Archimedean spiral from the inner to the outer radius.
+func spiral(turns, rIn, rOut, width float64) *Path {
+	n := int(360 * turns)
+	p := &Path{}
+	point := func(i int) [3]float64 {
+		t := float64(i) / float64(n)
+		r, a := rIn+t*(rOut-rIn), 2*math.Pi*turns*t
+		return [3]float64{r * math.Cos(a), r * math.Sin(a), 0}
+	}
+	for i := 0; i < n; i++ {
+		p.Segments = append(p.Segments, Segment{P1: point(i), P2: point(i + 1), Width: width})
+	}
+	return p
+}
+
+func TestPath_Inductance(t *testing.T) {
+	const r, width = 10, 0.5
+	gmd := 0.2235 * (width + ozThickness)
+	want := mu0 * r * 1e-3 * (math.Log(8*r/gmd) - 2)
+	if got := loop(r, 0, width).Inductance(nil); !near(got, want, 0.03) {
+		t.Errorf("loop Inductance = %v, want %v", got, want)
+	}
+
+	// A planar spiral agrees with the closed-form approximations.
+	const turns, rIn, rOut = 10, 5, 15
+	got := spiral(turns, rIn, rOut, 0.5).Inductance(nil)
+	for name, want := range map[string]float64{
+		"Wheeler":      Wheeler(turns, rIn, rOut),
+		"CurrentSheet": CurrentSheet(turns, rIn, rOut),
+	} {
+		if !near(got, want, 0.15) {
+			t.Errorf("spiral Inductance = %v, want %v (%v)", got, want, name)
+		}
+	}
+}
+
+func TestPath_Inductance_Filaments(t *testing.T) {
+	const turns, rIn, rOut = 40, 5, 29
+	p := spiral(turns, rIn, rOut, 0.3)
+	want := Wheeler(turns, rIn, rOut)
+	if sheet := CurrentSheet(turns, rIn, rOut); !near(sheet, want, 0.02) {
+		t.Fatalf("CurrentSheet = %v, want %v", sheet, want)
+	}
+
+	// Ten filaments per turn are enough...
+	if got := p.Inductance(&Copper{Filaments: 10 * turns}); !near(got, want, 0.05) {
+		t.Errorf("Inductance(10 per turn) = %v, want %v", got, want)
+	}
+	// ...but with three per turn they cut across the turns.
+	if got := p.Inductance(&Copper{Filaments: 3 * turns}); got > want/2 {
+		t.Errorf("Inductance(3 per turn) = %v, want less than %v", got, want/2)
+	}
+}
+
+func TestMutualInductance(t *testing.T) {
+	a := loop(10, 0, 0.5)
+	var last float64
+	for i, z := range []float64{1.6, 3.2, 10} {
+		b := loop(10, z, 0.5)
+		m := MutualInductance(a, b, nil)
+		if r := MutualInductance(b, a, nil); !near(r, m, 1e-9) {
+			t.Errorf("MutualInductance(z=%v) = %v one way and %v the other", z, m, r)
+		}
+		if m <= 0 || m >= a.Inductance(nil) || i > 0 && m >= last {
+			t.Errorf("MutualInductance(z=%v) = %v, want less than %v", z, m, last)
+		}
+		last = m
+	}
+}
@@ -0,0 +1,332 @@
+// Package netlist works out the electrical connectivity of a design
+// from its geometry alone: the islands of copper on each copper layer
+// that touch or overlap, joined between the layers by the plated holes
+// of the drill layers, are its nets.
+//
+// Primitives may be labeled with the names of the nets that they are
+// meant to be on (such as the terminal pads of each winding of a
+// coil), which then name the nets. A net with several labels is a short
+// between them and a label found on several nets is an open circuit.
+//
+// Each net may also be analyzed as a network of resistors, with the
+// current flowing along the centerlines of its traces, for its length,
+// DC resistance and (by numerical integration of Neumann's formula)
+// the inductance of windings such as those of the coil package.
+package netlist
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// touch is the largest gap between copper that is taken to be
+// connected, allowing for rounding where flattened curves meet.
+const touch = 1e-6
+
+// Conductor is a piece of copper (or a plated hole) on a net.
+type Conductor struct {
+	// Layer is the copper or drill layer of the conductor.
+	Layer *gerber.Layer
+	// Primitive is the primitive (as added to the layer) that
+	// the conductor is drawn by.
+	Primitive gerber.Primitive
+	// Feature is the geometry of the conductor, which is all of the
+	// primitive unless it is made up of several separate pieces.
+	Feature *gerber.Feature
+}
+
+// Net represents a set of connected conductors.
+type Net struct {
+	// Name is the name of the net: its labels joined by "+", or "N1",
+	// "N2", ... for unlabeled nets, numbered in order.
+	Name string
+	// Labels are the distinct labels of the net's primitives, sorted.
+	Labels []string
+	// Conductors are the pieces of copper of the net, ordered by layer
+	// and then by primitive, followed by its plated holes.
+	Conductors []*Conductor
+
+	nl *Netlist
+}
+
+// Netlist represents the nets of a design.
+type Netlist struct {
+	// Nets are ordered by their first conductor.
+	Nets []*Net
+
+	nets   map[gerber.Primitive][]*Net // the distinct nets of each primitive
+	copper []*gerber.Layer             // from the top of the board down
+}
+
+// node is a piece of copper or a hole, in a union-find forest.
+type node struct {
+	parent *node
+	rank   int
+	c      *Conductor
+	copper bool
+}
+
+func (n *node) root() *node {
+	for n.parent != n {
+		n.parent = n.parent.parent // path halving
+		n = n.parent
+	}
+	return n
+}
+
+func union(a, b *node) {
+	a, b = a.root(), b.root()
+	switch {
+	case a == b:
+	case a.rank < b.rank:
+		a.parent = b
+	case a.rank > b.rank:
+		b.parent = a
+	default:
+		b.parent = a
+		a.rank++
+	}
+}
+
+// Extract returns the nets of the design. Labels (which may be nil)
+// map primitives on the copper and drill layers to the names of the
+// nets that they are meant to be on.
+//
+// Copper that touches or overlaps other copper on the same layer is
+// connected to it. Drilled holes are plated: each hole connects all of
+// the copper that it touches on every layer. Holes touching no copper
+// are not on any net.
+func Extract(g *gerber.Gerber, labels map[gerber.Primitive]string) *Netlist {
+	var nodes []*node
+	newNode := func(layer *gerber.Layer, p gerber.Primitive, f *gerber.Feature, copper bool) *node {
+		n := &node{c: &Conductor{Layer: layer, Primitive: p, Feature: f}, copper: copper}
+		n.parent = n
+		nodes = append(nodes, n)
+		return n
+	}
+
+	// byLayer are the nodes of each copper layer by primitive index.
+	byLayer := map[*gerber.Layer]map[int][]*node{}
+	var copper, drills []*gerber.Layer
+	for _, layer := range g.Layers {
+		switch {
+		case layer.Role == gerber.DrillRole:
+			drills = append(drills, layer)
+			continue
+		case !layer.Role.IsCopper():
+			continue
+		}
+		byIndex := map[int][]*node{}
+		for i, p := range layer.Primitives {
+			for _, f := range gerber.Features(p) {
+				byIndex[i] = append(byIndex[i], newNode(layer, p, f, true))
+			}
+		}
+		byLayer[layer] = byIndex
+		copper = append(copper, layer)
+		joinLayer(layer, byIndex)
+	}
+
+	for _, layer := range drills {
+		for _, p := range layer.Primitives {
+			for _, f := range gerber.Features(p) {
+				h := newNode(layer, p, f, false)
+				for _, c := range copper {
+					for _, i := range c.Search(f.MBB()) {
+						for _, n := range byLayer[c][i] {
+							if connected(h.c.Feature, n.c.Feature) {
+								union(h, n)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	nl := newNetlist(nodes, labels)
+	nl.copper = copper
+	sort.SliceStable(nl.copper, func(i, j int) bool {
+		return depth(nl.copper[i]) < depth(nl.copper[j])
+	})
+	return nl
+}
+
+// depth orders the copper layers from the top of the board down.
+func depth(layer *gerber.Layer) int {
+	switch layer.Role {
+	case gerber.TopCopperRole:
+		return 0
+	case gerber.BottomCopperRole:
+		return math.MaxInt32
+	}
+	n, _ := strconv.Atoi(strings.TrimPrefix(string(layer.Role), "Layer"))
+	return n
+}
+
+// joinLayer joins the touching copper on the layer.
+func joinLayer(layer *gerber.Layer, byIndex map[int][]*node) {
+	for i := range layer.Primitives {
+		ns := byIndex[i]
+		// The pieces of a compound primitive may touch each other.
+		for a, n := range ns {
+			for _, m := range ns[a+1:] {
+				if connected(n.c.Feature, m.c.Feature) {
+					union(n, m)
+				}
+			}
+		}
+		for _, j := range layer.Overlaps(i, touch) {
+			if j < i {
+				continue
+			}
+			for _, n := range ns {
+				for _, m := range byIndex[j] {
+					if connected(n.c.Feature, m.c.Feature) {
+						union(n, m)
+					}
+				}
+			}
+		}
+	}
+}
+
+// connected reports whether the features touch or overlap.
+func connected(f, g *gerber.Feature) bool {
+	a, b := f.MBB(), g.MBB()
+	if math.Max(math.Max(b.Min[0]-a.Max[0], a.Min[0]-b.Max[0]), math.Max(b.Min[1]-a.Max[1], a.Min[1]-b.Max[1])) > touch {
+		return false
+	}
+	d, _, _ := f.Distance(g)
+	return d <= touch
+}
+
+// newNetlist groups the nodes into nets and names them.
+func newNetlist(nodes []*node, labels map[gerber.Primitive]string) *Netlist {
+	nl := &Netlist{nets: map[gerber.Primitive][]*Net{}}
+	byRoot := map[*node]*Net{}
+	hasCopper := map[*node]bool{}
+	for _, n := range nodes {
+		if n.copper {
+			hasCopper[n.root()] = true
+		}
+	}
+	for _, n := range nodes {
+		r := n.root()
+		if !hasCopper[r] {
+			continue
+		}
+		net, ok := byRoot[r]
+		if !ok {
+			net = &Net{nl: nl}
+			byRoot[r] = net
+			nl.Nets = append(nl.Nets, net)
+		}
+		net.Conductors = append(net.Conductors, n.c)
+		if label, ok := labels[n.c.Primitive]; ok && !contains(net.Labels, label) {
+			net.Labels = append(net.Labels, label)
+		}
+		if ps := nl.nets[n.c.Primitive]; !containsNet(ps, net) {
+			nl.nets[n.c.Primitive] = append(ps, net)
+		}
+	}
+
+	var unlabeled int
+	for _, net := range nl.Nets {
+		if len(net.Labels) == 0 {
+			unlabeled++
+			net.Name = fmt.Sprintf("N%v", unlabeled)
+			continue
+		}
+		sort.Strings(net.Labels)
+		net.Name = strings.Join(net.Labels, "+")
+	}
+	return nl
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsNet(list []*Net, net *Net) bool {
+	for _, v := range list {
+		if v == net {
+			return true
+		}
+	}
+	return false
+}
+
+// NetOf returns the net of the primitive, or nil if it
+// is on no net or (in pieces) on several of them.
+func (nl *Netlist) NetOf(p gerber.Primitive) *Net {
+	if nets := nl.nets[p]; len(nets) == 1 {
+		return nets[0]
+	}
+	return nil
+}
+
+// Map returns the names of the nets of the primitives that are each on
+// a single net, such as for checking the clearance between the nets
+// with the drc package. Note that a short between two nets makes them
+// one and the same net.
+func (nl *Netlist) Map() map[gerber.Primitive]string {
+	result := map[gerber.Primitive]string{}
+	for p := range nl.nets {
+		if net := nl.NetOf(p); net != nil {
+			result[p] = net.Name
+		}
+	}
+	return result
+}
+
+// Shorts returns the nets that have more than one label.
+func (nl *Netlist) Shorts() []*Net {
+	var result []*Net
+	for _, net := range nl.Nets {
+		if len(net.Labels) > 1 {
+			result = append(result, net)
+		}
+	}
+	return result
+}
+
+// Labeled returns the nets with the label.
+func (nl *Netlist) Labeled(label string) []*Net {
+	var result []*Net
+	for _, net := range nl.Nets {
+		if contains(net.Labels, label) {
+			result = append(result, net)
+		}
+	}
+	return result
+}
+
+// Opens returns the sorted labels that are found on more than one net,
+// such as those of both ends of a broken winding.
+func (nl *Netlist) Opens() []string {
+	count := map[string]int{}
+	for _, net := range nl.Nets {
+		for _, label := range net.Labels {
+			count[label]++
+		}
+	}
+	var result []string
+	for label, n := range count {
+		if n > 1 {
+			result = append(result, label)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
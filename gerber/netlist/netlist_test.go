@@ -0,0 +1,116 @@
+package netlist
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/coil"
+)
+
+// windings returns a design with two windings between pairs of pads:
+// W1 on the top layer and W2 passing through a via to the bottom layer.
+func windings(via bool) (*gerber.Gerber, *gerber.Layer, map[gerber.Primitive]string, gerber.Primitive) {
+	g := gerber.New("test")
+	top := g.TopCopper()
+	bottom := g.BottomCopper()
+	drill := g.Drill()
+
+	w1a, w1b := gerber.Circle(gerber.Pt{0, 0}, 1), gerber.Circle(gerber.Pt{10, 0}, 1)
+	w2a, w2b := gerber.Circle(gerber.Pt{0, 5}, 1), gerber.Circle(gerber.Pt{10, 5}, 1)
+	top.Add(w1a, w1b, w2a, gerber.Line(0, 0, 10, 0, gerber.CircleShape, 0.2), gerber.Line(0, 5, 5, 5, gerber.CircleShape, 0.2))
+	bottom.Add(w2b)
+	if via {
+		top.Add(gerber.Circle(gerber.Pt{5, 5}, 0.6))
+		bottom.Add(gerber.Circle(gerber.Pt{5, 5}, 0.6))
+		drill.Add(gerber.Circle(gerber.Pt{5, 5}, 0.3))
+	}
+	w2 := gerber.Line(5, 5, 10, 5, gerber.CircleShape, 0.2)
+	bottom.Add(w2)
+	// An unplated mounting hole.
+	drill.Add(gerber.Circle(gerber.Pt{20, 20}, 3))
+
+	labels := map[gerber.Primitive]string{w1a: "W1", w1b: "W1", w2a: "W2", w2b: "W2"}
+	return g, top, labels, w2
+}
+
+func names(nets []*Net) []string {
+	var result []string
+	for _, net := range nets {
+		result = append(result, net.Name)
+	}
+	return result
+}
+
+func TestExtract(t *testing.T) {
+	g, _, labels, w2 := windings(true)
+	nl := Extract(g, labels)
+	if got, want := names(nl.Nets), []string{"W1", "W2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("nets = %v, want %v", got, want)
+	}
+	if shorts, opens := nl.Shorts(), nl.Opens(); len(shorts) != 0 || len(opens) != 0 {
+		t.Errorf("Shorts, Opens = %v, %v, want none", names(shorts), opens)
+	}
+	if net := nl.NetOf(w2); net == nil || net.Name != "W2" {
+		t.Errorf("NetOf(bottom trace) = %v, want W2", net)
+	}
+	// W2 is made up of its pads, traces, both via pads and the via hole.
+	if got := len(nl.Labeled("W2")[0].Conductors); got != 7 {
+		t.Errorf("W2 has %v conductors, want 7", got)
+	}
+	if got := len(nl.Map()); got != 10 {
+		t.Errorf("Map has %v primitives, want 10", got)
+	}
+}
+
+func TestExtract_Short(t *testing.T) {
+	g, top, labels, _ := windings(true)
+	top.Add(gerber.Line(3, 0, 3, 5, gerber.CircleShape, 0.2))
+
+	nl := Extract(g, labels)
+	if got, want := names(nl.Shorts()), []string{"W1+W2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Shorts = %v, want %v", got, want)
+	}
+	if len(nl.Nets) != 1 {
+		t.Errorf("nets = %v, want 1", names(nl.Nets))
+	}
+}
+
+func TestExtract_Open(t *testing.T) {
+	g, _, labels, _ := windings(false)
+
+	nl := Extract(g, labels)
+	if got, want := nl.Opens(), []string{"W2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Opens = %v, want %v", got, want)
+	}
+	if got, want := names(nl.Labeled("W2")), []string{"W2", "W2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Labeled(W2) = %v, want %v", got, want)
+	}
+}
+
+func TestExtract_Coil(t *testing.T) {
+	for _, topology := range []coil.Topology{coil.Series, coil.Parallel} {
+		t.Run(topology.String(), func(t *testing.T) {
+			g, err := coil.New(&coil.Config{Turns: 3, Layers: 4, Topology: topology})
+			if err != nil {
+				t.Fatal(err)
+			}
+			nl := Extract(g, nil)
+			// Every winding runs from pad to pad through the vias.
+			if len(nl.Nets) != 1 {
+				t.Fatalf("nets = %v, want 1", names(nl.Nets))
+			}
+			var holes int
+			for _, c := range nl.Nets[0].Conductors {
+				if c.Layer.Role == gerber.DrillRole {
+					holes++
+				}
+			}
+			for _, layer := range g.Layers {
+				if layer.Role == gerber.DrillRole && holes != len(layer.Primitives) {
+					t.Errorf("net has %v holes, want %v", holes, len(layer.Primitives))
+				}
+			}
+		})
+	}
+}
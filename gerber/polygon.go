@@ -0,0 +1,171 @@
+package gerber
+
+import (
+	"math"
+	"sort"
+)
+
+// PolygonWithHoles returns a filled polygon primitive with the given
+// outer contour and inner contours (holes), such as an annular ring
+// or a logo. All points are relative to offset.
+// All dimensions are in millimeters.
+func PolygonWithHoles(offset Pt, points []Pt, holes [][]Pt) *PolygonT {
+	return &PolygonT{
+		Offset: offset,
+		Points: points,
+		Filled: true,
+		Holes:  holes,
+	}
+}
+
+// Contains reports whether the point (in absolute coordinates) lies
+// within the polygon (inside its outer contour and outside its holes)
+// using the even-odd rule.
+func (p *PolygonT) Contains(pt Pt) bool {
+	pt = Pt{pt[0] - p.Offset[0], pt[1] - p.Offset[1]}
+	inside := pointInContour(pt, p.Points)
+	for _, hole := range p.Holes {
+		if pointInContour(pt, hole) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// pointInContour reports whether pt lies within the closed contour
+// using the even-odd (crossing number) rule.
+func pointInContour(pt Pt, contour []Pt) bool {
+	var inside bool
+	n := len(contour)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := contour[i], contour[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) &&
+			pt[0] < a[0]+(pt[1]-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// signedArea returns the signed area of the closed contour,
+// which is positive if it runs counter-clockwise.
+func signedArea(contour []Pt) float64 {
+	var area float64
+	n := len(contour)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		area += (contour[j][0] - contour[i][0]) * (contour[j][1] + contour[i][1])
+	}
+	return 0.5 * area
+}
+
+// openContour returns the contour without a repeated closing point.
+func openContour(contour []Pt) []Pt {
+	if n := len(contour); n > 1 && contour[0] == contour[n-1] {
+		return contour[:n-1]
+	}
+	return contour
+}
+
+// segmentsCross reports whether the segments p1-p2 and q1-q2 touch or
+// cross anywhere other than at a shared end point.
+func segmentsCross(p1, p2, q1, q2 Pt) bool {
+	if p1 == q1 || p1 == q2 || p2 == q1 || p2 == q2 {
+		return false
+	}
+	orient := func(a, b, c Pt) float64 {
+		return (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	}
+	d1, d2 := orient(q1, q2, p1), orient(q1, q2, p2)
+	d3, d4 := orient(p1, p2, q1), orient(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	onSegment := func(a, b, c Pt) bool {
+		return math.Min(a[0], b[0]) <= c[0] && c[0] <= math.Max(a[0], b[0]) &&
+			math.Min(a[1], b[1]) <= c[1] && c[1] <= math.Max(a[1], b[1])
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+// cutIns returns a single contour that joins each of the holes to the
+// outer contour with a cut-in: a pair of coincident edges running from
+// the outer contour to the hole and back. The holes are reversed as
+// needed to run opposite to the outer contour, so the result is correct
+// under both the even-odd and non-zero fill rules.
+func (p *PolygonT) cutIns() []Pt {
+	merged := append([]Pt(nil), openContour(p.Points)...)
+	outerCCW := signedArea(merged) > 0
+
+	var holes [][]Pt
+	for _, hole := range p.Holes {
+		hole = append([]Pt(nil), openContour(hole)...)
+		if len(hole) < 3 {
+			continue
+		}
+		if (signedArea(hole) > 0) == outerCCW {
+			for i, j := 0, len(hole)-1; i < j; i, j = i+1, j-1 {
+				hole[i], hole[j] = hole[j], hole[i]
+			}
+		}
+		holes = append(holes, hole)
+	}
+	// Join the holes from right to left, starting each cut-in at the
+	// rightmost point of the hole, so that earlier cut-ins are never
+	// blocked by holes that have not been joined yet.
+	rightmost := func(hole []Pt) int {
+		best := 0
+		for i, pt := range hole {
+			if pt[0] > hole[best][0] {
+				best = i
+			}
+		}
+		return best
+	}
+	sort.SliceStable(holes, func(i, j int) bool {
+		return holes[i][rightmost(holes[i])][0] > holes[j][rightmost(holes[j])][0]
+	})
+
+	for hi, hole := range holes {
+		mi := rightmost(hole)
+		m := hole[mi]
+		blocked := func(pt Pt) bool {
+			for _, contour := range append([][]Pt{merged}, holes[hi:]...) {
+				n := len(contour)
+				for i, j := 0, n-1; i < n; j, i = i, i+1 {
+					if segmentsCross(m, pt, contour[j], contour[i]) {
+						return true
+					}
+				}
+			}
+			mid := Pt{0.5 * (m[0] + pt[0]), 0.5 * (m[1] + pt[1])}
+			return !p.Contains(Pt{mid[0] + p.Offset[0], mid[1] + p.Offset[1]})
+		}
+
+		// Use the nearest visible point of the merged contour.
+		best, bestDist := -1, math.Inf(1)
+		for i, pt := range merged {
+			d := math.Hypot(pt[0]-m[0], pt[1]-m[1])
+			if d < bestDist && !blocked(pt) {
+				best, bestDist = i, d
+			}
+		}
+		if best < 0 {
+			// This can only happen for invalid (overlapping) holes.
+			// Fall back to the nearest point regardless.
+			for i, pt := range merged {
+				if d := math.Hypot(pt[0]-m[0], pt[1]-m[1]); d < bestDist {
+					best, bestDist = i, d
+				}
+			}
+		}
+
+		var next []Pt
+		next = append(next, merged[:best+1]...)
+		next = append(next, hole[mi:]...)
+		next = append(next, hole[:mi+1]...)
+		next = append(next, merged[best:]...)
+		merged = next
+	}
+	return merged
+}
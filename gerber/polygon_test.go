@@ -0,0 +1,225 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var regionCoordRE = regexp.MustCompile(`X-?\d+Y-?\d+`)
+
+// checkRegions fails the test unless each G36/G37 region of the Gerber
+// output is a single contour that ends by drawing back to its start.
+// It returns the number of regions.
+func checkRegions(t *testing.T, out string) int {
+	t.Helper()
+	var n int
+	var in bool
+	var start, last string
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case line == "G36*":
+			n++
+			in, start, last = true, "", ""
+		case !in:
+		case line == "G37*":
+			if start == "" || last != start {
+				t.Errorf("region %v from %v ends at %v, want it closed", n, start, last)
+			}
+			in = false
+		case strings.HasSuffix(line, "D02*"):
+			if start != "" {
+				t.Errorf("region %v moves to %v after starting at %v", n, line, start)
+			}
+			start = regionCoordRE.FindString(line)
+		case strings.HasSuffix(line, "D01*"):
+			last = regionCoordRE.FindString(line)
+		}
+	}
+	return n
+}
+
+// squareContour returns a square contour of side 2*r centered at c,
+// counter-clockwise unless cw is set.
+func squareContour(c Pt, r float64, cw bool) []Pt {
+	pts := []Pt{{c[0] - r, c[1] - r}, {c[0] + r, c[1] - r}, {c[0] + r, c[1] + r}, {c[0] - r, c[1] + r}}
+	if cw {
+		pts[1], pts[3] = pts[3], pts[1]
+	}
+	return pts
+}
+
+// circleContour returns a circular contour of n points.
+func circleContour(c Pt, r float64, n int) []Pt {
+	var pts []Pt
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		pts = append(pts, Pt{c[0] + r*math.Cos(a), c[1] + r*math.Sin(a)})
+	}
+	return pts
+}
+
+// windingNumber returns the winding number of the closed contour about pt.
+func windingNumber(pt Pt, contour []Pt) int {
+	var wn int
+	n := len(contour)
+	for i := 0; i < n; i++ {
+		a, b := contour[i], contour[(i+1)%n]
+		cross := (b[0]-a[0])*(pt[1]-a[1]) - (pt[0]-a[0])*(b[1]-a[1])
+		if a[1] <= pt[1] && b[1] > pt[1] && cross > 0 {
+			wn++
+		} else if a[1] > pt[1] && b[1] <= pt[1] && cross < 0 {
+			wn--
+		}
+	}
+	return wn
+}
+
+func TestPolygonT_Contains(t *testing.T) {
+	ring := PolygonWithHoles(Pt{10, 10}, squareContour(Pt{0, 0}, 5, false), [][]Pt{squareContour(Pt{0, 0}, 2, false)})
+	tests := []struct {
+		pt   Pt
+		want bool
+	}{
+		{pt: Pt{10, 10}, want: false},
+		{pt: Pt{13, 10}, want: true},
+		{pt: Pt{6, 6}, want: true},
+		{pt: Pt{16, 10}, want: false},
+		{pt: Pt{0, 0}, want: false},
+	}
+	for _, tt := range tests {
+		if got := ring.Contains(tt.pt); got != tt.want {
+			t.Errorf("Contains(%v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+}
+
+func TestPolygonT_CutIns(t *testing.T) {
+	tests := []struct {
+		name  string
+		outer []Pt
+		holes [][]Pt
+	}{
+		{
+			name:  "square ring",
+			outer: squareContour(Pt{0, 0}, 5, false),
+			holes: [][]Pt{squareContour(Pt{0, 0}, 2, false)},
+		},
+		{
+			name:  "clockwise outer, closed contours",
+			outer: append(squareContour(Pt{0, 0}, 5, true), Pt{-5, -5}),
+			holes: [][]Pt{append(squareContour(Pt{0, 0}, 2, true), Pt{-2, -2})},
+		},
+		{
+			name:  "annulus",
+			outer: circleContour(Pt{0, 0}, 5, 64),
+			holes: [][]Pt{circleContour(Pt{0, 0}, 3, 48)},
+		},
+		{
+			name:  "three holes in a row",
+			outer: []Pt{{-10, -3}, {10, -3}, {10, 3}, {-10, 3}},
+			holes: [][]Pt{
+				squareContour(Pt{-6, 0}, 1.5, false),
+				squareContour(Pt{0, 0}, 1.5, true),
+				circleContour(Pt{6, 0}, 1.5, 16),
+			},
+		},
+		{
+			name:  "hole shadowed by another hole",
+			outer: squareContour(Pt{0, 0}, 10, false),
+			holes: [][]Pt{
+				{{2, -8}, {4, -8}, {4, 8}, {2, 8}}, // a tall wall to the right
+				squareContour(Pt{-2, 0}, 1, false),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PolygonWithHoles(Pt{0, 0}, tt.outer, tt.holes)
+			merged := p.cutIns()
+			mbb := p.MBB()
+			// Sample a grid of points (offset to avoid the cut-in edges)
+			// and compare the merged contour against the polygon.
+			for x := mbb.Min[0] - 1; x <= mbb.Max[0]+1; x += 0.173 {
+				for y := mbb.Min[1] - 1; y <= mbb.Max[1]+1; y += 0.131 {
+					pt := Pt{x, y}
+					want := p.Contains(pt)
+					if got := pointInContour(pt, merged); got != want {
+						t.Fatalf("even-odd at %v = %v, want %v", pt, got, want)
+					}
+					if got := windingNumber(pt, merged) != 0; got != want {
+						t.Fatalf("non-zero at %v = %v, want %v", pt, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPolygonT_WriteGerber_Holes(t *testing.T) {
+	holes := [][]Pt{
+		squareContour(Pt{-5, 0}, 2, false),
+		squareContour(Pt{5, 0}, 2, false),
+	}
+	tests := []struct {
+		name      string
+		clear     bool
+		holes     [][]Pt
+		wantCount map[string]int
+	}{
+		{
+			name:      "cut-ins",
+			holes:     holes,
+			wantCount: map[string]int{"G36*": 1, "%LPC*%": 0, "D01*": 3 + 2 + 4 + 2 + 4 + 1},
+		},
+		{
+			name:      "clear holes",
+			clear:     true,
+			holes:     holes,
+			wantCount: map[string]int{"G36*": 3, "%LPC*%": 1, "%LPD*%": 1},
+		},
+		{
+			name:      "degenerate cut-ins",
+			holes:     [][]Pt{nil, {{1, 1}}, {{1, 1}, {2, 2}}, holes[0]},
+			wantCount: map[string]int{"G36*": 1, "D01*": 3 + 2 + 4 + 1},
+		},
+		{
+			name:      "degenerate clear holes",
+			clear:     true,
+			holes:     [][]Pt{nil, {{1, 1}}, {{1, 1}, {2, 2}}, holes[0]},
+			wantCount: map[string]int{"G36*": 2, "D01*": 4 + 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := PolygonWithHoles(Pt{0, 0}, squareContour(Pt{0, 0}, 10, false), tt.holes)
+			p.ClearHoles = tt.clear
+			var buf bytes.Buffer
+			if err := p.WriteGerber(&buf, 12); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			checkRegions(t, got)
+			for s, want := range tt.wantCount {
+				if n := strings.Count(got, s); n != want {
+					t.Errorf("count(%q) = %v, want %v\n%v", s, n, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestPolygonT_Transform_Holes(t *testing.T) {
+	p := PolygonWithHoles(Pt{1, 1}, squareContour(Pt{0, 0}, 5, false), [][]Pt{squareContour(Pt{0, 0}, 2, false)})
+	got := p.Transform(Translate(10, 0)).(*PolygonT)
+	if len(got.Holes) != 1 || !got.Filled {
+		t.Fatalf("Transform lost the holes: %+v", got)
+	}
+	if got.Contains(Pt{11, 1}) || !got.Contains(Pt{14, 1}) {
+		t.Errorf("transformed polygon does not have its hole at (11,1)")
+	}
+}
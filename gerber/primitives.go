@@ -29,6 +29,18 @@ type Primitive interface {
 	Aperture() *Aperture
 	// MBB returns the minimum bounding box in millimeters.
 	MBB() MBB
+	// Transform returns a transformed copy of the primitive.
+	// The original primitive is left unchanged.
+	Transform(xf Transform) Primitive
+}
+
+// Compound is a Primitive made up of other primitives, such as
+// a placed Group. Layers write (and view) each of the primitives
+// individually using their own apertures.
+type Compound interface {
+	Primitive
+	// Primitives returns the primitives making up the compound.
+	Primitives() []Primitive
 }
 
 // Aperture represents the nature of the primitive
@@ -85,7 +97,11 @@ type ArcT struct {
 	StartAngle float64
 	EndAngle   float64
 	Thickness  float64
-	mbb        *MBB // cached minimum bounding box
+	// Tolerance is the maximum deviation (sagitta) in millimeters
+	// when flattening the arc. Zero means DefaultTolerance.
+	Tolerance float64
+	xf        *Transform // optional transformation
+	mbb       *MBB       // cached minimum bounding box
 }
 
 // Arc returns an arc primitive.
@@ -112,30 +128,73 @@ func Arc(
 	}
 }
 
-// WriteGerber writes the primitive to the Gerber file.
-func (a *ArcT) WriteGerber(w io.Writer, apertureIndex int) error {
-	delta := a.EndAngle - a.StartAngle
-	length := delta * a.Radius
-	// Resolution of segments is 0.1mm
-	segments := int(0.5+length*10.0) + 1
-	delta /= float64(segments)
-
-	angle := float64(a.StartAngle)
-	for i := 0; i < segments; i++ {
-		x1 := a.Center[0] + a.XScale*math.Cos(angle)*a.Radius
-		y1 := a.Center[1] + a.YScale*math.Sin(angle)*a.Radius
+func (a *ArcT) tolerance() float64 {
+	if a.Tolerance > 0 {
+		return a.Tolerance
+	}
+	return DefaultTolerance
+}
 
-		angle += delta
+// axes returns the (transformed) center of the arc and the vectors
+// u and v such that PointAt(angle) = c + u*cos(angle) + v*sin(angle).
+func (a *ArcT) axes() (c, u, v Pt) {
+	c = a.Center
+	u = Pt{a.XScale * a.Radius, 0}
+	v = Pt{0, a.YScale * a.Radius}
+	if a.xf != nil {
+		c = a.xf.Apply(c)
+		u = Pt{a.xf.A * u[0], a.xf.D * u[0]}
+		v = Pt{a.xf.B * v[1], a.xf.E * v[1]}
+	}
+	return c, u, v
+}
 
-		x2 := a.Center[0] + a.XScale*math.Cos(angle)*a.Radius
-		y2 := a.Center[1] + a.YScale*math.Sin(angle)*a.Radius
+// Flatten returns the points along the arc, from StartAngle to EndAngle,
+// such that no chord deviates from the arc by more than its tolerance.
+func (a *ArcT) Flatten() []Pt {
+	_, u, v := a.axes()
+	// The chords of the unit circle are mapped onto the arc by a linear
+	// transformation, which stretches their deviation by at most its
+	// largest singular value.
+	s2 := u[0]*u[0] + u[1]*u[1] + v[0]*v[0] + v[1]*v[1]
+	det := u[0]*v[1] - u[1]*v[0]
+	radius := math.Sqrt(0.5 * (s2 + math.Sqrt(math.Max(0, s2*s2-4*det*det))))
+
+	sweep := a.EndAngle - a.StartAngle
+	n := int(math.Ceil(sweep / segmentAngle(radius, a.tolerance())))
+	if n < 1 {
+		n = 1
+	}
+	pts := make([]Pt, 0, n+1)
+	for i := 0; i <= n; i++ {
+		pts = append(pts, a.PointAt(a.StartAngle+sweep*float64(i)/float64(n)))
+	}
+	return pts
+}
 
-		line := Line(x1, y1, x2, y2, a.Shape, a.Thickness)
-		line.WriteGerber(w, apertureIndex)
+// WriteGerber writes the primitive to the Gerber file.
+func (a *ArcT) WriteGerber(w io.Writer, apertureIndex int) error {
+	pts := a.Flatten()
+	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
+	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*pts[0][0]), int(0.5+sf*pts[0][1]))
+	for _, pt := range pts[1:] {
+		fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]))
 	}
 	return nil
 }
 
+// PointAt returns the point on the arc at the given angle (in radians).
+func (a *ArcT) PointAt(angle float64) Pt {
+	pt := Pt{
+		a.Center[0] + a.XScale*math.Cos(angle)*a.Radius,
+		a.Center[1] + a.YScale*math.Sin(angle)*a.Radius,
+	}
+	if a.xf != nil {
+		return a.xf.Apply(pt)
+	}
+	return pt
+}
+
 // Aperture returns the primitive's desired aperture.
 func (a *ArcT) Aperture() *Aperture {
 	return &Aperture{
@@ -144,36 +203,33 @@ func (a *ArcT) Aperture() *Aperture {
 	}
 }
 
+// MBB returns the minimum bounding box of the arc, computed
+// analytically from its end points and any extremes within its sweep.
 func (a *ArcT) MBB() MBB {
 	if a.mbb != nil {
 		return *a.mbb
 	}
 
-	delta := a.EndAngle - a.StartAngle
-	length := delta * a.Radius
-	// Resolution of segments is 0.1mm
-	segments := int(0.5+length*10.0) + 1
-	delta /= float64(segments)
-
-	angle := float64(a.StartAngle)
-	for i := 0; i < segments; i++ {
-		x1 := a.Center[0] + a.XScale*math.Cos(angle)*a.Radius
-		y1 := a.Center[1] + a.YScale*math.Sin(angle)*a.Radius
-
-		angle += delta
-
-		x2 := a.Center[0] + a.XScale*math.Cos(angle)*a.Radius
-		y2 := a.Center[1] + a.YScale*math.Sin(angle)*a.Radius
-
-		line := Line(x1, y1, x2, y2, a.Shape, a.Thickness)
-		mbb := line.MBB()
-		if a.mbb == nil {
-			a.mbb = &mbb
-		} else {
-			a.mbb.Join(&mbb)
+	start := a.PointAt(a.StartAngle)
+	a.mbb = &MBB{Min: start, Max: start}
+	end := a.PointAt(a.EndAngle)
+	a.mbb.Join(&MBB{Min: end, Max: end})
+
+	// Each coordinate c + u*cos(angle) + v*sin(angle) has its
+	// extremes where its derivative vanishes: at atan2(v, u) + k*pi.
+	_, u, v := a.axes()
+	for i := 0; i < 2; i++ {
+		a0 := math.Atan2(v[i], u[i])
+		for k := math.Ceil((a.StartAngle - a0) / math.Pi); a0+k*math.Pi <= a.EndAngle; k++ {
+			pt := a.PointAt(a0 + k*math.Pi)
+			a.mbb.Join(&MBB{Min: pt, Max: pt})
 		}
 	}
 
+	a.mbb.Min[0] -= 0.5 * a.Thickness
+	a.mbb.Min[1] -= 0.5 * a.Thickness
+	a.mbb.Max[0] += 0.5 * a.Thickness
+	a.mbb.Max[1] += 0.5 * a.Thickness
 	return *a.mbb
 }
 
@@ -274,37 +330,116 @@ func (l *LineT) MBB() MBB {
 type PolygonT struct {
 	Offset Pt
 	Points []Pt
-	mbb    *MBB // cached minimum bounding box
+	// Filled polygons are written as regions. Otherwise, the closed
+	// outline of the polygon is stroked with a circular aperture
+	// of the given Thickness.
+	Filled    bool
+	Thickness float64
+	// Holes are the inner contours of the polygon (relative to Offset).
+	// Each hole must lie within the outer contour and not overlap the
+	// other holes.
+	Holes [][]Pt
+	// ClearHoles writes the holes of a filled polygon as clear (%LPC)
+	// regions after the dark outer region, rather than joining them to
+	// the outer contour with cut-ins. Note that clear regions also erase
+	// anything drawn beneath them earlier in the layer.
+	ClearHoles bool
+	mbb        *MBB // cached minimum bounding box
 }
 
 // Polygon returns a polygon primitive.
 // All dimensions are in millimeters.
+// The thickness is only used when the polygon is not filled.
 func Polygon(offset Pt, filled bool, points []Pt, thickness float64) *PolygonT {
 	return &PolygonT{
-		Offset: offset,
-		Points: points,
+		Offset:    offset,
+		Points:    points,
+		Filled:    filled,
+		Thickness: thickness,
 	}
 }
 
 // WriteGerber writes the primitive to the Gerber file.
 func (p *PolygonT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if !p.Filled {
+		return p.writeOutline(w, apertureIndex)
+	}
+	if len(p.Holes) == 0 {
+		p.writeRegion(w, p.Points)
+		return nil
+	}
+	if !p.ClearHoles {
+		p.writeRegion(w, p.cutIns())
+		return nil
+	}
+	p.writeRegion(w, p.Points)
+	io.WriteString(w, "%LPC*%\n")
+	for _, hole := range p.Holes {
+		if hole = openContour(hole); len(hole) < 3 {
+			continue
+		}
+		p.writeRegion(w, hole)
+	}
+	io.WriteString(w, "%LPD*%\n")
+	return nil
+}
+
+// writeRegion writes the (offset) contour as a G36/G37 region.
+func (p *PolygonT) writeRegion(w io.Writer, pts []Pt) {
 	io.WriteString(w, "G54D11*\n")
 	io.WriteString(w, "G36*\n")
-	for i, pt := range p.Points {
+	for i, pt := range pts {
 		if i == 0 {
 			fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(pt[0]+p.Offset[0])), int(0.5+sf*(pt[1]+p.Offset[1])))
 			continue
 		}
 		fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(pt[0]+p.Offset[0])), int(0.5+sf*(pt[1]+p.Offset[1])))
 	}
-	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(p.Points[0][0]+p.Offset[0])), int(0.5+sf*(p.Points[0][1]+p.Offset[1])))
+	// A region's contour must be closed by drawing back to its start.
+	if first, last := pts[0], pts[len(pts)-1]; first != last {
+		fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(first[0]+p.Offset[0])), int(0.5+sf*(first[1]+p.Offset[1])))
+	}
 	io.WriteString(w, "G37*\n")
+}
+
+// writeOutline strokes the closed outline of the polygon
+// and of each of its holes.
+func (p *PolygonT) writeOutline(w io.Writer, apertureIndex int) error {
+	if p.Thickness <= 0 {
+		return fmt.Errorf("outlined polygon must have a positive thickness, got %v", p.Thickness)
+	}
+	if len(p.Points) < 2 {
+		return fmt.Errorf("outlined polygon must have at least two points, got %v", len(p.Points))
+	}
+	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
+	for _, contour := range append([][]Pt{p.Points}, p.Holes...) {
+		if len(contour) == 0 {
+			continue
+		}
+		for i, pt := range contour {
+			if i == 0 {
+				fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*(pt[0]+p.Offset[0])), int(0.5+sf*(pt[1]+p.Offset[1])))
+				continue
+			}
+			fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(pt[0]+p.Offset[0])), int(0.5+sf*(pt[1]+p.Offset[1])))
+		}
+		if first, last := contour[0], contour[len(contour)-1]; first != last {
+			fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*(first[0]+p.Offset[0])), int(0.5+sf*(first[1]+p.Offset[1])))
+		}
+	}
 	return nil
 }
 
-// Aperture returns nil for PolygonT because it uses the default aperture.
+// Aperture returns nil for filled polygons because they use the
+// default aperture, or the stroke aperture for outlined polygons.
 func (p *PolygonT) Aperture() *Aperture {
-	return nil
+	if p.Filled {
+		return nil
+	}
+	return &Aperture{
+		Shape: CircleShape,
+		Size:  p.Thickness,
+	}
 }
 
 func (p *PolygonT) MBB() MBB {
@@ -320,6 +455,15 @@ func (p *PolygonT) MBB() MBB {
 		}
 		p.mbb.Join(v)
 	}
+	if p.mbb == nil {
+		p.mbb = &MBB{Min: p.Offset, Max: p.Offset}
+	}
+	if !p.Filled {
+		p.mbb.Min[0] -= 0.5 * p.Thickness
+		p.mbb.Min[1] -= 0.5 * p.Thickness
+		p.mbb.Max[0] += 0.5 * p.Thickness
+		p.mbb.Max[1] += 0.5 * p.Thickness
+	}
 
 	return *p.mbb
 }
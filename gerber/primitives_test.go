@@ -1,6 +1,7 @@
 package gerber
 
 import (
+	"bytes"
 	"math"
 	"testing"
 )
@@ -22,7 +23,7 @@ func TestArcT_Primitive(t *testing.T) {
 }
 
 func TestArcT_MBB(t *testing.T) {
-	const eps = 1e-3
+	const eps = 1e-9
 	tests := []struct {
 		name string
 		p    *ArcT
@@ -78,6 +79,22 @@ func TestArcT_MBB(t *testing.T) {
 			p:    Arc(Pt{10, 20}, 10, CircleShape, 1, 1, 270, 360, 2),
 			want: MBB{Min: Pt{9, 9}, Max: Pt{21, 21}},
 		},
+		{
+			name: "arc spanning a quadrant boundary",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 45, 135, 0),
+			want: MBB{Min: Pt{-5 * math.Sqrt2, 5 * math.Sqrt2}, Max: Pt{5 * math.Sqrt2, 10}},
+		},
+		{
+			name: "ellipse",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 2, 0.5, 0, 360, 0),
+			want: MBB{Min: Pt{-20, -5}, Max: Pt{20, 5}},
+		},
+		{
+			name: "rotated ellipse",
+			p:    Arc(Pt{0, 0}, 1, CircleShape, 2, 1, 0, 360, 0).Transform(Rotate(45)).(*ArcT),
+			// x = sqrt(2)*cos(t) - sin(t)/sqrt(2) has amplitude sqrt(2.5).
+			want: MBB{Min: Pt{-math.Sqrt(2.5), -math.Sqrt(2.5)}, Max: Pt{math.Sqrt(2.5), math.Sqrt(2.5)}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,6 +116,48 @@ func TestArcT_MBB(t *testing.T) {
 	}
 }
 
+func TestArcT_Flatten(t *testing.T) {
+	for _, tol := range []float64{0.1, 0.01, 0.001} {
+		for _, radius := range []float64{0.5, 10, 500} {
+			a := Arc(Pt{1, 2}, radius, CircleShape, 1, 1, 10, 300, 0.1)
+			a.Tolerance = tol
+			pts := a.Flatten()
+			if pts[0] != a.PointAt(a.StartAngle) || pts[len(pts)-1] != a.PointAt(a.EndAngle) {
+				t.Fatalf("tol=%v, radius=%v: Flatten end points = %v, %v", tol, radius, pts[0], pts[len(pts)-1])
+			}
+			var maxDev float64
+			for i := 1; i < len(pts); i++ {
+				mid := Pt{0.5*(pts[i][0]+pts[i-1][0]) - 1, 0.5*(pts[i][1]+pts[i-1][1]) - 2}
+				maxDev = math.Max(maxDev, radius-math.Hypot(mid[0], mid[1]))
+			}
+			if maxDev > tol+1e-9 {
+				t.Errorf("tol=%v, radius=%v: chords deviate by %v", tol, radius, maxDev)
+			}
+			// Not grossly over-segmented.
+			if maxDev < 0.25*tol && len(pts) > 8 {
+				t.Errorf("tol=%v, radius=%v: %v points deviate by only %v", tol, radius, len(pts), maxDev)
+			}
+		}
+	}
+}
+
+func TestArcT_WriteGerber(t *testing.T) {
+	a := Arc(Pt{0, 0}, 1, CircleShape, 1, 1, 0, 90, 0.1)
+	a.Tolerance = 0.1 // two segments
+	var buf bytes.Buffer
+	if err := a.WriteGerber(&buf, 12); err != nil {
+		t.Fatal(err)
+	}
+	want := `G54D12*
+X1000000Y000000D02*
+X707107Y707107D01*
+X000000Y1000000D01*
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteGerber =\n%v\nwant:\n%v", got, want)
+	}
+}
+
 func TestCircleT_Primitive(t *testing.T) {
 	var p Primitive = &CircleT{}
 	if p == nil {
@@ -221,6 +280,16 @@ func TestPolygonT_MBB(t *testing.T) {
 			p:    Polygon(Pt{0, 0}, true, []Pt{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}, 0),
 			want: MBB{Min: Pt{-1, -1}, Max: Pt{1, 1}},
 		},
+		{
+			name: "filled box ignores thickness",
+			p:    Polygon(Pt{0, 0}, true, []Pt{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}, 0.5),
+			want: MBB{Min: Pt{-1, -1}, Max: Pt{1, 1}},
+		},
+		{
+			name: "outlined box",
+			p:    Polygon(Pt{10, 20}, false, []Pt{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}, 0.5),
+			want: MBB{Min: Pt{8.75, 18.75}, Max: Pt{11.25, 21.25}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -241,3 +310,85 @@ func TestPolygonT_MBB(t *testing.T) {
 		})
 	}
 }
+
+func TestPolygonT_WriteGerber(t *testing.T) {
+	box := []Pt{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+	tests := []struct {
+		name         string
+		p            *PolygonT
+		want         string
+		wantAperture *Aperture
+	}{
+		{
+			name: "filled",
+			p:    Polygon(Pt{0, 0}, true, box, 0.1),
+			want: `G54D11*
+G36*
+X000000Y000000D02*
+X1000000Y000000D01*
+X1000000Y1000000D01*
+X000000Y1000000D01*
+X000000Y000000D01*
+G37*
+`,
+		},
+		{
+			name: "filled and already closed",
+			p:    Polygon(Pt{0, 0}, true, append(box, box[0]), 0),
+			want: `G54D11*
+G36*
+X000000Y000000D02*
+X1000000Y000000D01*
+X1000000Y1000000D01*
+X000000Y1000000D01*
+X000000Y000000D01*
+G37*
+`,
+		},
+		{
+			name: "outlined",
+			p:    Polygon(Pt{1, 2}, false, box, 0.1),
+			want: `G54D12*
+X1000000Y2000000D02*
+X2000000Y2000000D01*
+X2000000Y3000000D01*
+X1000000Y3000000D01*
+X1000000Y2000000D01*
+`,
+			wantAperture: &Aperture{Shape: CircleShape, Size: 0.1},
+		},
+		{
+			name: "outlined and already closed",
+			p:    Polygon(Pt{0, 0}, false, append(box, box[0]), 0.1),
+			want: `G54D12*
+X000000Y000000D02*
+X1000000Y000000D01*
+X1000000Y1000000D01*
+X000000Y1000000D01*
+X000000Y000000D01*
+`,
+			wantAperture: &Aperture{Shape: CircleShape, Size: 0.1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.p.WriteGerber(&buf, 12); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteGerber =\n%v\nwant:\n%v", got, tt.want)
+			}
+			got := tt.p.Aperture()
+			if (got == nil) != (tt.wantAperture == nil) || (got != nil && *got != *tt.wantAperture) {
+				t.Errorf("Aperture = %v, want %v", got, tt.wantAperture)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := Polygon(Pt{0, 0}, false, box, 0).WriteGerber(&buf, 12); err == nil {
+		t.Error("outlined polygon with zero thickness: WriteGerber = nil error, want error")
+	}
+}
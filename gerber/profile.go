@@ -0,0 +1,297 @@
+package gerber
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile describes the capabilities and file conventions of a PCB
+// manufacturer: the smallest features it can make (used by design rule
+// checks), the filename extensions it expects for each layer and the
+// format of the Gerber and drill files. See Profiles and LoadProfile.
+// All dimensions are in millimeters.
+type Profile struct {
+	Name string `json:"name"`
+	// MinTrace is the minimum width of a copper trace.
+	MinTrace float64 `json:"minTrace"`
+	// MinSpace is the minimum gap between copper features.
+	MinSpace float64 `json:"minSpace"`
+	// MinDrill is the minimum diameter of a drilled hole.
+	MinDrill float64 `json:"minDrill"`
+	// MinAnnularRing is the minimum width of the copper
+	// ring around a plated hole.
+	MinAnnularRing float64 `json:"minAnnularRing"`
+	// MinEdgeClearance is the minimum distance from copper
+	// to the board outline.
+	MinEdgeClearance float64 `json:"minEdgeClearance"`
+
+	// Extensions override the default filename extensions
+	// (such as "gtl" for the TopCopper role) by layer role.
+	Extensions map[LayerRole]string `json:"extensions,omitempty"`
+	// InnerExtension is the filename extension of the inner copper
+	// layers, where %v is replaced by the layer number. Empty means
+	// the default "g%vl".
+	InnerExtension string `json:"innerExtension,omitempty"`
+	// GerberDecimals is the number of digits after the decimal point
+	// of the coordinates in Gerber files, from 4 to 6. Zero means 6.
+	GerberDecimals int `json:"gerberDecimals,omitempty"`
+	// Excellon writes the drill layer as an Excellon drill file
+	// rather than as a Gerber file.
+	Excellon bool `json:"excellon,omitempty"`
+	// ExcellonDecimals is the number of digits after the decimal point
+	// of the coordinates in Excellon files, from 3 to 6. Zero means 3.
+	ExcellonDecimals int `json:"excellonDecimals,omitempty"`
+}
+
+// defaultExtensions are the filename extensions of the layers
+// when no profile overrides them.
+var defaultExtensions = map[LayerRole]string{
+	TopCopperRole:        "gtl",
+	TopSolderMaskRole:    "gts",
+	TopSilkscreenRole:    "gto",
+	BottomCopperRole:     "gbl",
+	BottomSolderMaskRole: "gbs",
+	BottomSilkscreenRole: "gbo",
+	DrillRole:            "xln",
+	OutlineRole:          "gko",
+}
+
+const defaultInnerExtension = "g%vl"
+
+// Profiles are the built-in manufacturer profiles, keyed by lowercase
+// name. Their capabilities are the manufacturers' published standard
+// (not advanced) options, which change over time, so check them before
+// ordering. They may be modified or added to.
+var Profiles = map[string]*Profile{
+	"jlcpcb": {
+		Name:             "JLCPCB",
+		MinTrace:         0.127,
+		MinSpace:         0.127,
+		MinDrill:         0.3,
+		MinAnnularRing:   0.13,
+		MinEdgeClearance: 0.3,
+		Extensions:       map[LayerRole]string{DrillRole: "drl"},
+		InnerExtension:   "g%v",
+		Excellon:         true,
+	},
+	"oshpark": {
+		Name:             "OSH Park",
+		MinTrace:         0.1524,
+		MinSpace:         0.1524,
+		MinDrill:         0.254,
+		MinAnnularRing:   0.127,
+		MinEdgeClearance: 0.381,
+		Excellon:         true,
+	},
+	"pcbway": {
+		Name:             "PCBWay",
+		MinTrace:         0.1,
+		MinSpace:         0.1,
+		MinDrill:         0.2,
+		MinAnnularRing:   0.15,
+		MinEdgeClearance: 0.25,
+		Extensions:       map[LayerRole]string{DrillRole: "drl"},
+		InnerExtension:   "g%v",
+		Excellon:         true,
+	},
+	"eurocircuits": {
+		Name:             "Eurocircuits",
+		MinTrace:         0.15,
+		MinSpace:         0.15,
+		MinDrill:         0.25,
+		MinAnnularRing:   0.15,
+		MinEdgeClearance: 0.25,
+		Extensions:       map[LayerRole]string{DrillRole: "drl"},
+		Excellon:         true,
+	},
+}
+
+// validate returns an error if the file formats of the profile
+// cannot be written.
+func (p *Profile) validate() error {
+	if d := p.GerberDecimals; d != 0 && (d < 4 || d > 6) {
+		return fmt.Errorf("profile %q: gerberDecimals must be from 4 to 6, got %v", p.Name, d)
+	}
+	if d := p.ExcellonDecimals; d != 0 && (d < 3 || d > 6) {
+		return fmt.Errorf("profile %q: excellonDecimals must be from 3 to 6, got %v", p.Name, d)
+	}
+	return nil
+}
+
+// copy returns a deep copy of the profile.
+func (p *Profile) copy() *Profile {
+	n := *p
+	n.Extensions = map[LayerRole]string{}
+	for k, v := range p.Extensions {
+		n.Extensions[k] = v
+	}
+	return &n
+}
+
+// LookupProfile returns a copy of the named built-in profile.
+// The name is not case sensitive.
+func LookupProfile(name string) (*Profile, error) {
+	p, ok := Profiles[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for k := range Profiles {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown profile %q; want one of %v", name, strings.Join(names, ", "))
+	}
+	return p.copy(), nil
+}
+
+// LoadProfile reads a profile from JSON, such as:
+//
+//	{"base": "jlcpcb", "minSpace": 0.1, "extensions": {"Drill": "txt"}}
+//
+// The optional "base" names a built-in profile whose settings are
+// overridden by the others.
+func LoadProfile(r io.Reader) (*Profile, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var base struct {
+		Base string `json:"base"`
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("profile: %v", err)
+	}
+	p := &Profile{}
+	if base.Base != "" {
+		if p, err = LookupProfile(base.Base); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("profile: %v", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OpenProfile returns the named built-in profile or, failing that,
+// loads the profile from the named JSON file. It is convenient for
+// selecting a profile with a single command-line flag.
+func OpenProfile(nameOrFilename string) (*Profile, error) {
+	if p, err := LookupProfile(nameOrFilename); err == nil {
+		return p, nil
+	}
+	f, err := os.Open(nameOrFilename)
+	if err != nil {
+		return nil, fmt.Errorf("profile %q is neither built in nor a readable file: %v", nameOrFilename, err)
+	}
+	defer f.Close()
+	return LoadProfile(f)
+}
+
+// Profile returns the manufacturer profile of the design, or nil.
+func (g *Gerber) Profile() *Profile {
+	return g.profile
+}
+
+// SetProfile retargets the design to the manufacturer profile (or back
+// to the defaults if nil), renaming the layers with its extensions.
+// It returns an error, leaving the design unchanged, if the profile's
+// file formats cannot be written.
+func (g *Gerber) SetProfile(p *Profile) error {
+	if p != nil {
+		if err := p.validate(); err != nil {
+			return err
+		}
+	}
+	g.profile = p
+	for _, layer := range g.Layers {
+		layer.Filename = g.FilenamePrefix + "." + g.extension(layer.Role)
+	}
+	return nil
+}
+
+var layerNRE = regexp.MustCompile(`^Layer(\d+)$`)
+
+// extension returns the filename extension of a layer with the role.
+func (g *Gerber) extension(role LayerRole) string {
+	p := g.profile
+	if p != nil {
+		if ext, ok := p.Extensions[role]; ok {
+			return ext
+		}
+	}
+	if m := layerNRE.FindStringSubmatch(string(role)); len(m) == 2 {
+		n, _ := strconv.Atoi(m[1])
+		if p != nil && p.InnerExtension != "" {
+			return fmt.Sprintf(p.InnerExtension, n)
+		}
+		return fmt.Sprintf(defaultInnerExtension, n)
+	}
+	if ext, ok := defaultExtensions[role]; ok {
+		return ext
+	}
+	return strings.ToLower(string(role))
+}
+
+// gerberDecimals returns the number of digits after the decimal point
+// of the coordinates in the Gerber files.
+func (g *Gerber) gerberDecimals() int {
+	if g == nil || g.profile == nil || g.profile.GerberDecimals == 0 {
+		return 6
+	}
+	return g.profile.GerberDecimals
+}
+
+// formatWriter rewrites the coordinates of Gerber files (which are
+// written with 6 decimal digits) with fewer decimal digits.
+type formatWriter struct {
+	w        io.Writer
+	decimals int
+	buf      []byte
+	err      error
+}
+
+var coordRE = regexp.MustCompile(`([XYIJ])(-?\d+)`)
+
+func (f *formatWriter) Write(b []byte) (int, error) {
+	f.buf = append(f.buf, b...)
+	for {
+		i := strings.IndexByte(string(f.buf), '\n')
+		if i < 0 {
+			break
+		}
+		f.writeLine(string(f.buf[:i+1]))
+		f.buf = f.buf[i+1:]
+	}
+	return len(b), f.err
+}
+
+// writeLine writes the (complete) line, rewriting its coordinates.
+// Extended commands (such as aperture definitions) are left alone,
+// other than the coordinate format itself.
+func (f *formatWriter) writeLine(line string) {
+	if f.err != nil {
+		return
+	}
+	switch {
+	case strings.HasPrefix(line, "%FS"):
+		line = fmt.Sprintf("%%FSLAX3%vY3%v*%%\n", f.decimals, f.decimals)
+	case !strings.HasPrefix(line, "%"):
+		scale := math.Pow(10, float64(6-f.decimals))
+		line = coordRE.ReplaceAllStringFunc(line, func(s string) string {
+			v, _ := strconv.ParseInt(s[1:], 10, 64)
+			return fmt.Sprintf("%v%d", s[:1], int64(math.Round(float64(v)/scale)))
+		})
+	}
+	_, f.err = io.WriteString(f.w, line)
+}
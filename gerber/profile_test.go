@@ -0,0 +1,155 @@
+package gerber
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLookupProfile(t *testing.T) {
+	p, err := LookupProfile("JLCPCB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "JLCPCB" || p.MinSpace != 0.127 {
+		t.Errorf("LookupProfile = %+v, want JLCPCB", p)
+	}
+
+	// The returned profile is a copy.
+	p.Extensions[DrillRole] = "txt"
+	if got := Profiles["jlcpcb"].Extensions[DrillRole]; got != "drl" {
+		t.Errorf("built-in drill extension = %q, want drl", got)
+	}
+
+	if _, err := LookupProfile("nowhere"); err == nil {
+		t.Error("LookupProfile(nowhere) = nil error, want error")
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	p, err := LoadProfile(strings.NewReader(`{"base": "jlcpcb", "minSpace": 0.1, "extensions": {"Outline": "gm1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MinSpace != 0.1 || p.MinTrace != 0.127 {
+		t.Errorf("MinSpace, MinTrace = %v, %v, want 0.1, 0.127", p.MinSpace, p.MinTrace)
+	}
+	if p.Extensions[OutlineRole] != "gm1" || p.Extensions[DrillRole] != "drl" {
+		t.Errorf("Extensions = %v, want the base's merged with the overrides", p.Extensions)
+	}
+
+	for _, s := range []string{
+		`{"base": "nowhere"}`,
+		`{"minSpace": "wide"}`,
+		`{`,
+		`{"gerberDecimals": 7}`,
+		`{"gerberDecimals": 3}`,
+		`{"gerberDecimals": -1}`,
+		`{"excellonDecimals": 7}`,
+		`{"base": "jlcpcb", "excellonDecimals": -3}`,
+	} {
+		if _, err := LoadProfile(strings.NewReader(s)); err == nil {
+			t.Errorf("LoadProfile(%q) = nil error, want error", s)
+		}
+	}
+}
+
+func TestSetProfile(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	inner := g.LayerN(2)
+	drill := g.Drill()
+
+	p, err := LookupProfile("jlcpcb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetProfile(p)
+	for _, tt := range []struct {
+		layer *Layer
+		want  string
+	}{
+		{layer: top, want: "test.gtl"},
+		{layer: inner, want: "test.g2"},
+		{layer: drill, want: "test.drl"},
+	} {
+		if tt.layer.Filename != tt.want {
+			t.Errorf("%v Filename = %q, want %q", tt.layer.Role, tt.layer.Filename, tt.want)
+		}
+	}
+	if got := g.Outline().Filename; got != "test.gko" {
+		t.Errorf("Outline Filename = %q, want test.gko", got)
+	}
+
+	g.SetProfile(nil)
+	if inner.Filename != "test.g2l" || drill.Filename != "test.xln" {
+		t.Errorf("Filenames = %q, %q, want the defaults", inner.Filename, drill.Filename)
+	}
+
+	// An invalid profile is rejected and leaves the design unchanged.
+	if err := g.SetProfile(&Profile{GerberDecimals: 8, Extensions: map[LayerRole]string{DrillRole: "drl"}}); err == nil {
+		t.Error("SetProfile(GerberDecimals: 8) = nil error, want error")
+	}
+	if g.Profile() != nil || drill.Filename != "test.xln" {
+		t.Errorf("Profile, drill Filename = %v, %q, want nil, test.xln", g.Profile(), drill.Filename)
+	}
+}
+
+func TestProfile_GerberDecimals(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(Line(1.23456, -2, 3.5, 4.00005, CircleShape, 0.2))
+	g.SetProfile(&Profile{GerberDecimals: 4})
+
+	var buf bytes.Buffer
+	if err := top.WriteGerber(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{"%FSLAX34Y34*%\n", "X12346Y-20000D02*\n", "X35000Y40001D01*\n", "%ADD12C,0.20000*%\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteGerber = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestWriteExcellon(t *testing.T) {
+	g := New("test")
+	drill := g.Drill()
+	drill.Add(
+		Circle(Pt{1, 2}, 0.8),
+		Circle(Pt{3, 4}, 0.3),
+		Line(5, 6, 7, 6, CircleShape, 0.8),
+		// Drilled by the same tool as the 0.3mm hole.
+		Circle(Pt{8, 9}, 0.3000004),
+	)
+	g.SetProfile(&Profile{Excellon: true})
+
+	var buf bytes.Buffer
+	if err := drill.write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `M48
+METRIC
+T1C0.300
+T2C0.800
+%
+G90
+G05
+T1
+X3.000Y4.000
+X8.000Y9.000
+T2
+X1.000Y2.000
+X5.000Y6.000G85X7.000Y6.000
+M30
+`
+	if got := buf.String(); got != want {
+		t.Errorf("WriteExcellon =\n%v\nwant:\n%v", got, want)
+	}
+
+	drill.Add(Polygon(Pt{}, true, []Pt{{0, 0}, {1, 0}, {1, 1}}, 0))
+	if err := drill.WriteExcellon(&buf); err == nil {
+		t.Error("WriteExcellon(polygon) = nil error, want error")
+	}
+}
@@ -0,0 +1,284 @@
+package gerber
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+const (
+	// rtreeMaxEntries and rtreeMinEntries bound the size of the nodes.
+	rtreeMaxEntries = 16
+	rtreeMinEntries = 4
+)
+
+// rtree is an R-tree (with Guttman's quadratic split) of the bounding
+// boxes of the primitives of a layer, keyed by their indices.
+type rtree struct {
+	root *rnode
+	size int
+}
+
+// rnode is a node of the R-tree. The entries of
+// leaves are primitives, and those of others are nodes.
+type rnode struct {
+	leaf    bool
+	entries []rentry
+}
+
+type rentry struct {
+	mbb   MBB
+	child *rnode // nil in leaves
+	index int    // of the primitive in its layer, in leaves
+}
+
+func newRTree() *rtree {
+	return &rtree{root: &rnode{leaf: true}}
+}
+
+// mbb returns the bounding box of all the entries of the node.
+func (n *rnode) mbb() MBB {
+	mbb := n.entries[0].mbb
+	for _, e := range n.entries[1:] {
+		mbb.Join(&e.mbb)
+	}
+	return mbb
+}
+
+// insert adds the primitive with the index and bounding box to the tree.
+func (t *rtree) insert(index int, mbb MBB) {
+	t.size++
+	if split := t.root.insert(rentry{mbb: mbb, index: index}); split != nil {
+		old := t.root
+		t.root = &rnode{entries: []rentry{
+			{mbb: old.mbb(), child: old},
+			{mbb: split.mbb(), child: split},
+		}}
+	}
+}
+
+// insert adds the leaf entry to the subtree, returning the new sibling
+// of the node if it had to be split.
+func (n *rnode) insert(e rentry) *rnode {
+	if n.leaf {
+		n.entries = append(n.entries, e)
+	} else {
+		// Descend into the child needing the least enlargement.
+		best, bestGrowth, bestArea := 0, math.Inf(1), math.Inf(1)
+		for i, c := range n.entries {
+			area := mbbArea(c.mbb)
+			joined := c.mbb
+			joined.Join(&e.mbb)
+			growth := mbbArea(joined) - area
+			if growth < bestGrowth || (growth == bestGrowth && area < bestArea) {
+				best, bestGrowth, bestArea = i, growth, area
+			}
+		}
+		c := &n.entries[best]
+		split := c.child.insert(e)
+		c.mbb = c.child.mbb()
+		if split != nil {
+			n.entries = append(n.entries, rentry{mbb: split.mbb(), child: split})
+		}
+	}
+	if len(n.entries) <= rtreeMaxEntries {
+		return nil
+	}
+	return n.split()
+}
+
+// split divides the entries of the overfull node between the node
+// and its returned new sibling.
+func (n *rnode) split() *rnode {
+	entries := n.entries
+	// Pick as seeds the two entries that would waste the most area together.
+	s1, s2, worst := 0, 1, math.Inf(-1)
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			joined := entries[i].mbb
+			joined.Join(&entries[j].mbb)
+			if d := mbbArea(joined) - mbbArea(entries[i].mbb) - mbbArea(entries[j].mbb); d > worst {
+				s1, s2, worst = i, j, d
+			}
+		}
+	}
+
+	a := &rnode{leaf: n.leaf, entries: []rentry{entries[s1]}}
+	b := &rnode{leaf: n.leaf, entries: []rentry{entries[s2]}}
+	mbbA, mbbB := entries[s1].mbb, entries[s2].mbb
+	var rest []rentry
+	for i, e := range entries {
+		if i != s1 && i != s2 {
+			rest = append(rest, e)
+		}
+	}
+	for len(rest) > 0 {
+		// Give the remaining entries to a group that needs them all
+		// to reach the minimum size.
+		if len(a.entries)+len(rest) <= rtreeMinEntries {
+			a.entries = append(a.entries, rest...)
+			break
+		}
+		if len(b.entries)+len(rest) <= rtreeMinEntries {
+			b.entries = append(b.entries, rest...)
+			break
+		}
+		// Assign next the entry with the strongest preference for a group.
+		next, diff, growA, growB := 0, math.Inf(-1), 0.0, 0.0
+		for i, e := range rest {
+			ja, jb := mbbA, mbbB
+			ja.Join(&e.mbb)
+			jb.Join(&e.mbb)
+			ga, gb := mbbArea(ja)-mbbArea(mbbA), mbbArea(jb)-mbbArea(mbbB)
+			if d := math.Abs(ga - gb); d > diff {
+				next, diff, growA, growB = i, d, ga, gb
+			}
+		}
+		e := rest[next]
+		rest = append(rest[:next], rest[next+1:]...)
+		if growA < growB || (growA == growB && len(a.entries) <= len(b.entries)) {
+			a.entries = append(a.entries, e)
+			mbbA.Join(&e.mbb)
+		} else {
+			b.entries = append(b.entries, e)
+			mbbB.Join(&e.mbb)
+		}
+	}
+	n.entries = a.entries
+	return b
+}
+
+// search returns the indices of the primitives whose bounding boxes
+// intersect the window, in ascending order.
+func (t *rtree) search(window MBB) []int {
+	var result []int
+	var walk func(n *rnode)
+	walk = func(n *rnode) {
+		for _, e := range n.entries {
+			if !mbbIntersects(e.mbb, window) {
+				continue
+			}
+			if n.leaf {
+				result = append(result, e.index)
+				continue
+			}
+			walk(e.child)
+		}
+	}
+	walk(t.root)
+	sort.Ints(result)
+	return result
+}
+
+// nearest returns the indices of up to k primitives whose bounding
+// boxes are nearest to the point, nearest first.
+func (t *rtree) nearest(pt Pt, k int) []int {
+	var result []int
+	q := &rqueue{{node: t.root}}
+	for q.Len() > 0 && len(result) < k {
+		item := heap.Pop(q).(ritem)
+		if item.node == nil {
+			result = append(result, item.index)
+			continue
+		}
+		for _, e := range item.node.entries {
+			next := ritem{dist: mbbDistance(e.mbb, pt), node: e.child, index: e.index}
+			heap.Push(q, next)
+		}
+	}
+	return result
+}
+
+// ritem is a node or (if node is nil) a primitive to visit
+// during a nearest neighbor search.
+type ritem struct {
+	dist  float64
+	node  *rnode
+	index int
+}
+
+// rqueue is a priority queue of the nearest items first,
+// and of the primitives added first among equally near ones.
+type rqueue []ritem
+
+func (q rqueue) Len() int { return len(q) }
+func (q rqueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	if (q[i].node == nil) != (q[j].node == nil) {
+		return q[i].node != nil // expand nodes first
+	}
+	return q[i].index < q[j].index
+}
+func (q rqueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *rqueue) Push(x interface{}) { *q = append(*q, x.(ritem)) }
+func (q *rqueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+func mbbArea(mbb MBB) float64 {
+	return (mbb.Max[0] - mbb.Min[0]) * (mbb.Max[1] - mbb.Min[1])
+}
+
+func mbbIntersects(a, b MBB) bool {
+	return a.Min[0] <= b.Max[0] && b.Min[0] <= a.Max[0] && a.Min[1] <= b.Max[1] && b.Min[1] <= a.Max[1]
+}
+
+// mbbDistance returns the distance from the point to the bounding box,
+// which is zero if the point lies within it.
+func mbbDistance(mbb MBB, pt Pt) float64 {
+	dx := math.Max(0, math.Max(mbb.Min[0]-pt[0], pt[0]-mbb.Max[0]))
+	dy := math.Max(0, math.Max(mbb.Min[1]-pt[1], pt[1]-mbb.Max[1]))
+	return math.Hypot(dx, dy)
+}
+
+// spatialIndex returns the spatial index of the layer's primitives,
+// building it if it is missing or out of date.
+func (l *Layer) spatialIndex() *rtree {
+	if l.index != nil && l.index.size == len(l.Primitives) {
+		return l.index
+	}
+	l.index = newRTree()
+	for i, p := range l.Primitives {
+		l.index.insert(i, p.MBB())
+	}
+	return l.index
+}
+
+// Search returns the indices (in Primitives) of the primitives whose
+// bounding boxes intersect the window, in ascending order. Searching
+// a single point hit-tests the layer.
+//
+// The search uses a spatial index that is built on first use and then
+// kept up to date by Add and rebuilt by Transform. It is rebuilt if
+// Primitives is otherwise changed in length, but primitives must not
+// be moved or replaced in place once the layer has been searched.
+func (l *Layer) Search(window MBB) []int {
+	return l.spatialIndex().search(window)
+}
+
+// Nearest returns the indices of up to k primitives whose bounding
+// boxes are nearest to the point, nearest first (and in ascending
+// order when equally near, such as when the point lies within them).
+func (l *Layer) Nearest(pt Pt, k int) []int {
+	return l.spatialIndex().nearest(pt, k)
+}
+
+// Overlaps returns the indices of the other primitives whose bounding
+// boxes intersect that of the i'th primitive grown by margin on all
+// sides, in ascending order.
+func (l *Layer) Overlaps(i int, margin float64) []int {
+	mbb := l.Primitives[i].MBB()
+	window := MBB{Min: Pt{mbb.Min[0] - margin, mbb.Min[1] - margin}, Max: Pt{mbb.Max[0] + margin, mbb.Max[1] + margin}}
+	var result []int
+	for _, j := range l.Search(window) {
+		if j != i {
+			result = append(result, j)
+		}
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package gerber
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLayer_Search(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	g := New("test")
+	layer := g.TopCopper()
+	randomCircle := func() Primitive {
+		return Circle(Pt{100 * rnd.Float64(), 100 * rnd.Float64()}, 0.1+2*rnd.Float64())
+	}
+	for i := 0; i < 500; i++ {
+		layer.Add(randomCircle())
+	}
+
+	search := func(window MBB) []int {
+		var want []int
+		for i, p := range layer.Primitives {
+			if mbbIntersects(p.MBB(), window) {
+				want = append(want, i)
+			}
+		}
+		return want
+	}
+	check := func() {
+		t.Helper()
+		for i := 0; i < 50; i++ {
+			x, y := 100*rnd.Float64(), 100*rnd.Float64()
+			window := MBB{Min: Pt{x, y}, Max: Pt{x + 20*rnd.Float64(), y + 20*rnd.Float64()}}
+			if got, want := layer.Search(window), search(window); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Search(%v) = %v, want %v", window, got, want)
+			}
+		}
+	}
+	check()
+
+	// Add keeps the index up to date.
+	for i := 0; i < 100; i++ {
+		layer.Add(randomCircle())
+	}
+	check()
+	// Primitives appended directly are indexed once searched.
+	for i := 0; i < 100; i++ {
+		layer.Primitives = append(layer.Primitives, randomCircle())
+	}
+	check()
+
+	// A point hit-tests the layer.
+	pt := layer.Primitives[42].(*CircleT).pt
+	got := layer.Search(MBB{Min: pt, Max: pt})
+	if i := sort.SearchInts(got, 42); i == len(got) || got[i] != 42 {
+		t.Errorf("Search(%v) = %v, want it to contain 42", pt, got)
+	}
+
+	// Transform moves the primitives within the index.
+	g.Transform(Translate(200, 0))
+	if got := layer.Search(MBB{Min: Pt{-10, -10}, Max: Pt{110, 110}}); len(got) != 0 {
+		t.Errorf("Search(old place) = %v, want none", got)
+	}
+	got = layer.Search(MBB{Min: Pt{190, -10}, Max: Pt{310, 110}})
+	if len(got) != len(layer.Primitives) {
+		t.Errorf("Search(moved) = %v primitives, want %v", len(got), len(layer.Primitives))
+	}
+}
+
+func TestLayer_Nearest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	g := New("test")
+	layer := g.TopCopper()
+	for i := 0; i < 300; i++ {
+		layer.Add(Line(100*rnd.Float64(), 100*rnd.Float64(), 100*rnd.Float64(), 100*rnd.Float64(), CircleShape, 0.2))
+	}
+
+	for i := 0; i < 20; i++ {
+		pt := Pt{120*rnd.Float64() - 10, 120*rnd.Float64() - 10}
+		got := layer.Nearest(pt, 5)
+		if len(got) != 5 {
+			t.Fatalf("Nearest(%v, 5) = %v, want 5 primitives", pt, got)
+		}
+		for j := 1; j < len(got); j++ {
+			if mbbDistance(layer.Primitives[got[j]].MBB(), pt) < mbbDistance(layer.Primitives[got[j-1]].MBB(), pt) {
+				t.Errorf("Nearest(%v, 5) = %v, not nearest first", pt, got)
+			}
+		}
+		// Nothing left out is nearer than the furthest found.
+		found := map[int]bool{}
+		for _, j := range got {
+			found[j] = true
+		}
+		furthest := mbbDistance(layer.Primitives[got[len(got)-1]].MBB(), pt)
+		for j, p := range layer.Primitives {
+			if !found[j] && mbbDistance(p.MBB(), pt) < furthest {
+				t.Errorf("Nearest(%v, 5) = %v, missing nearer primitive %v", pt, got, j)
+			}
+		}
+	}
+
+	if got := layer.Nearest(Pt{}, 1000); len(got) != len(layer.Primitives) {
+		t.Errorf("Nearest(k=1000) returned %v primitives, want %v", len(got), len(layer.Primitives))
+	}
+}
+
+func TestLayer_Overlaps(t *testing.T) {
+	g := New("test")
+	layer := g.TopCopper()
+	layer.Add(
+		Circle(Pt{0, 0}, 1),
+		Circle(Pt{1.2, 0}, 1), // 0.2 apart
+		Circle(Pt{0, 0.5}, 1), // overlapping
+		Circle(Pt{5, 0}, 1),
+	)
+
+	tests := []struct {
+		margin float64
+		want   []int
+	}{
+		{margin: 0, want: []int{2}},
+		{margin: 0.25, want: []int{1, 2}},
+		{margin: 5, want: []int{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		if got := layer.Overlaps(0, tt.margin); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Overlaps(0, %v) = %v, want %v", tt.margin, got, tt.want)
+		}
+	}
+}
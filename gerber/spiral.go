@@ -0,0 +1,427 @@
+package gerber
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// DefaultTolerance is the maximum deviation (sagitta) in millimeters
+// allowed when curved primitives are flattened into straight segments
+// and they do not specify their own tolerance.
+var DefaultTolerance = 0.005
+
+// Direction represents the winding direction of a spiral.
+type Direction int
+
+const (
+	// CounterClockwise winds counter-clockwise from the inside out.
+	CounterClockwise Direction = iota
+	// Clockwise winds clockwise from the inside out.
+	Clockwise
+)
+
+// SpiralT represents an Archimedean spiral trace
+// and satisfies the Primitive interface.
+type SpiralT struct {
+	Center Pt
+	// InnerRadius is the radius of the trace centerline at the start.
+	InnerRadius float64
+	// Pitch is the increase in radius per full turn (center to center).
+	Pitch float64
+	// Turns is the number of (possibly fractional) turns.
+	Turns float64
+	// Width is the width of the trace.
+	Width float64
+	// StartAngle is the angle (in radians) of the inner end of the spiral.
+	StartAngle float64
+	// Direction is the winding direction from the inside out.
+	Direction Direction
+	// Tolerance is the maximum deviation (sagitta) in millimeters
+	// when flattening the spiral. Zero means DefaultTolerance.
+	Tolerance float64
+	// Arcs causes the spiral to be written as native circular arcs
+	// (G02/G03) drawn with a circular aperture of Width, rather than
+	// as a filled region.
+	Arcs bool
+
+	xf  *Transform // optional (non-similarity) transformation
+	mbb *MBB       // cached minimum bounding box
+}
+
+// Spiral returns an Archimedean spiral primitive.
+// All dimensions are in millimeters.
+// The start angle is specified in degrees (and stored as radians).
+func Spiral(center Pt, innerRadius, pitch, turns, width, startAngle float64, direction Direction) *SpiralT {
+	return &SpiralT{
+		Center:      center,
+		InnerRadius: innerRadius,
+		Pitch:       pitch,
+		Turns:       turns,
+		Width:       width,
+		StartAngle:  math.Pi * startAngle / 180.0,
+		Direction:   direction,
+	}
+}
+
+// sweep returns the total angle swept by the spiral in radians.
+func (s *SpiralT) sweep() float64 {
+	return 2 * math.Pi * s.Turns
+}
+
+// radius returns the radius of the centerline after sweeping t radians.
+func (s *SpiralT) radius(t float64) float64 {
+	return s.InnerRadius + s.Pitch*t/(2*math.Pi)
+}
+
+// angle returns the angle of the spiral after sweeping t radians.
+func (s *SpiralT) angle(t float64) float64 {
+	if s.Direction == Clockwise {
+		return s.StartAngle - t
+	}
+	return s.StartAngle + t
+}
+
+// point returns the point at the given radial offset from the
+// centerline after sweeping t radians.
+func (s *SpiralT) point(t, offset float64) Pt {
+	r := s.radius(t) + offset
+	a := s.angle(t)
+	pt := Pt{s.Center[0] + r*math.Cos(a), s.Center[1] + r*math.Sin(a)}
+	if s.xf != nil {
+		return s.xf.Apply(pt)
+	}
+	return pt
+}
+
+// StartPoint returns the inner end of the spiral's centerline.
+func (s *SpiralT) StartPoint() Pt {
+	return s.point(0, 0)
+}
+
+// EndPoint returns the outer end of the spiral's centerline.
+func (s *SpiralT) EndPoint() Pt {
+	return s.point(s.sweep(), 0)
+}
+
+func (s *SpiralT) tolerance() float64 {
+	if s.Tolerance > 0 {
+		return s.Tolerance
+	}
+	return DefaultTolerance
+}
+
+// segmentAngle returns the largest angle step for which a chord
+// of a circle of the given radius deviates from it by at most tol.
+func segmentAngle(radius, tol float64) float64 {
+	const maxStep = 0.25 * math.Pi
+	if radius <= tol {
+		return maxStep
+	}
+	step := 2 * math.Acos(1-tol/radius)
+	if step > maxStep {
+		return maxStep
+	}
+	return step
+}
+
+// steps returns the sweep parameters used to flatten the spiral
+// such that an edge offset radially by offset stays within tolerance.
+func (s *SpiralT) steps(offset float64) []float64 {
+	tol := s.tolerance()
+	if s.xf != nil {
+		tol /= math.Max(1, s.xf.ScaleFactor())
+	}
+	sweep := s.sweep()
+	ts := []float64{0}
+	for t := 0.0; t < sweep; {
+		t += segmentAngle(math.Abs(s.radius(t)+offset), tol)
+		if t > sweep {
+			t = sweep
+		}
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// Centerline returns the flattened centerline of the spiral
+// from the inside out.
+func (s *SpiralT) Centerline() []Pt {
+	var pts []Pt
+	for _, t := range s.steps(0) {
+		pts = append(pts, s.point(t, 0))
+	}
+	return pts
+}
+
+// Outline returns the closed outline of the spiral trace as a region,
+// following the outer edge from the inside out and returning along
+// the inner edge.
+func (s *SpiralT) Outline() []Pt {
+	hw := 0.5 * s.Width
+	var pts []Pt
+	for _, t := range s.steps(hw) {
+		pts = append(pts, s.point(t, hw))
+	}
+	inner := s.steps(-hw)
+	for i := len(inner) - 1; i >= 0; i-- {
+		pts = append(pts, s.point(inner[i], -hw))
+	}
+	return append(pts, pts[0])
+}
+
+// Length returns the length of the spiral's centerline in millimeters.
+func (s *SpiralT) Length() float64 {
+	if s.xf != nil {
+		var length float64
+		pts := s.Centerline()
+		for i := 1; i < len(pts); i++ {
+			length += math.Hypot(pts[i][0]-pts[i-1][0], pts[i][1]-pts[i-1][1])
+		}
+		return length
+	}
+
+	b := s.Pitch / (2 * math.Pi)
+	r1, r2 := s.radius(0), s.radius(s.sweep())
+	if math.Abs(b) < 1e-12 {
+		return math.Abs(r1) * s.sweep()
+	}
+	// Arc length of r = a + b*t integrates to:
+	f := func(r float64) float64 {
+		h := math.Hypot(r, b)
+		return (r*h + b*b*math.Log(r+h)) / (2 * b)
+	}
+	return math.Abs(f(r2) - f(r1))
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (s *SpiralT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if s.Arcs {
+		return s.writeArcs(w, apertureIndex)
+	}
+	io.WriteString(w, "G54D11*\n")
+	io.WriteString(w, "G36*\n")
+	for i, pt := range s.Outline() {
+		if i == 0 {
+			fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]))
+			continue
+		}
+		fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*pt[0]), int(0.5+sf*pt[1]))
+	}
+	io.WriteString(w, "G37*\n")
+	return nil
+}
+
+// writeArcs writes the centerline of the spiral as a series of
+// circular arcs, each of which passes through the spiral at its
+// start, middle and end.
+func (s *SpiralT) writeArcs(w io.Writer, apertureIndex int) error {
+	tol := s.tolerance()
+	sweep := s.sweep()
+
+	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
+	io.WriteString(w, "G75*\n")
+	start := s.point(0, 0)
+	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*start[0]), int(0.5+sf*start[1]))
+	for t := 0.0; t < sweep; {
+		step := 0.25 * math.Pi
+		if t+step > sweep {
+			step = sweep - t
+		}
+		var center Pt
+		var ccw, ok bool
+		for {
+			center, ccw, ok = circleFrom3(s.point(t, 0), s.point(t+0.5*step, 0), s.point(t+step, 0))
+			if (ok && s.arcFits(t, step, center, tol)) || step < 1e-6 {
+				break
+			}
+			step *= 0.5
+		}
+		p1 := s.point(t, 0)
+		p2 := s.point(t+step, 0)
+		if !ok {
+			fmt.Fprintf(w, "G01X%06dY%06dD01*\n", int(0.5+sf*p2[0]), int(0.5+sf*p2[1]))
+		} else {
+			g := "G02"
+			if ccw {
+				g = "G03"
+			}
+			fmt.Fprintf(w, "%vX%06dY%06dI%06dJ%06dD01*\n", g,
+				int(0.5+sf*p2[0]), int(0.5+sf*p2[1]),
+				int(0.5+sf*(center[0]-p1[0])), int(0.5+sf*(center[1]-p1[1])))
+		}
+		t += step
+	}
+	io.WriteString(w, "G01*\n")
+	return nil
+}
+
+// arcFits reports whether the circle about center fits the spiral
+// between t and t+step within the tolerance.
+func (s *SpiralT) arcFits(t, step float64, center Pt, tol float64) bool {
+	p := s.point(t, 0)
+	r := math.Hypot(p[0]-center[0], p[1]-center[1])
+	for _, f := range []float64{0.25, 0.75} {
+		q := s.point(t+f*step, 0)
+		if math.Abs(math.Hypot(q[0]-center[0], q[1]-center[1])-r) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// circleFrom3 returns the center of the circle passing through the
+// three points, and whether the points run counter-clockwise around it.
+// ok is false if the points are collinear.
+func circleFrom3(p1, p2, p3 Pt) (center Pt, ccw, ok bool) {
+	ax, ay := p2[0]-p1[0], p2[1]-p1[1]
+	bx, by := p3[0]-p1[0], p3[1]-p1[1]
+	d := 2 * (ax*by - ay*bx)
+	if math.Abs(d) < 1e-12 {
+		return Pt{}, false, false
+	}
+	a2, b2 := ax*ax+ay*ay, bx*bx+by*by
+	center = Pt{
+		p1[0] + (by*a2-ay*b2)/d,
+		p1[1] + (ax*b2-bx*a2)/d,
+	}
+	return center, d > 0, true
+}
+
+// Aperture returns the primitive's desired aperture.
+// Spirals written as regions use the default aperture.
+func (s *SpiralT) Aperture() *Aperture {
+	if !s.Arcs {
+		return nil
+	}
+	return &Aperture{
+		Shape: CircleShape,
+		Size:  s.Width,
+	}
+}
+
+// MBB returns the minimum bounding box in millimeters.
+func (s *SpiralT) MBB() MBB {
+	if s.mbb != nil {
+		return *s.mbb
+	}
+	if s.xf != nil {
+		pts := s.Outline()
+		if s.Arcs {
+			pts = s.Centerline()
+		}
+		for i, pt := range pts {
+			v := MBB{Min: pt, Max: pt}
+			if i == 0 {
+				s.mbb = &v
+				continue
+			}
+			s.mbb.Join(&v)
+		}
+		if s.Arcs {
+			s.expandMBB(0.5 * s.Width * s.xf.ScaleFactor())
+		}
+		return *s.mbb
+	}
+
+	hw := 0.5 * s.Width
+	offsets := []float64{hw, -hw}
+	if s.Arcs {
+		offsets = []float64{0}
+	}
+	s.mbb = &MBB{Min: s.point(0, offsets[0]), Max: s.point(0, offsets[0])}
+	for _, offset := range offsets {
+		s.mbb.Max[0] = math.Max(s.mbb.Max[0], s.extreme(offset, 0)[0])
+		s.mbb.Max[1] = math.Max(s.mbb.Max[1], s.extreme(offset, 0.5*math.Pi)[1])
+		s.mbb.Min[0] = math.Min(s.mbb.Min[0], s.extreme(offset, math.Pi)[0])
+		s.mbb.Min[1] = math.Min(s.mbb.Min[1], s.extreme(offset, 1.5*math.Pi)[1])
+	}
+	if s.Arcs {
+		s.expandMBB(hw)
+	}
+	return *s.mbb
+}
+
+func (s *SpiralT) expandMBB(d float64) {
+	s.mbb.Min[0] -= d
+	s.mbb.Min[1] -= d
+	s.mbb.Max[0] += d
+	s.mbb.Max[1] += d
+}
+
+// extreme returns the point of the edge curve at the given radial
+// offset from the centerline that lies furthest in the direction phi.
+// The candidates are the ends of the curve and, within each turn,
+// the single point where the curve's tangent is perpendicular to phi.
+func (s *SpiralT) extreme(offset, phi float64) Pt {
+	sweep := s.sweep()
+	dot := func(t float64) float64 {
+		return (s.radius(t) + offset) * math.Cos(s.angle(t)-phi)
+	}
+	sign := 1.0
+	if s.Direction == Clockwise {
+		sign = -1
+	}
+	// alpha is the angle of the curve relative to phi, and toT converts
+	// it back into a sweep parameter.
+	alpha := func(t float64) float64 {
+		return s.angle(t) - phi
+	}
+	toT := func(alpha float64) float64 {
+		return sign * (alpha + phi - s.StartAngle)
+	}
+
+	best, bestT := dot(0), 0.0
+	if v := dot(sweep); v > best {
+		best, bestT = v, sweep
+	}
+	b := s.Pitch / (2 * math.Pi)
+	kMin := math.Floor(math.Min(alpha(0), alpha(sweep))/(2*math.Pi)) - 1
+	kMax := math.Ceil(math.Max(alpha(0), alpha(sweep))/(2*math.Pi)) + 1
+	for k := kMin; k <= kMax; k++ {
+		// Solve tan(a) = sign*b/R(t) for a near 2*pi*k.
+		a := 2 * math.Pi * k
+		for i := 0; i < 8; i++ {
+			r := s.radius(toT(a)) + offset
+			if r <= 0 {
+				break
+			}
+			a = 2*math.Pi*k + math.Atan(sign*b/r)
+		}
+		t := toT(a)
+		if t < 0 || t > sweep {
+			continue
+		}
+		if v := dot(t); v > best {
+			best, bestT = v, t
+		}
+	}
+	return s.point(bestT, offset)
+}
+
+// Transform returns a transformed copy of the spiral.
+// Translations, rotations, uniform scaling and mirroring keep the
+// exact spiral geometry; other transformations are applied to the
+// flattened geometry.
+func (s *SpiralT) Transform(xf Transform) Primitive {
+	n := *s
+	n.mbb = nil
+	if s.xf == nil {
+		if mirror, rotation, scale, ok := xf.similarity(); ok {
+			n.Center = xf.Apply(s.Center)
+			n.InnerRadius *= scale
+			n.Pitch *= scale
+			n.Width *= scale
+			if mirror {
+				n.StartAngle = math.Pi - n.StartAngle
+				n.Direction = 1 - n.Direction
+			}
+			n.StartAngle += math.Pi * rotation / 180.0
+			return &n
+		}
+	} else {
+		xf = s.xf.Then(xf)
+	}
+	n.xf = &xf
+	return &n
+}
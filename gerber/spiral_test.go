@@ -0,0 +1,179 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSpiralT_Primitive(t *testing.T) {
+	var p Primitive = &SpiralT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("SpiralT does not implement the Primitive interface")
+	}
+}
+
+// sampledMBB returns the MBB of a densely-sampled spiral edge.
+func sampledMBB(s *SpiralT) MBB {
+	var mbb *MBB
+	hw := 0.5 * s.Width
+	offsets := []float64{hw, -hw}
+	if s.Arcs {
+		offsets = []float64{0}
+	}
+	const n = 200000
+	for _, offset := range offsets {
+		for i := 0; i <= n; i++ {
+			pt := s.point(s.sweep()*float64(i)/n, offset)
+			v := MBB{Min: pt, Max: pt}
+			if mbb == nil {
+				mbb = &v
+				continue
+			}
+			mbb.Join(&v)
+		}
+	}
+	if s.Arcs {
+		mbb.Min[0] -= hw
+		mbb.Min[1] -= hw
+		mbb.Max[0] += hw
+		mbb.Max[1] += hw
+	}
+	return *mbb
+}
+
+func TestSpiralT_MBB(t *testing.T) {
+	const eps = 1e-6
+	arcs := Spiral(Pt{0, 0}, 3, 0.3, 7.3, 0.15, 45, Clockwise)
+	arcs.Arcs = true
+	tests := []struct {
+		name string
+		s    *SpiralT
+	}{
+		{name: "ccw", s: Spiral(Pt{0, 0}, 1, 0.3, 10, 0.15, 0, CounterClockwise)},
+		{name: "cw", s: Spiral(Pt{0, 0}, 1, 0.3, 10, 0.15, 0, Clockwise)},
+		{name: "offset, fractional turns", s: Spiral(Pt{10, -5}, 2, 0.5, 3.25, 0.2, 123, CounterClockwise)},
+		{name: "quarter turn", s: Spiral(Pt{0, 0}, 5, 1, 0.25, 0.5, 100, CounterClockwise)},
+		{name: "arcs", s: arcs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.s.MBB()
+			want := sampledMBB(tt.s)
+			if math.Abs(got.Min[0]-want.Min[0]) > eps {
+				t.Errorf("Min[0]=%v, want %v", got.Min[0], want.Min[0])
+			}
+			if math.Abs(got.Min[1]-want.Min[1]) > eps {
+				t.Errorf("Min[1]=%v, want %v", got.Min[1], want.Min[1])
+			}
+			if math.Abs(got.Max[0]-want.Max[0]) > eps {
+				t.Errorf("Max[0]=%v, want %v", got.Max[0], want.Max[0])
+			}
+			if math.Abs(got.Max[1]-want.Max[1]) > eps {
+				t.Errorf("Max[1]=%v, want %v", got.Max[1], want.Max[1])
+			}
+		})
+	}
+}
+
+func TestSpiralT_Length(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *SpiralT
+		want float64
+	}{
+		{name: "circle", s: Spiral(Pt{0, 0}, 2, 0, 1, 0.1, 0, CounterClockwise), want: 4 * math.Pi},
+		// Average radius of 4 over 10 turns.
+		{name: "spiral", s: Spiral(Pt{0, 0}, 1, 0.6, 10, 0.1, 0, Clockwise), want: 2 * math.Pi * 10 * 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.Length(); math.Abs(got-tt.want) > 0.01*tt.want {
+				t.Errorf("Length = %v, want %v", got, tt.want)
+			}
+
+			var sampled float64
+			pts := tt.s.Centerline()
+			for i := 1; i < len(pts); i++ {
+				sampled += math.Hypot(pts[i][0]-pts[i-1][0], pts[i][1]-pts[i-1][1])
+			}
+			if got := tt.s.Length(); math.Abs(got-sampled) > 1e-3*sampled {
+				t.Errorf("Length = %v, want %v from centerline", got, sampled)
+			}
+		})
+	}
+}
+
+func TestSpiralT_Transform(t *testing.T) {
+	const eps = 1e-9
+	s := Spiral(Pt{1, 2}, 1, 0.3, 5, 0.15, 30, CounterClockwise)
+	xf := MirrorX().Then(Rotate(40)).Then(Scale(2, 2)).Then(Translate(5, 5))
+	got := s.Transform(xf).(*SpiralT)
+	if got.xf != nil {
+		t.Fatalf("similarity transform was not applied exactly")
+	}
+	if got.Direction != Clockwise {
+		t.Errorf("Direction = %v, want Clockwise", got.Direction)
+	}
+	if want := 2 * s.Length(); math.Abs(got.Length()-want) > 1e-6 {
+		t.Errorf("Length = %v, want %v", got.Length(), want)
+	}
+	for _, tt := range []struct{ got, want Pt }{
+		{got.StartPoint(), xf.Apply(s.StartPoint())},
+		{got.EndPoint(), xf.Apply(s.EndPoint())},
+	} {
+		if math.Abs(tt.got[0]-tt.want[0]) > eps || math.Abs(tt.got[1]-tt.want[1]) > eps {
+			t.Errorf("transformed point = %v, want %v", tt.got, tt.want)
+		}
+	}
+
+	sheared := s.Transform(Scale(2, 1)).(*SpiralT)
+	if sheared.xf == nil {
+		t.Fatalf("non-similarity transform was applied exactly")
+	}
+	want := s.EndPoint()
+	want[0] *= 2
+	if got := sheared.EndPoint(); math.Abs(got[0]-want[0]) > eps || math.Abs(got[1]-want[1]) > eps {
+		t.Errorf("EndPoint = %v, want %v", got, want)
+	}
+}
+
+func TestSpiralT_WriteGerber(t *testing.T) {
+	tests := []struct {
+		name    string
+		arcs    bool
+		dir     Direction
+		want    []string
+		notWant []string
+	}{
+		{name: "region", want: []string{"G36*\n", "G37*\n"}, notWant: []string{"G75*", "G03"}},
+		{name: "ccw arcs", arcs: true, want: []string{"G54D12*\nG75*\n", "G03X"}, notWant: []string{"G36*", "G02"}},
+		{name: "cw arcs", arcs: true, dir: Clockwise, want: []string{"G02X"}, notWant: []string{"G03"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Spiral(Pt{0, 0}, 1, 0.3, 3, 0.15, 0, tt.dir)
+			s.Arcs = tt.arcs
+			var buf bytes.Buffer
+			if err := s.WriteGerber(&buf, 12); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("WriteGerber missing %q", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("WriteGerber unexpectedly contains %q", notWant)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package gerber
+
+import (
+	"math"
+	"sync"
+)
+
+// outlineGap is the largest gap between the ends of the strokes
+// of the board outline that are taken to be joined.
+const outlineGap = 1e-3
+
+// LayerStats are the statistics of a layer, such as fabricators
+// use to quote plating and copper balancing.
+type LayerStats struct {
+	Layer *Layer
+	// Primitives is the number of primitives on the layer
+	// and Apertures is the number of distinct apertures they use.
+	Primitives, Apertures int
+	// Area is the area in square millimeters of the image of the layer:
+	// of its primitives less the areas cleared by the ClearT primitives
+	// drawn over them, with overlapping areas counted once. Curves are
+	// flattened to within DefaultTolerance.
+	Area float64
+	// Coverage is the percentage of the area within the board outline
+	// that the image of the layer covers, or zero if the design has
+	// no closed board outline.
+	Coverage float64
+	// Smallest is the size of the smallest feature of the layer: the
+	// width of its narrowest stroke (or flash), or the lesser side of
+	// the bounding box of its smallest region. It is zero if the layer
+	// has no features.
+	Smallest float64
+	// DrillHits is the number of holes (and slots) drilled by each tool
+	// of a drill layer, keyed by the tool diameter.
+	DrillHits map[float64]int
+}
+
+// Area returns the area in square millimeters of the image of the
+// layer (see LayerStats).
+func (l *Layer) Area() float64 {
+	return l.flatImage().area()
+}
+
+// Stats returns the statistics of the layer.
+func (l *Layer) Stats() *LayerStats {
+	var loops [][]Pt
+	if l.g != nil {
+		loops = l.g.boardLoops()
+	}
+	return l.stats(loops, loopsArea(loops))
+}
+
+// Stats returns the statistics of each of the layers of the design.
+func (g *Gerber) Stats() []*LayerStats {
+	loops := g.boardLoops()
+	board := loopsArea(loops)
+	result := make([]*LayerStats, len(g.Layers))
+	var wg sync.WaitGroup
+	for i, layer := range g.Layers {
+		wg.Add(1)
+		go func(i int, layer *Layer) {
+			result[i] = layer.stats(loops, board)
+			wg.Done()
+		}(i, layer)
+	}
+	wg.Wait()
+	return result
+}
+
+// BoardArea returns the area in square millimeters within the board
+// outline, less any cutouts, or zero if it has no closed outline.
+func (g *Gerber) BoardArea() float64 {
+	return loopsArea(g.boardLoops())
+}
+
+func (l *Layer) stats(loops [][]Pt, board float64) *LayerStats {
+	s := &LayerStats{Layer: l, Primitives: len(l.Primitives), Apertures: len(l.Apertures)}
+	im := l.flatImage()
+	s.Area = im.area()
+
+	switch {
+	case board <= 0 || len(im.shapes) == 0:
+	case im.within(loops):
+		s.Coverage = 100 * s.Area / board
+	default:
+		// Clear everything beyond the outline.
+		mbb := im.shapes[0].mbb
+		for _, v := range im.shapes[1:] {
+			mbb.Join(&v.mbb)
+		}
+		for _, loop := range loops {
+			for _, pt := range loop {
+				mbb.Join(&MBB{Min: pt, Max: pt})
+			}
+		}
+		beyond := [][]Pt{{
+			{mbb.Min[0] - 1, mbb.Min[1] - 1},
+			{mbb.Max[0] + 1, mbb.Min[1] - 1},
+			{mbb.Max[0] + 1, mbb.Max[1] + 1},
+			{mbb.Min[0] - 1, mbb.Max[1] + 1},
+		}}
+		im.add(false, append(beyond, loops...)...)
+		s.Coverage = 100 * im.area() / board
+	}
+
+	s.Smallest = math.Inf(1)
+	for _, p := range l.Primitives {
+		for _, f := range Features(p) {
+			size := f.Width
+			if f.IsRegion() {
+				mbb := f.MBB()
+				size = math.Min(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+			}
+			if size > 0 {
+				s.Smallest = math.Min(s.Smallest, size)
+			}
+		}
+	}
+	if math.IsInf(s.Smallest, 1) {
+		s.Smallest = 0
+	}
+
+	if l.Role == DrillRole {
+		hits, _ := l.drillHits()
+		s.DrillHits = map[float64]int{}
+		for d, h := range hits {
+			s.DrillHits[d] = len(h)
+		}
+	}
+	return s
+}
+
+// flatImage returns the flattened image of the layer.
+func (l *Layer) flatImage() *flatImage {
+	im := &flatImage{}
+	for _, p := range l.Primitives {
+		dark := true
+		if c, ok := p.(*ClearT); ok {
+			p, dark = c.Primitive, false
+		}
+		for _, f := range Features(p) {
+			im.addFeature(f, dark)
+		}
+	}
+	return im
+}
+
+// within reports whether the image lies wholly within the loops (by
+// the even-odd rule), so that clipping it to them would leave it
+// unchanged. The image must have been indexed by area.
+func (im *flatImage) within(loops [][]Pt) bool {
+	for _, loop := range loops {
+		for k, a := range loop {
+			if im.top(a) >= 0 {
+				return false
+			}
+			b := loop[(k+1)%len(loop)]
+			mbb := MBB{Min: Pt{math.Min(a[0], b[0]), math.Min(a[1], b[1])}, Max: Pt{math.Max(a[0], b[0]), math.Max(a[1], b[1])}}
+			for _, j := range im.grid.near(mbb) {
+				if !mbbIntersects(im.shapes[j].mbb, mbb) {
+					continue
+				}
+				for _, c := range im.shapes[j].contours {
+					for k, p := range c {
+						if len(splits(a, b, p, c[(k+1)%len(c)])) > 0 {
+							return false
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, s := range im.shapes {
+		var inside bool
+		for _, loop := range loops {
+			if pointInContour(s.contours[0][0], loop) {
+				inside = !inside
+			}
+		}
+		if !inside {
+			return false
+		}
+	}
+	return true
+}
+
+// loopsArea returns the area within the loops by the even-odd rule.
+func loopsArea(loops [][]Pt) float64 {
+	if len(loops) == 0 {
+		return 0
+	}
+	im := &flatImage{}
+	im.add(true, loops...)
+	return im.area()
+}
+
+// boardLoops returns the closed loops of the board outline, formed
+// by joining the ends of the strokes on the outline layers.
+func (g *Gerber) boardLoops() [][]Pt {
+	var loops, paths [][]Pt
+	for _, layer := range g.Layers {
+		if layer.Role != OutlineRole {
+			continue
+		}
+		for _, p := range layer.Primitives {
+			for _, f := range Features(p) {
+				switch {
+				case f.IsRegion():
+					loops = append(loops, f.Region)
+				case len(f.Path) > 1:
+					paths = append(paths, f.Path)
+				}
+			}
+		}
+	}
+
+	joined := func(a, b Pt) bool {
+		return math.Abs(a[0]-b[0]) <= outlineGap && math.Abs(a[1]-b[1]) <= outlineGap
+	}
+	used := make([]bool, len(paths))
+	for i, p := range paths {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		loop := append([]Pt(nil), p...)
+		for !joined(loop[0], loop[len(loop)-1]) {
+			end, found := loop[len(loop)-1], false
+			for j, q := range paths {
+				if used[j] {
+					continue
+				}
+				switch {
+				case joined(end, q[0]):
+					loop = append(loop, q[1:]...)
+				case joined(end, q[len(q)-1]):
+					for k := len(q) - 2; k >= 0; k-- {
+						loop = append(loop, q[k])
+					}
+				default:
+					continue
+				}
+				used[j], found = true, true
+				break
+			}
+			if !found {
+				break
+			}
+		}
+		if len(loop) > 3 && joined(loop[0], loop[len(loop)-1]) {
+			loops = append(loops, loop[:len(loop)-1])
+		}
+	}
+	return loops
+}
@@ -0,0 +1,165 @@
+package gerber
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// square returns the corners of a square with the lower left corner
+// at (x,y).
+func square(x, y, side float64) []Pt {
+	return []Pt{{x, y}, {x + side, y}, {x + side, y + side}, {x, y + side}}
+}
+
+// circleArea returns the area of a circle as it is flattened.
+func circleArea(r float64) float64 {
+	n := 2 * int(math.Ceil(math.Pi/segmentAngle(r, DefaultTolerance)))
+	return 0.5 * float64(n) * r * r * math.Sin(2*math.Pi/float64(n))
+}
+
+func TestLayer_Area(t *testing.T) {
+	tests := []struct {
+		name       string
+		primitives []Primitive
+		want       float64
+	}{
+		{
+			name:       "square",
+			primitives: []Primitive{Polygon(Pt{}, true, square(0, 0, 2), 0)},
+			want:       4,
+		},
+		{
+			name:       "overlapping squares",
+			primitives: []Primitive{Polygon(Pt{}, true, square(0, 0, 2), 0), Polygon(Pt{}, true, square(1, 1, 2), 0)},
+			want:       7,
+		},
+		{
+			name:       "abutting squares",
+			primitives: []Primitive{Polygon(Pt{}, true, square(0, 0, 2), 0), Polygon(Pt{}, true, square(2, 0, 2), 0)},
+			want:       8,
+		},
+		{
+			name:       "repeated square",
+			primitives: []Primitive{Polygon(Pt{}, true, square(0, 0, 2), 0), Polygon(Pt{}, true, square(0, 0, 2), 0)},
+			want:       4,
+		},
+		{
+			name:       "square with a hole",
+			primitives: []Primitive{PolygonWithHoles(Pt{}, square(0, 0, 4), [][]Pt{square(1, 1, 2)})},
+			want:       12,
+		},
+		{
+			name:       "cleared square",
+			primitives: []Primitive{Polygon(Pt{}, true, square(0, 0, 4), 0), Clear(Polygon(Pt{}, true, square(1, 1, 2), 0))},
+			want:       12,
+		},
+		{
+			name: "cleared and redrawn",
+			primitives: []Primitive{
+				Polygon(Pt{}, true, square(0, 0, 4), 0),
+				Clear(Polygon(Pt{}, true, square(1, 1, 2), 0)),
+				Polygon(Pt{}, true, square(1, 1, 1), 0),
+			},
+			want: 13,
+		},
+		{
+			name:       "clear under dark",
+			primitives: []Primitive{Clear(Polygon(Pt{}, true, square(1, 1, 2), 0)), Polygon(Pt{}, true, square(0, 0, 4), 0)},
+			want:       16,
+		},
+		{
+			name:       "circle",
+			primitives: []Primitive{Circle(Pt{3, 4}, 2)},
+			want:       circleArea(1),
+		},
+		{
+			name:       "line",
+			primitives: []Primitive{Line(0, 0, 10, 0, CircleShape, 0.5)},
+			want:       10*0.5 + circleArea(0.25),
+		},
+		{
+			name:       "crossing lines",
+			primitives: []Primitive{Line(0, 0, 10, 0, CircleShape, 0.5), Line(5, -5, 5, 5, CircleShape, 0.5)},
+			want:       2*(10*0.5+circleArea(0.25)) - 0.5*0.5,
+		},
+		{
+			name:       "pad on a trace",
+			primitives: []Primitive{Line(0, 0, 10, 0, CircleShape, 0.5), Circle(Pt{10, 0}, 0.5)},
+			want:       10*0.5 + circleArea(0.25),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New("test")
+			layer := g.TopCopper()
+			layer.Add(tt.primitives...)
+			if got := layer.Area(); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Area = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGerber_Stats(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	drill := g.Drill()
+	outline := g.Outline()
+	// A 10x10mm board (drawn out of order) with a 2x2mm cutout.
+	outline.Add(
+		Line(0, 0, 10, 0, CircleShape, 0.1),
+		Line(10, 10, 0, 10, CircleShape, 0.1),
+		Line(0, 10, 0, 0, CircleShape, 0.1),
+		Line(10, 0, 10, 10, CircleShape, 0.1),
+		Polygon(Pt{}, false, square(7, 7, 2), 0.1),
+	)
+	// Copper over the left half of the board, overhanging its edge.
+	top.Add(Polygon(Pt{}, true, []Pt{{-1, -1}, {5, -1}, {5, 11}, {-1, 11}}, 0), Circle(Pt{8, 2}, 0.3))
+	drill.Add(Circle(Pt{1, 1}, 0.3), Circle(Pt{2, 1}, 0.3), Circle(Pt{8, 2}, 0.8), Line(3, 3, 4, 3, CircleShape, 0.8))
+	// Copper wholly within the board.
+	bottom := g.BottomCopper()
+	bottom.Add(Circle(Pt{5, 5}, 4))
+
+	if got := g.BoardArea(); math.Abs(got-96) > 1e-6 {
+		t.Errorf("BoardArea = %v, want 96", got)
+	}
+
+	stats := g.Stats()
+	if len(stats) != 4 {
+		t.Fatalf("Stats = %v layers, want 4", len(stats))
+	}
+	copper := stats[0]
+	if copper.Layer != top || copper.Primitives != 2 || copper.Apertures != 1 {
+		t.Errorf("copper stats = %+v, want 2 primitives and 1 aperture", copper)
+	}
+	pad := circleArea(0.15)
+	if want := 72 + pad; math.Abs(copper.Area-want) > 1e-6 {
+		t.Errorf("copper Area = %v, want %v", copper.Area, want)
+	}
+	if want := 100 * (50 + pad) / 96; math.Abs(copper.Coverage-want) > 1e-6 {
+		t.Errorf("copper Coverage = %v, want %v", copper.Coverage, want)
+	}
+	if copper.Smallest != 0.3 || copper.DrillHits != nil {
+		t.Errorf("copper Smallest, DrillHits = %v, %v, want 0.3, nil", copper.Smallest, copper.DrillHits)
+	}
+
+	if got, want := stats[1].DrillHits, map[float64]int{0.3: 2, 0.8: 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DrillHits = %v, want %v", got, want)
+	}
+	if got := stats[2].Coverage; got <= 0 {
+		t.Errorf("outline Coverage = %v, want some", got)
+	}
+	if got, want := stats[3].Coverage, 100*circleArea(2)/96; math.Abs(got-want) > 1e-6 {
+		t.Errorf("bottom copper Coverage = %v, want %v", got, want)
+	}
+
+	// A design without an outline has no coverage.
+	g = New("test")
+	top = g.TopCopper()
+	top.Add(Circle(Pt{}, 1))
+	if s := top.Stats(); s.Coverage != 0 || s.Area <= 0 {
+		t.Errorf("Stats = %+v, want an area and no coverage", s)
+	}
+}
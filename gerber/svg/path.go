@@ -0,0 +1,398 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// subpath is a sequence of line and cubic Bezier segments, stored as
+// the start point followed by three points (two control points and
+// an end point) per segment. Line segments have their control points
+// on the line.
+type subpath struct {
+	pts    []gerber.Pt
+	lines  []bool // whether each segment is a straight line
+	closed bool
+}
+
+func (s *subpath) lineTo(pt gerber.Pt) {
+	last := s.pts[len(s.pts)-1]
+	s.pts = append(s.pts, last, pt, pt)
+	s.lines = append(s.lines, true)
+}
+
+func (s *subpath) cubicTo(c1, c2, pt gerber.Pt) {
+	s.pts = append(s.pts, c1, c2, pt)
+	s.lines = append(s.lines, false)
+}
+
+// transform applies the transformation to all the points.
+func (s *subpath) transform(xf gerber.Transform) {
+	for i, pt := range s.pts {
+		s.pts[i] = xf.Apply(pt)
+	}
+}
+
+// flatten returns the points along the subpath, flattening its curves
+// within tol. Closed subpaths end with their first point.
+func (s *subpath) flatten(tol float64) []gerber.Pt {
+	pts := []gerber.Pt{s.pts[0]}
+	for i, line := range s.lines {
+		seg := s.pts[3*i : 3*i+4]
+		if line {
+			pts = append(pts, seg[3])
+			continue
+		}
+		b := gerber.CubicBezier(seg, gerber.CircleShape, 0)
+		b.Tolerance = tol
+		pts = append(pts, b.Flatten()[1:]...)
+	}
+	if s.closed && pts[len(pts)-1] != pts[0] {
+		pts = append(pts, pts[0])
+	}
+	return pts
+}
+
+// pathScanner tokenizes SVG path data and point lists.
+type pathScanner struct {
+	s   string
+	pos int
+}
+
+func (p *pathScanner) skipSpace() {
+	for p.pos < len(p.s) {
+		switch p.s[p.pos] {
+		case ' ', '\t', '\n', '\r', ',':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// command returns the next command letter, if any.
+func (p *pathScanner) command() (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') && c != 'e' && c != 'E' {
+			p.pos++
+			return c, true
+		}
+	}
+	return 0, false
+}
+
+// more reports whether a number follows.
+func (p *pathScanner) more() bool {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return false
+	}
+	c := p.s[p.pos]
+	return c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.'
+}
+
+// number returns the next number, which may directly follow the
+// previous one (as in "1-2" or "1.5.5").
+func (p *pathScanner) number() (float64, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos < len(p.s) && (p.s[p.pos] == '-' || p.s[p.pos] == '+') {
+		p.pos++
+	}
+	var dot, digits bool
+scan:
+	for ; p.pos < len(p.s); p.pos++ {
+		switch c := p.s[p.pos]; {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == '.' && !dot:
+			dot = true
+		case (c == 'e' || c == 'E') && digits:
+			if p.pos+1 < len(p.s) && (p.s[p.pos+1] == '-' || p.s[p.pos+1] == '+') {
+				p.pos++
+			}
+		default:
+			break scan
+		}
+	}
+	v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number at offset %v in %q", start, p.s)
+	}
+	return v, nil
+}
+
+// flag returns the next arc flag, which may be a single
+// digit directly followed by the next number.
+func (p *pathScanner) flag() (bool, error) {
+	p.skipSpace()
+	if p.pos < len(p.s) && (p.s[p.pos] == '0' || p.s[p.pos] == '1') {
+		p.pos++
+		return p.s[p.pos-1] == '1', nil
+	}
+	return false, fmt.Errorf("invalid arc flag at offset %v in %q", p.pos, p.s)
+}
+
+func (p *pathScanner) numbers(n int) ([]float64, error) {
+	v := make([]float64, n)
+	for i := range v {
+		var err error
+		if v[i], err = p.number(); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// parsePoints parses the points attribute of a polygon or polyline.
+func parsePoints(s string) ([]gerber.Pt, error) {
+	p := &pathScanner{s: s}
+	var pts []gerber.Pt
+	for p.more() {
+		v, err := p.numbers(2)
+		if err != nil {
+			return nil, err
+		}
+		pts = append(pts, gerber.Pt{v[0], v[1]})
+	}
+	return pts, nil
+}
+
+// parsePath parses SVG path data into subpaths.
+func parsePath(d string) ([]*subpath, error) {
+	p := &pathScanner{s: d}
+	var paths []*subpath
+	var cur *subpath
+	var pt, start, lastCtrl gerber.Pt
+	var prevCmd byte
+
+	ensure := func() {
+		if cur == nil {
+			cur = &subpath{pts: []gerber.Pt{pt}}
+			paths = append(paths, cur)
+		}
+	}
+
+	for {
+		cmd, ok := p.command()
+		if !ok {
+			if p.pos < len(p.s) {
+				return nil, fmt.Errorf("unexpected %q at offset %v in path", p.s[p.pos], p.pos)
+			}
+			break
+		}
+		if prevCmd == 0 && cmd != 'M' && cmd != 'm' {
+			return nil, fmt.Errorf("path must start with a move-to, not %q", cmd)
+		}
+		if cmd == 'Z' || cmd == 'z' {
+			if cur != nil {
+				if pt != start {
+					cur.lineTo(start)
+				}
+				cur.closed = true
+				cur = nil
+			}
+			pt, prevCmd = start, cmd
+			continue
+		}
+		rel := cmd >= 'a'
+		offset := func(x, y float64) gerber.Pt {
+			if rel {
+				return gerber.Pt{pt[0] + x, pt[1] + y}
+			}
+			return gerber.Pt{x, y}
+		}
+
+		for first := true; first || p.more(); first = false {
+			switch cmd {
+			case 'M', 'm':
+				v, err := p.numbers(2)
+				if err != nil {
+					return nil, err
+				}
+				if first {
+					pt = offset(v[0], v[1])
+					start = pt
+					cur = &subpath{pts: []gerber.Pt{pt}}
+					paths = append(paths, cur)
+					break
+				}
+				// Subsequent pairs are implicit line-to commands.
+				pt = offset(v[0], v[1])
+				cur.lineTo(pt)
+			case 'L', 'l':
+				v, err := p.numbers(2)
+				if err != nil {
+					return nil, err
+				}
+				ensure()
+				pt = offset(v[0], v[1])
+				cur.lineTo(pt)
+			case 'H', 'h':
+				x, err := p.number()
+				if err != nil {
+					return nil, err
+				}
+				ensure()
+				if rel {
+					x += pt[0]
+				}
+				pt = gerber.Pt{x, pt[1]}
+				cur.lineTo(pt)
+			case 'V', 'v':
+				y, err := p.number()
+				if err != nil {
+					return nil, err
+				}
+				ensure()
+				if rel {
+					y += pt[1]
+				}
+				pt = gerber.Pt{pt[0], y}
+				cur.lineTo(pt)
+			case 'C', 'c', 'S', 's':
+				smooth := cmd == 'S' || cmd == 's'
+				n := 6
+				if smooth {
+					n = 4
+				}
+				v, err := p.numbers(n)
+				if err != nil {
+					return nil, err
+				}
+				ensure()
+				c1 := pt
+				if smooth {
+					if prevCmd == 'C' || prevCmd == 'c' || prevCmd == 'S' || prevCmd == 's' {
+						c1 = gerber.Pt{2*pt[0] - lastCtrl[0], 2*pt[1] - lastCtrl[1]}
+					}
+					v = append([]float64{0, 0}, v...)
+				} else {
+					c1 = offset(v[0], v[1])
+				}
+				c2, end := offset(v[2], v[3]), offset(v[4], v[5])
+				cur.cubicTo(c1, c2, end)
+				lastCtrl, pt = c2, end
+			case 'Q', 'q', 'T', 't':
+				smooth := cmd == 'T' || cmd == 't'
+				var q, end gerber.Pt
+				if smooth {
+					v, err := p.numbers(2)
+					if err != nil {
+						return nil, err
+					}
+					q = pt
+					if prevCmd == 'Q' || prevCmd == 'q' || prevCmd == 'T' || prevCmd == 't' {
+						q = gerber.Pt{2*pt[0] - lastCtrl[0], 2*pt[1] - lastCtrl[1]}
+					}
+					end = offset(v[0], v[1])
+				} else {
+					v, err := p.numbers(4)
+					if err != nil {
+						return nil, err
+					}
+					q, end = offset(v[0], v[1]), offset(v[2], v[3])
+				}
+				ensure()
+				// Elevate the quadratic to a cubic.
+				c1 := gerber.Pt{pt[0] + 2.0/3*(q[0]-pt[0]), pt[1] + 2.0/3*(q[1]-pt[1])}
+				c2 := gerber.Pt{end[0] + 2.0/3*(q[0]-end[0]), end[1] + 2.0/3*(q[1]-end[1])}
+				cur.cubicTo(c1, c2, end)
+				lastCtrl, pt = q, end
+			case 'A', 'a':
+				v, err := p.numbers(3)
+				if err != nil {
+					return nil, err
+				}
+				large, err := p.flag()
+				if err != nil {
+					return nil, err
+				}
+				sweep, err := p.flag()
+				if err != nil {
+					return nil, err
+				}
+				e, err := p.numbers(2)
+				if err != nil {
+					return nil, err
+				}
+				ensure()
+				end := offset(e[0], e[1])
+				arcTo(cur, pt, end, v[0], v[1], v[2], large, sweep)
+				pt = end
+			default:
+				return nil, fmt.Errorf("unsupported path command %q", cmd)
+			}
+			prevCmd = cmd
+		}
+	}
+	return paths, nil
+}
+
+// arcTo appends the elliptical arc from p1 to p2 as cubic Beziers,
+// following the endpoint to center conversion of the SVG specification.
+func arcTo(s *subpath, p1, p2 gerber.Pt, rx, ry, phi float64, large, sweep bool) {
+	if p1 == p2 {
+		return
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		s.lineTo(p2)
+		return
+	}
+	sinPhi, cosPhi := math.Sincos(phi * math.Pi / 180)
+	dx, dy := 0.5*(p1[0]-p2[0]), 0.5*(p1[1]-p2[1])
+	x1 := cosPhi*dx + sinPhi*dy
+	y1 := -sinPhi*dx + cosPhi*dy
+
+	// Scale up radii that are too small to reach.
+	if l := x1*x1/(rx*rx) + y1*y1/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1*y1 - ry*ry*x1*x1
+	den := rx*rx*y1*y1 + ry*ry*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1, cy1 := coef*rx*y1/ry, -coef*ry*x1/rx
+	cx := cosPhi*cx1 - sinPhi*cy1 + 0.5*(p1[0]+p2[0])
+	cy := sinPhi*cx1 + cosPhi*cy1 + 0.5*(p1[1]+p2[1])
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	theta := angle(1, 0, (x1-cx1)/rx, (y1-cy1)/ry)
+	delta := angle((x1-cx1)/rx, (y1-cy1)/ry, (-x1-cx1)/rx, (-y1-cy1)/ry)
+	if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	}
+
+	// Approximate each quarter (or less) of the arc with a cubic.
+	n := int(math.Ceil(math.Abs(delta) / (0.5 * math.Pi)))
+	step := delta / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	point := func(x, y float64) gerber.Pt {
+		ex, ey := rx*x, ry*y
+		return gerber.Pt{cx + cosPhi*ex - sinPhi*ey, cy + sinPhi*ex + cosPhi*ey}
+	}
+	for i := 0; i < n; i++ {
+		a1 := theta + step*float64(i)
+		a2 := a1 + step
+		s1, c1 := math.Sincos(a1)
+		s2, c2 := math.Sincos(a2)
+		end := point(c2, s2)
+		if i == n-1 {
+			end = p2
+		}
+		s.cubicTo(point(c1-k*s1, s1+k*c1), point(c2+k*s2, s2-k*c2), end)
+	}
+}
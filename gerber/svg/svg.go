@@ -0,0 +1,500 @@
+// Package svg imports SVG artwork (such as logos) into Gerber primitives.
+//
+// The path, rect, circle, ellipse, polygon and polyline elements are
+// supported, along with nested groups, transforms and the fill,
+// fill-rule, stroke and stroke-width properties (as attributes or
+// within style attributes). Filled shapes become polygon regions with
+// holes, and stroked shapes become traces.
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+// style represents the inherited presentation properties of an element.
+type style struct {
+	fill        bool
+	evenOdd     bool
+	stroke      bool
+	strokeWidth float64
+	xf          gerber.Transform
+}
+
+// shape is a parsed element, in (transformed) SVG user units.
+type shape struct {
+	paths       []*subpath
+	fill        bool
+	evenOdd     bool
+	stroke      bool
+	strokeWidth float64 // scaled by the element's transformation
+}
+
+// Parse reads the SVG document and returns its shapes as primitives,
+// scaled to the given width (in millimeters) with the lower-left
+// corner of their bounding box at origin.
+func Parse(r io.Reader, origin gerber.Pt, width float64) ([]gerber.Primitive, error) {
+	shapes, err := parseShapes(r)
+	if err != nil {
+		return nil, err
+	}
+	return convert(shapes, origin, width)
+}
+
+// Import reads the SVG document and adds its shapes to the layer.
+// See Parse.
+func Import(layer *gerber.Layer, r io.Reader, origin gerber.Pt, width float64) error {
+	primitives, err := Parse(r, origin, width)
+	if err != nil {
+		return err
+	}
+	layer.Add(primitives...)
+	return nil
+}
+
+// parseShapes parses the supported elements of the document.
+func parseShapes(r io.Reader) ([]*shape, error) {
+	d := xml.NewDecoder(r)
+	stack := []style{{fill: true, strokeWidth: 1, xf: gerber.Identity()}}
+	var skip int // depth within elements that are not rendered
+	var shapes []*shape
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("svg: %v", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "defs", "clipPath", "mask", "symbol", "marker", "pattern", "title", "desc", "metadata":
+				skip = 1
+				continue
+			}
+			st, err := stack[len(stack)-1].apply(t.Attr)
+			if err != nil {
+				return nil, err
+			}
+			if attr(t.Attr, "display") == "none" {
+				skip = 1
+				continue
+			}
+			stack = append(stack, st)
+			paths, err := elementPaths(t)
+			if err != nil {
+				return nil, err
+			}
+			if len(paths) == 0 || (!st.fill && !st.stroke) {
+				continue
+			}
+			for _, p := range paths {
+				p.transform(st.xf)
+			}
+			// Scale the stroke width as the transformation scales areas.
+			sw := st.strokeWidth * math.Sqrt(math.Abs(st.xf.Det()))
+			shapes = append(shapes, &shape{paths: paths, fill: st.fill, evenOdd: st.evenOdd, stroke: st.stroke, strokeWidth: sw})
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return shapes, nil
+}
+
+// attr returns the value of the named attribute, or "".
+func attr(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if a.Name.Local == name {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+// apply returns the style of an element with the given
+// attributes, inheriting from s.
+func (s style) apply(attrs []xml.Attr) (style, error) {
+	props := map[string]string{}
+	for _, a := range attrs {
+		props[a.Name.Local] = strings.TrimSpace(a.Value)
+	}
+	// Style properties override presentation attributes.
+	for _, decl := range strings.Split(props["style"], ";") {
+		if kv := strings.SplitN(decl, ":", 2); len(kv) == 2 {
+			props[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+
+	if v, ok := props["fill"]; ok && v != "inherit" {
+		s.fill = v != "none" && v != "transparent"
+	}
+	if v, ok := props["fill-rule"]; ok && v != "inherit" {
+		s.evenOdd = v == "evenodd"
+	}
+	if v, ok := props["stroke"]; ok && v != "inherit" {
+		s.stroke = v != "none" && v != "transparent"
+	}
+	if v, ok := props["stroke-width"]; ok && v != "inherit" {
+		w, err := length(v)
+		if err != nil {
+			return s, err
+		}
+		s.strokeWidth = w
+	}
+	if v, ok := props["transform"]; ok {
+		xf, err := parseTransform(v)
+		if err != nil {
+			return s, err
+		}
+		s.xf = xf.Then(s.xf)
+	}
+	return s, nil
+}
+
+// length parses a length, ignoring any units since the
+// artwork is scaled to its final size anyway.
+func length(s string) (float64, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), "abcdefghijklmnopqrstuvwxyz%")
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("svg: invalid length %q", s)
+	}
+	return v, nil
+}
+
+// parseTransform parses a transform attribute, such as
+// "translate(10,20) rotate(45)".
+func parseTransform(s string) (gerber.Transform, error) {
+	xf := gerber.Identity()
+	for {
+		s = strings.TrimLeft(s, " \t\n\r,")
+		if s == "" {
+			return xf, nil
+		}
+		open, end := strings.Index(s, "("), strings.Index(s, ")")
+		if open < 0 || end < open {
+			return xf, fmt.Errorf("svg: invalid transform %q", s)
+		}
+		name := strings.TrimSpace(s[:open])
+		p := &pathScanner{s: s[open+1 : end]}
+		var v []float64
+		for p.more() {
+			n, err := p.number()
+			if err != nil {
+				return xf, fmt.Errorf("svg: invalid transform: %v", err)
+			}
+			v = append(v, n)
+		}
+
+		var t gerber.Transform
+		switch {
+		case name == "matrix" && len(v) == 6:
+			t = gerber.Transform{A: v[0], B: v[2], C: v[4], D: v[1], E: v[3], F: v[5]}
+		case name == "translate" && len(v) == 1:
+			t = gerber.Translate(v[0], 0)
+		case name == "translate" && len(v) == 2:
+			t = gerber.Translate(v[0], v[1])
+		case name == "scale" && len(v) == 1:
+			t = gerber.Scale(v[0], v[0])
+		case name == "scale" && len(v) == 2:
+			t = gerber.Scale(v[0], v[1])
+		case name == "rotate" && len(v) == 1:
+			t = gerber.Rotate(v[0])
+		case name == "rotate" && len(v) == 3:
+			t = gerber.RotateAbout(gerber.Pt{v[1], v[2]}, v[0])
+		case name == "skewX" && len(v) == 1:
+			t = gerber.Transform{A: 1, B: math.Tan(v[0] * math.Pi / 180), E: 1}
+		case name == "skewY" && len(v) == 1:
+			t = gerber.Transform{A: 1, D: math.Tan(v[0] * math.Pi / 180), E: 1}
+		default:
+			return xf, fmt.Errorf("svg: unsupported transform %q", s[:end+1])
+		}
+		// The rightmost transformation is applied first.
+		xf = t.Then(xf)
+		s = s[end+1:]
+	}
+}
+
+// elementPaths returns the subpaths of a shape element, if any.
+func elementPaths(e xml.StartElement) ([]*subpath, error) {
+	var err error
+	num := func(name string) float64 {
+		v, lerr := length(attr(e.Attr, name))
+		if lerr != nil && err == nil {
+			err = lerr
+		}
+		return v
+	}
+	ellipse := func(cx, cy, rx, ry float64) []*subpath {
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		p1, p2 := gerber.Pt{cx + rx, cy}, gerber.Pt{cx - rx, cy}
+		s := &subpath{pts: []gerber.Pt{p1}, closed: true}
+		arcTo(s, p1, p2, rx, ry, 0, false, true)
+		arcTo(s, p2, p1, rx, ry, 0, false, true)
+		return []*subpath{s}
+	}
+
+	var paths []*subpath
+	switch e.Name.Local {
+	case "path":
+		paths, err = parsePath(attr(e.Attr, "d"))
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		rx, ry := num("rx"), num("ry")
+		if w <= 0 || h <= 0 {
+			break
+		}
+		// A missing radius defaults to the other one.
+		if attr(e.Attr, "rx") == "" {
+			rx = ry
+		}
+		if attr(e.Attr, "ry") == "" {
+			ry = rx
+		}
+		rx, ry = math.Min(math.Abs(rx), 0.5*w), math.Min(math.Abs(ry), 0.5*h)
+		s := &subpath{pts: []gerber.Pt{{x + rx, y}}, closed: true}
+		corner := func(p1, p2 gerber.Pt) {
+			s.lineTo(p1)
+			arcTo(s, p1, p2, rx, ry, 0, false, true)
+		}
+		corner(gerber.Pt{x + w - rx, y}, gerber.Pt{x + w, y + ry})
+		corner(gerber.Pt{x + w, y + h - ry}, gerber.Pt{x + w - rx, y + h})
+		corner(gerber.Pt{x + rx, y + h}, gerber.Pt{x, y + h - ry})
+		corner(gerber.Pt{x, y + ry}, gerber.Pt{x + rx, y})
+		paths = []*subpath{s}
+	case "circle":
+		r := num("r")
+		paths = ellipse(num("cx"), num("cy"), r, r)
+	case "ellipse":
+		paths = ellipse(num("cx"), num("cy"), num("rx"), num("ry"))
+	case "line":
+		s := &subpath{pts: []gerber.Pt{{num("x1"), num("y1")}}}
+		s.lineTo(gerber.Pt{num("x2"), num("y2")})
+		paths = []*subpath{s}
+	case "polygon", "polyline":
+		var pts []gerber.Pt
+		pts, err = parsePoints(attr(e.Attr, "points"))
+		if err != nil || len(pts) < 2 {
+			break
+		}
+		s := &subpath{pts: pts[:1], closed: e.Name.Local == "polygon"}
+		for _, pt := range pts[1:] {
+			s.lineTo(pt)
+		}
+		paths = []*subpath{s}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("svg: %v: %v", e.Name.Local, err)
+	}
+	// Drop the lone move-tos.
+	var result []*subpath
+	for _, p := range paths {
+		if len(p.lines) > 0 {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// convert scales the shapes to their final size and converts
+// them into primitives.
+func convert(shapes []*shape, origin gerber.Pt, width float64) ([]gerber.Primitive, error) {
+	if len(shapes) == 0 {
+		return nil, nil
+	}
+	// Find the scale from the geometry, flattened tightly
+	// relative to the size of its control points.
+	bounds := func(points func(p *subpath) []gerber.Pt) gerber.MBB {
+		var mbb *gerber.MBB
+		for _, sh := range shapes {
+			for _, p := range sh.paths {
+				for _, pt := range points(p) {
+					if mbb == nil {
+						mbb = &gerber.MBB{Min: pt, Max: pt}
+					}
+					mbb.Join(&gerber.MBB{Min: pt, Max: pt})
+				}
+			}
+		}
+		return *mbb
+	}
+	mbb := bounds(func(p *subpath) []gerber.Pt { return p.pts })
+	size := math.Max(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+	mbb = bounds(func(p *subpath) []gerber.Pt { return p.flatten(1e-6 * size) })
+	if mbb.Max[0] <= mbb.Min[0] {
+		return nil, fmt.Errorf("svg: artwork has no width")
+	}
+	s := width / (mbb.Max[0] - mbb.Min[0])
+	xf := gerber.Transform{A: s, C: origin[0] - s*mbb.Min[0], E: -s, F: origin[1] + s*mbb.Max[1]}
+	tol := gerber.DefaultTolerance / s
+
+	var result []gerber.Primitive
+	for _, sh := range shapes {
+		if sh.fill {
+			var contours [][]gerber.Pt
+			for _, p := range sh.paths {
+				// Fills implicitly close every subpath.
+				pts := p.flatten(tol)
+				if pts[len(pts)-1] != pts[0] {
+					pts = append(pts, pts[0])
+				}
+				contours = append(contours, apply(xf, pts[:len(pts)-1]))
+			}
+			for _, poly := range fillPolygons(contours, sh.evenOdd) {
+				result = append(result, poly)
+			}
+		}
+		if sh.stroke && sh.strokeWidth > 0 {
+			for _, p := range sh.paths {
+				result = append(result, gerber.Trace(apply(xf, p.flatten(tol)), gerber.CircleShape, s*sh.strokeWidth))
+			}
+		}
+	}
+	return result, nil
+}
+
+// fillPolygons classifies the contours of a filled shape as outers,
+// holes or redundant (bounding filled areas on both sides) under the
+// fill rule, and returns the outers with their holes.
+func fillPolygons(contours [][]gerber.Pt, evenOdd bool) []*gerber.PolygonT {
+	filled := func(pt gerber.Pt) bool {
+		var w int
+		for _, c := range contours {
+			w += winding(pt, c)
+		}
+		if evenOdd {
+			return w%2 != 0
+		}
+		return w != 0
+	}
+
+	type outer struct {
+		pts   []gerber.Pt
+		area  float64
+		holes [][]gerber.Pt
+	}
+	var outers []*outer
+	type hole struct {
+		pts []gerber.Pt
+		at  gerber.Pt // a point just outside the hole
+	}
+	var holes []hole
+	for _, c := range contours {
+		left, right, ok := sides(c)
+		if !ok {
+			continue
+		}
+		l, r := filled(left), filled(right)
+		if l == r {
+			continue
+		}
+		area := area(c)
+		// The inside of a counter-clockwise contour is on its left.
+		inside, outside := left, right
+		if area < 0 {
+			inside, outside = right, left
+			area = -area
+		}
+		if filled(inside) {
+			outers = append(outers, &outer{pts: c, area: area})
+		} else {
+			holes = append(holes, hole{pts: c, at: outside})
+		}
+	}
+
+	// Assign each hole to the smallest outer containing it.
+	for _, h := range holes {
+		var best *outer
+		for _, o := range outers {
+			if (best == nil || o.area < best.area) && winding(h.at, o.pts) != 0 {
+				best = o
+			}
+		}
+		if best != nil {
+			best.holes = append(best.holes, h.pts)
+		}
+	}
+	var result []*gerber.PolygonT
+	for _, o := range outers {
+		result = append(result, gerber.PolygonWithHoles(gerber.Pt{}, o.pts, o.holes))
+	}
+	return result
+}
+
+// sides returns points just to the left and right of the middle
+// of the longest edge of the closed contour.
+func sides(c []gerber.Pt) (left, right gerber.Pt, ok bool) {
+	var a, b gerber.Pt
+	var best float64
+	for i, pt := range c {
+		next := c[(i+1)%len(c)]
+		if l := math.Hypot(next[0]-pt[0], next[1]-pt[1]); l > best {
+			a, b, best = pt, next, l
+		}
+	}
+	if best == 0 {
+		return left, right, false
+	}
+	const eps = 1e-6 // millimeters
+	mid := gerber.Pt{0.5 * (a[0] + b[0]), 0.5 * (a[1] + b[1])}
+	nx, ny := -(b[1]-a[1])/best*eps, (b[0]-a[0])/best*eps
+	return gerber.Pt{mid[0] + nx, mid[1] + ny}, gerber.Pt{mid[0] - nx, mid[1] - ny}, true
+}
+
+// winding returns the winding number of the closed contour around pt.
+func winding(pt gerber.Pt, c []gerber.Pt) int {
+	var w int
+	for i, a := range c {
+		b := c[(i+1)%len(c)]
+		cross := (b[0]-a[0])*(pt[1]-a[1]) - (pt[0]-a[0])*(b[1]-a[1])
+		switch {
+		case a[1] <= pt[1] && b[1] > pt[1] && cross > 0:
+			w++
+		case a[1] > pt[1] && b[1] <= pt[1] && cross < 0:
+			w--
+		}
+	}
+	return w
+}
+
+// area returns the signed area of the closed contour,
+// which is positive for counter-clockwise contours.
+func area(c []gerber.Pt) float64 {
+	var sum float64
+	for i, a := range c {
+		b := c[(i+1)%len(c)]
+		sum += a[0]*b[1] - b[0]*a[1]
+	}
+	return 0.5 * sum
+}
+
+// apply returns a transformed copy of pts.
+func apply(xf gerber.Transform, pts []gerber.Pt) []gerber.Pt {
+	result := make([]gerber.Pt, len(pts))
+	for i, pt := range pts {
+		result[i] = xf.Apply(pt)
+	}
+	return result
+}
@@ -0,0 +1,273 @@
+package svg
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+)
+
+const eps = 1e-6
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		d      string
+		want   [][]gerber.Pt // end points of the segments
+		closed []bool
+	}{
+		{
+			name:   "absolute lines",
+			d:      "M0,0 L10,0 L10,10 Z",
+			want:   [][]gerber.Pt{{{0, 0}, {10, 0}, {10, 10}, {0, 0}}},
+			closed: []bool{true},
+		},
+		{
+			name:   "relative and compact numbers",
+			d:      "m1-1h2v.5.5l-1-1z",
+			want:   [][]gerber.Pt{{{1, -1}, {3, -1}, {3, -0.5}, {3, 0}, {2, -1}, {1, -1}}},
+			closed: []bool{true},
+		},
+		{
+			name:   "implicit line-tos and two subpaths",
+			d:      "M0 0 1 0 1 1 M5 5 l1 0",
+			want:   [][]gerber.Pt{{{0, 0}, {1, 0}, {1, 1}}, {{5, 5}, {6, 5}}},
+			closed: []bool{false, false},
+		},
+		{
+			name:   "subpath after close starts at its start",
+			d:      "M2 2 h1 z l0 1",
+			want:   [][]gerber.Pt{{{2, 2}, {3, 2}, {2, 2}}, {{2, 2}, {2, 3}}},
+			closed: []bool{true, false},
+		},
+		{
+			name:   "arc flags without separators",
+			d:      "M0 0a5 5 0 1010 0",
+			want:   [][]gerber.Pt{{{0, 0}, {5, 5}, {10, 0}}},
+			closed: []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := parsePath(tt.d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != len(tt.want) {
+				t.Fatalf("got %v subpaths, want %v", len(paths), len(tt.want))
+			}
+			for i, p := range paths {
+				var got []gerber.Pt
+				for j := 0; j < len(p.pts); j += 3 {
+					got = append(got, p.pts[j])
+				}
+				if len(got) != len(tt.want[i]) {
+					t.Fatalf("subpath %v: got %v, want %v", i, got, tt.want[i])
+				}
+				for j := range got {
+					if math.Abs(got[j][0]-tt.want[i][j][0]) > eps || math.Abs(got[j][1]-tt.want[i][j][1]) > eps {
+						t.Errorf("subpath %v point %v = %v, want %v", i, j, got[j], tt.want[i][j])
+					}
+				}
+				if p.closed != tt.closed[i] {
+					t.Errorf("subpath %v closed = %v, want %v", i, p.closed, tt.closed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParsePath_Errors(t *testing.T) {
+	for _, d := range []string{"L1 1", "M0 0 L1", "M0 0 X1 1", "M0 0 A1 1 0 2 0 1 1"} {
+		if _, err := parsePath(d); err == nil {
+			t.Errorf("parsePath(%q) = nil error, want error", d)
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	tests := []struct {
+		s    string
+		pt   gerber.Pt
+		want gerber.Pt
+	}{
+		{s: "translate(10,20)", pt: gerber.Pt{1, 2}, want: gerber.Pt{11, 22}},
+		{s: "translate(10)", pt: gerber.Pt{1, 2}, want: gerber.Pt{11, 2}},
+		{s: "scale(2)", pt: gerber.Pt{1, 2}, want: gerber.Pt{2, 4}},
+		{s: "scale(2 3)", pt: gerber.Pt{1, 2}, want: gerber.Pt{2, 6}},
+		{s: "rotate(90)", pt: gerber.Pt{1, 0}, want: gerber.Pt{0, 1}},
+		{s: "rotate(90 1 1)", pt: gerber.Pt{2, 1}, want: gerber.Pt{1, 2}},
+		{s: "matrix(1,2,3,4,5,6)", pt: gerber.Pt{1, 1}, want: gerber.Pt{9, 12}},
+		{s: "skewX(45)", pt: gerber.Pt{0, 1}, want: gerber.Pt{1, 1}},
+		// The rightmost transformation is applied first.
+		{s: "translate(10,0) scale(2)", pt: gerber.Pt{1, 1}, want: gerber.Pt{12, 2}},
+		{s: "scale(2),translate(10,0)", pt: gerber.Pt{1, 1}, want: gerber.Pt{22, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			xf, err := parseTransform(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := xf.Apply(tt.pt)
+			if math.Abs(got[0]-tt.want[0]) > eps || math.Abs(got[1]-tt.want[1]) > eps {
+				t.Errorf("Apply(%v) = %v, want %v", tt.pt, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseTransform("warp(1)"); err == nil {
+		t.Error("parseTransform(warp) = nil error, want error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	// Two squares side by side, one of them with a hole, in y-down SVG
+	// coordinates, with nested transformations.
+	const doc = `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="40mm" height="20mm" viewBox="0 0 40 20">
+  <defs><rect id="unused" width="1000" height="1000"/></defs>
+  <g transform="translate(10,0)">
+    <path d="M-10,0 h20 v20 h-20 z M-5,5 v10 h10 v-10 z" fill-rule="evenodd"/>
+    <g style="fill:#000;fill-rule:nonzero" transform="translate(20,0)">
+      <rect x="-10" width="20" height="20"/>
+    </g>
+    <rect x="0" y="0" width="100" height="100" display="none"/>
+  </g>
+</svg>`
+
+	primitives, err := Parse(strings.NewReader(doc), gerber.Pt{1, 2}, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(primitives) != 2 {
+		t.Fatalf("got %v primitives, want 2", len(primitives))
+	}
+
+	p0, ok := primitives[0].(*gerber.PolygonT)
+	if !ok || len(p0.Holes) != 1 {
+		t.Fatalf("primitives[0] = %#v, want a polygon with one hole", primitives[0])
+	}
+	// The artwork is scaled by 2 and flipped to have the y axis up.
+	for _, tt := range []struct {
+		pt   gerber.Pt
+		want bool
+	}{
+		{pt: gerber.Pt{3, 4}, want: true},
+		{pt: gerber.Pt{21, 20}, want: false}, // in the hole
+		{pt: gerber.Pt{21, 38}, want: true},
+		{pt: gerber.Pt{43, 20}, want: false}, // in the other square
+	} {
+		if got := p0.Contains(tt.pt); got != tt.want {
+			t.Errorf("primitives[0].Contains(%v) = %v, want %v", tt.pt, got, tt.want)
+		}
+	}
+
+	// The hole is joined to the outer contour by a cut-in, so the
+	// polygon is written as a single region closed back to its start.
+	var buf bytes.Buffer
+	if err := p0.WriteGerber(&buf, 11); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	region := out[strings.Index(out, "G36*\n")+len("G36*\n") : strings.Index(out, "G37*\n")]
+	lines := strings.Split(strings.TrimSpace(region), "\n")
+	first, last := lines[0], lines[len(lines)-1]
+	if strings.Count(out, "G36*") != 1 || strings.Count(region, "D02*") != 1 || !strings.HasSuffix(first, "D02*") ||
+		strings.TrimSuffix(last, "D01*") != strings.TrimSuffix(first, "D02*") {
+		t.Errorf("WriteGerber = %v, want a single region closed back to its start", out)
+	}
+
+	var mbb gerber.MBB
+	for i, p := range primitives {
+		v := p.MBB()
+		if i == 0 {
+			mbb = v
+			continue
+		}
+		mbb.Join(&v)
+	}
+	want := gerber.MBB{Min: gerber.Pt{1, 2}, Max: gerber.Pt{81, 42}}
+	if math.Abs(mbb.Min[0]-want.Min[0]) > eps || math.Abs(mbb.Min[1]-want.Min[1]) > eps ||
+		math.Abs(mbb.Max[0]-want.Max[0]) > eps || math.Abs(mbb.Max[1]-want.Max[1]) > eps {
+		t.Errorf("MBB = %v, want %v", mbb, want)
+	}
+}
+
+func TestParse_FillRule(t *testing.T) {
+	// Two nested squares with the same orientation.
+	const d = "M0,0 h10 v10 h-10 z M2,2 h6 v6 h-6 z"
+	tests := []struct {
+		rule      string
+		wantHoles int
+	}{
+		{rule: "evenodd", wantHoles: 1},
+		{rule: "nonzero", wantHoles: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			doc := `<svg><path fill-rule="` + tt.rule + `" d="` + d + `"/></svg>`
+			primitives, err := Parse(strings.NewReader(doc), gerber.Pt{}, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(primitives) != 1 {
+				t.Fatalf("got %v primitives, want 1", len(primitives))
+			}
+			p := primitives[0].(*gerber.PolygonT)
+			if len(p.Holes) != tt.wantHoles {
+				t.Errorf("got %v holes, want %v", len(p.Holes), tt.wantHoles)
+			}
+			if got, want := p.Contains(gerber.Pt{5, 5}), tt.wantHoles == 0; got != want {
+				t.Errorf("Contains(center) = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParse_Stroke(t *testing.T) {
+	const doc = `<svg>
+  <circle cx="50" cy="50" r="50" fill="none" stroke="black" stroke-width="4"/>
+</svg>`
+	primitives, err := Parse(strings.NewReader(doc), gerber.Pt{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(primitives) != 1 {
+		t.Fatalf("got %v primitives, want 1", len(primitives))
+	}
+	tr, ok := primitives[0].(*gerber.TraceT)
+	if !ok {
+		t.Fatalf("primitives[0] = %T, want *gerber.TraceT", primitives[0])
+	}
+	if math.Abs(tr.Thickness-0.4) > eps {
+		t.Errorf("Thickness = %v, want 0.4", tr.Thickness)
+	}
+	if first, last := tr.Points[0], tr.Points[len(tr.Points)-1]; first != last {
+		t.Errorf("closed circle trace ends at %v, want %v", last, first)
+	}
+	center := gerber.Pt{5, 5}
+	for _, pt := range tr.Points {
+		if r := math.Hypot(pt[0]-center[0], pt[1]-center[1]); math.Abs(r-5) > gerber.DefaultTolerance {
+			t.Fatalf("point %v at radius %v, want 5", pt, r)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, doc := range []string{
+		`<svg><path d="M0 0 L"/></svg>`,
+		`<svg><rect width="1" height="1" transform="warp(2)"/></svg>`,
+		`<svg><line x1="1" y1="0" x2="1" y2="5" stroke="black"/></svg>`, // no width
+		`<svg><rect`,
+	} {
+		if _, err := Parse(strings.NewReader(doc), gerber.Pt{}, 10); err == nil {
+			t.Errorf("Parse(%q) = nil error, want error", doc)
+		}
+	}
+}
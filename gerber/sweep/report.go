@@ -0,0 +1,80 @@
+package sweep
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WriteJSON writes the results as an indented JSON array.
+func WriteJSON(w io.Writer, results []*Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// WriteCSV writes the results as CSV with a header row: the parameters
+// (sorted by name), then the name, board size, net, length, resistance,
+// inductance (in microhenries), number of violations and error of each
+// variant, with their units in the header. Variants
+// with several nets have a row for each net and those with none have a
+// row with an empty net.
+func WriteCSV(w io.Writer, results []*Result) error {
+	names := paramNames(results)
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, names...), "name", "width_mm", "height_mm", "net", "length_mm", "resistance_ohm", "inductance_uh", "violations", "error")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'g', 6, 64)
+	}
+	for _, r := range results {
+		nets := r.Nets
+		if len(nets) == 0 {
+			nets = []Net{{}}
+		}
+		for _, net := range nets {
+			var row []string
+			for _, name := range names {
+				v, ok := r.Params[name]
+				if !ok {
+					row = append(row, "")
+					continue
+				}
+				row = append(row, f(v))
+			}
+			row = append(row, r.Name, f(r.Width), f(r.Height))
+			if net.Name == "" {
+				row = append(row, "", "", "", "")
+			} else {
+				row = append(row, net.Name, f(net.Length), f(net.Resistance), f(1e6*net.Inductance))
+			}
+			row = append(row, strconv.Itoa(len(r.Violations)), r.Error)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// paramNames returns the names of the parameters of the results, sorted.
+func paramNames(results []*Result) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, r := range results {
+		for name := range r.Params {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,277 @@
+// Package sweep runs a design over a grid of parameters, such as the
+// number of turns, trace width and gap of a coil, and reports on each
+// of its variants: the size of the board, the length, resistance and
+// inductance of each net and the violations of the design rules.
+//
+// All dimensions are in millimeters.
+package sweep
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/drc"
+	"github.com/gmlewis/go-gerber/gerber/netlist"
+)
+
+// Param is a parameter to sweep over.
+type Param struct {
+	Name   string
+	Values []float64
+}
+
+// Range returns a parameter running from start to stop (inclusive)
+// in steps of step.
+func Range(name string, start, stop, step float64) Param {
+	p := Param{Name: name}
+	if step <= 0 || stop < start {
+		p.Values = []float64{start}
+		return p
+	}
+	n := int(math.Floor((stop-start)/step+1e-9)) + 1
+	for i := 0; i < n; i++ {
+		p.Values = append(p.Values, start+float64(i)*step)
+	}
+	return p
+}
+
+// ParseParam parses the values of a parameter, such as from a
+// command-line flag: either a single value, a comma-separated list
+// of values, or a range as "start:stop:step" (or "start:stop" in
+// steps of one).
+func ParseParam(name, s string) (Param, error) {
+	if parts := strings.Split(s, ":"); len(parts) > 1 {
+		if len(parts) > 3 {
+			return Param{}, fmt.Errorf("%v: bad range %q", name, s)
+		}
+		v := []float64{0, 0, 1}
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return Param{}, fmt.Errorf("%v: bad range %q: %v", name, s, err)
+			}
+			v[i] = f
+		}
+		if v[2] <= 0 || v[1] < v[0] {
+			return Param{}, fmt.Errorf("%v: bad range %q", name, s)
+		}
+		return Range(name, v[0], v[1], v[2]), nil
+	}
+
+	p := Param{Name: name}
+	for _, part := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return Param{}, fmt.Errorf("%v: bad value %q: %v", name, part, err)
+		}
+		p.Values = append(p.Values, f)
+	}
+	return p, nil
+}
+
+// Params are the values of the parameters of one variant of a design.
+type Params map[string]float64
+
+// Design returns the variant of a design with the parameters.
+type Design func(p Params) (*gerber.Gerber, error)
+
+// Config represents the configuration of a sweep.
+type Config struct {
+	// Params are the parameters to sweep over. Every combination
+	// of their values is a variant of the design.
+	Params []Param
+	// Rules are the design rules to check each variant against.
+	// Nil means that no rules are checked.
+	Rules *drc.Rules
+	// Copper describes the copper for the electrical analysis
+	// (see netlist.Copper).
+	Copper *netlist.Copper
+	// Workers is the number of variants to run at once
+	// (default runtime.NumCPU()).
+	Workers int
+	// ZipDir is the directory to write the ZIP file of each variant
+	// to, named by its filename prefix. Empty means none are written.
+	// A variant whose ZIP file would have the same name as that of
+	// another has its Error set instead.
+	ZipDir string
+}
+
+// Net is the electrical analysis of one net of a variant.
+type Net struct {
+	Name string `json:"name"`
+	// Length is the length of the traces between its terminals.
+	Length float64 `json:"length"`
+	// Resistance is the DC resistance in ohms between its terminals.
+	Resistance float64 `json:"resistance"`
+	// Inductance is the self inductance in henries between its terminals.
+	Inductance float64 `json:"inductance"`
+}
+
+// Result is the report on one variant of a design.
+type Result struct {
+	Params Params `json:"params"`
+	// Name is the filename prefix of the variant.
+	Name string `json:"name"`
+	// Width and Height are the size of the board.
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+	// Nets are the nets of the variant (see netlist.Extract).
+	Nets []Net `json:"nets"`
+	// Violations are the violations of the design rules.
+	Violations []string `json:"violations"`
+	// Zip is the path of the ZIP file written, if any.
+	Zip string `json:"zip,omitempty"`
+	// Error is the error (if any) that stopped the variant
+	// from being built or written.
+	Error string `json:"error,omitempty"`
+}
+
+// grid returns every combination of the values of the parameters,
+// varying the last parameter the fastest.
+func grid(params []Param) []Params {
+	result := []Params{{}}
+	for _, p := range params {
+		var next []Params
+		for _, base := range result {
+			for _, v := range p.Values {
+				ps := Params{p.Name: v}
+				for k, bv := range base {
+					ps[k] = bv
+				}
+				next = append(next, ps)
+			}
+		}
+		result = next
+	}
+	return result
+}
+
+// Run runs the design over every combination of the parameters, in
+// parallel, and returns the results in order, varying the last
+// parameter the fastest. The variants that fail have their Error set.
+func Run(design Design, cfg *Config) ([]*Result, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	names := map[string]bool{}
+	for _, p := range cfg.Params {
+		if p.Name == "" || names[p.Name] {
+			return nil, fmt.Errorf("bad or repeated parameter name %q", p.Name)
+		}
+		if len(p.Values) == 0 {
+			return nil, fmt.Errorf("%v: no values", p.Name)
+		}
+		names[p.Name] = true
+	}
+	if cfg.ZipDir != "" {
+		if err := os.MkdirAll(cfg.ZipDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	variants := grid(cfg.Params)
+	results := make([]*Result, len(variants))
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	zips := &zipNames{used: map[string]bool{}}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = run(design, variants[j], cfg, zips)
+			}
+		}()
+	}
+	for j := range variants {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+	return results, nil
+}
+
+// zipNames are the names of the ZIP files claimed by the variants.
+type zipNames struct {
+	mu   sync.Mutex
+	used map[string]bool
+}
+
+// claim reports whether the name was not already claimed, claiming it.
+func (z *zipNames) claim(name string) bool {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.used[name] {
+		return false
+	}
+	z.used[name] = true
+	return true
+}
+
+// run builds and reports on one variant.
+func run(design Design, params Params, cfg *Config, zips *zipNames) *Result {
+	r := &Result{Params: params}
+	g, err := design(params)
+	if err == nil && g == nil {
+		err = errors.New("no design")
+	}
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	r.Name = g.FilenamePrefix
+	mbb := g.MBB()
+	r.Width, r.Height = mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1]
+
+	nl := netlist.Extract(g, nil)
+	for _, report := range nl.Analyze(cfg.Copper) {
+		r.Nets = append(r.Nets, Net{
+			Name:       report.Net.Name,
+			Length:     report.Length,
+			Resistance: report.Resistance,
+			Inductance: report.Inductance,
+		})
+	}
+	if cfg.Rules != nil {
+		for _, v := range drc.Check(g, cfg.Rules, nl.Map()) {
+			r.Violations = append(r.Violations, v.String())
+		}
+	}
+
+	if cfg.ZipDir != "" {
+		zip := filepath.Join(cfg.ZipDir, filepath.Base(g.FilenamePrefix)+".zip")
+		if !zips.claim(zip) {
+			r.Error = fmt.Sprintf("%v is also the ZIP file of another variant", zip)
+			return r
+		}
+		r.Zip = zip
+		if err := writeZip(g, r.Zip); err != nil {
+			r.Error = err.Error()
+		}
+	}
+	return r
+}
+
+func writeZip(g *gerber.Gerber, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := g.WriteZip(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,196 @@
+package sweep
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gmlewis/go-gerber/gerber"
+	"github.com/gmlewis/go-gerber/gerber/drc"
+)
+
+// trace is a design of a trace between two pads.
+func trace(p Params) (*gerber.Gerber, error) {
+	if p["length"] <= 0 {
+		return nil, errors.New("no length")
+	}
+	g := gerber.New(fmt.Sprintf("trace-l%v-w%v", p["length"], p["width"]))
+	top := g.TopCopper()
+	top.Add(
+		gerber.Circle(gerber.Pt{0, 0}, 1),
+		gerber.Circle(gerber.Pt{p["length"], 0}, 1),
+		gerber.Line(0, 0, p["length"], 0, gerber.CircleShape, p["width"]),
+	)
+	return g, nil
+}
+
+func TestParseParam(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []float64
+	}{
+		{s: "5", want: []float64{5}},
+		{s: "0.1, 0.15,0.2", want: []float64{0.1, 0.15, 0.2}},
+		{s: "10:30:10", want: []float64{10, 20, 30}},
+		{s: "1:3", want: []float64{1, 2, 3}},
+		{s: "0.1:0.3:0.05", want: []float64{0.1, 0.15, 0.2, 0.25, 0.3}},
+	}
+	for _, tt := range tests {
+		p, err := ParseParam("n", tt.s)
+		if err != nil {
+			t.Errorf("ParseParam(%q) = %v", tt.s, err)
+			continue
+		}
+		if len(p.Values) != len(tt.want) {
+			t.Errorf("ParseParam(%q) = %v, want %v", tt.s, p.Values, tt.want)
+			continue
+		}
+		for i, v := range p.Values {
+			if math.Abs(v-tt.want[i]) > 1e-9 {
+				t.Errorf("ParseParam(%q) = %v, want %v", tt.s, p.Values, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, s := range []string{"", "x", "1:2:3:4", "3:1", "1:2:0", "1,,2"} {
+		if _, err := ParseParam("n", s); err == nil {
+			t.Errorf("ParseParam(%q) = nil error, want error", s)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	results, err := Run(trace, &Config{
+		Params:  []Param{{Name: "length", Values: []float64{0, 10, 20}}, {Name: "width", Values: []float64{0.1, 0.25}}},
+		Rules:   &drc.Rules{TraceWidth: 0.2},
+		Workers: 3,
+		ZipDir:  dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Params
+	for _, r := range results {
+		got = append(got, r.Params)
+	}
+	want := []Params{
+		{"length": 0, "width": 0.1},
+		{"length": 0, "width": 0.25},
+		{"length": 10, "width": 0.1},
+		{"length": 10, "width": 0.25},
+		{"length": 20, "width": 0.1},
+		{"length": 20, "width": 0.25},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Run params = %v, want %v", got, want)
+	}
+
+	for _, r := range results[:2] {
+		if r.Error != "no length" {
+			t.Errorf("Run(%v) error = %q, want %q", r.Params, r.Error, "no length")
+		}
+	}
+	for _, r := range results[2:] {
+		if r.Error != "" {
+			t.Fatalf("Run(%v) error = %v", r.Params, r.Error)
+		}
+		if want := r.Params["length"] + 1; math.Abs(r.Width-want) > 1e-6 || math.Abs(r.Height-1) > 1e-6 {
+			t.Errorf("Run(%v) size = %v x %v, want %v x 1", r.Params, r.Width, r.Height, want)
+		}
+		if len(r.Nets) != 1 || math.Abs(r.Nets[0].Length-r.Params["length"]) > 1e-6 || r.Nets[0].Resistance <= 0 || r.Nets[0].Inductance <= 0 {
+			t.Errorf("Run(%v) nets = %+v, want one of length %v", r.Params, r.Nets, r.Params["length"])
+		}
+		if narrow := r.Params["width"] < 0.2; narrow != (len(r.Violations) > 0) {
+			t.Errorf("Run(%v) violations = %v", r.Params, r.Violations)
+		}
+		zr, err := zip.OpenReader(r.Zip)
+		if err != nil {
+			t.Fatalf("Run(%v) zip: %v", r.Params, err)
+		}
+		if len(zr.File) != 1 || zr.File[0].Name != r.Name+".gtl" {
+			t.Errorf("Run(%v) zip has %v files, want %v.gtl", r.Params, len(zr.File), r.Name)
+		}
+		zr.Close()
+	}
+
+	// Variants with the same name do not share a ZIP file.
+	results, err = Run(trace, &Config{
+		Params:  []Param{{Name: "length", Values: []float64{5}}, {Name: "copy", Values: []float64{1, 2, 3}}},
+		Workers: 3,
+		ZipDir:  dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zips int
+	for _, r := range results {
+		switch {
+		case r.Error == "" && r.Zip != "":
+			zips++
+		case !strings.Contains(r.Error, "also the ZIP file"):
+			t.Errorf("Run(%v) error = %q, want a repeated ZIP file", r.Params, r.Error)
+		}
+	}
+	if zips != 1 {
+		t.Errorf("Run wrote %v ZIP files for one name, want 1", zips)
+	}
+
+	if _, err := Run(trace, &Config{Params: []Param{{Name: "length", Values: []float64{1}}, {Name: "length", Values: []float64{2}}}}); err == nil {
+		t.Error("Run(repeated parameter) = nil error, want error")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	results, err := Run(trace, &Config{Params: []Param{{Name: "width", Values: []float64{0.25}}, Range("length", 0, 10, 10)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows[0], []string{"length", "width", "name", "width_mm", "height_mm", "net", "length_mm", "resistance_ohm", "inductance_uh", "violations", "error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("header = %v, want %v", got, want)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("CSV has %v rows, want 3", len(rows))
+	}
+	if got := rows[1]; got[0] != "0" || got[5] != "" || got[10] != "no length" {
+		t.Errorf("row 1 = %v, want the error", got)
+	}
+	if got := rows[2]; got[0] != "10" || got[1] != "0.25" || got[2] != "trace-l10-w0.25" || got[5] != "N1" || got[6] != "10" || got[9] != "0" {
+		t.Errorf("row 2 = %v", got)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []*Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, results) {
+		t.Errorf("JSON round trip = %+v, want %+v", decoded, results)
+	}
+}
@@ -43,6 +43,10 @@ type TextT struct {
 	message  string
 	fontName string
 	pts      float64
+	local    MBB        // bounding box of the text before it is placed
+	angle    float64    // rotation in degrees about (x,y)
+	baseline baseline   // optional curved baseline
+	xf       *Transform // optional transformation
 	Render   *fonts.Render
 }
 
@@ -74,6 +78,16 @@ func Text(x, y, xScale float64, message, fontName string, pts float64, opts *Tex
 	}
 }
 
+// TextRotated returns a text primitive rotated counter-clockwise
+// by angle degrees about (x,y), such as a vertical label (angle 90).
+// The text is aligned by opts before it is rotated.
+// All dimensions are in millimeters.
+func TextRotated(x, y, xScale, angle float64, message, fontName string, pts float64, opts *TextOpts) *TextT {
+	t := Text(x, y, xScale, message, fontName, pts, opts)
+	t.angle = angle
+	return t
+}
+
 func (t *TextT) renderText() error {
 	if t.Render == nil {
 		yScale := t.pts * mmPerPt
@@ -82,10 +96,49 @@ func (t *TextT) renderText() error {
 		if t.Render, err = fonts.Text(t.x, t.y, xScale, yScale, t.message, t.fontName, t.opts); err != nil {
 			return err
 		}
+		t.local = t.Render.MBB
+		switch {
+		case t.baseline != nil:
+			t.placeOnBaseline()
+		case t.angle != 0:
+			t.transformRender(RotateAbout(Pt{t.x, t.y}, t.angle))
+		}
+		if t.xf != nil {
+			t.transformRender(*t.xf)
+		}
 	}
 	return nil
 }
 
+// transformRender applies the transformation to the rendered
+// polygons and recomputes their minimum bounding boxes.
+func (t *TextT) transformRender(xf Transform) {
+	for _, poly := range t.Render.Polygons {
+		poly.Pts = xf.transformPts(poly.Pts)
+	}
+	t.updateMBB()
+}
+
+// updateMBB recomputes the minimum bounding boxes
+// of the rendered polygons.
+func (t *TextT) updateMBB() {
+	for i, poly := range t.Render.Polygons {
+		for j, pt := range poly.Pts {
+			v := MBB{Min: pt, Max: pt}
+			if j == 0 {
+				poly.MBB = v
+			} else {
+				poly.MBB.Join(&v)
+			}
+		}
+		if i == 0 {
+			t.Render.MBB = poly.MBB
+		} else {
+			t.Render.MBB.Join(&poly.MBB)
+		}
+	}
+}
+
 func (t *TextT) MBB() MBB {
 	if err := t.renderText(); err != nil {
 		log.Fatal(err)
@@ -93,7 +146,8 @@ func (t *TextT) MBB() MBB {
 	return t.Render.MBB
 }
 
-// Width returns the width of the text in millimeters.
+// Width returns the width of the text in millimeters, measured
+// horizontally after any rotation or placement along a path.
 func (t *TextT) Width() float64 {
 	if err := t.renderText(); err != nil {
 		log.Fatal(err)
@@ -102,7 +156,8 @@ func (t *TextT) Width() float64 {
 	return width
 }
 
-// Height returns the height of the text in millimeters
+// Height returns the height of the text in millimeters, measured
+// vertically after any rotation or placement along a path.
 func (t *TextT) Height() float64 {
 	if err := t.renderText(); err != nil {
 		log.Fatal(err)
@@ -113,16 +168,25 @@ func (t *TextT) Height() float64 {
 
 // WriteGerber writes the primitive to the Gerber file.
 func (t *TextT) WriteGerber(w io.Writer, apertureIndex int) error {
+	return t.writePolygons(w, false)
+}
+
+// writePolygons writes the rendered glyph polygons as regions.
+// If invert is set, the polarities are swapped so that the glyphs
+// are cleared out of whatever lies beneath them (knockout text).
+// The dark polarity is always restored afterwards.
+func (t *TextT) writePolygons(w io.Writer, invert bool) error {
 	if err := t.renderText(); err != nil {
 		return err
 	}
 
 	currentDark := true
 	for _, poly := range t.Render.Polygons {
-		if poly.Dark && !currentDark {
+		dark := poly.Dark != invert
+		if dark && !currentDark {
 			io.WriteString(w, "%LPD*%\n")
 			currentDark = true
-		} else if !poly.Dark && currentDark {
+		} else if !dark && currentDark {
 			io.WriteString(w, "%LPC*%\n")
 			currentDark = false
 		}
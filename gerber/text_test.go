@@ -224,3 +224,85 @@ func TestText(t *testing.T) {
 		})
 	}
 }
+
+func TestTextRotated(t *testing.T) {
+	const eps = 1e-6
+	text := Text(10, 20, 1, "012", "freeserif", 72, &BottomLeft)
+	want := text.MBB()
+	rotated := TextRotated(10, 20, 1, 90, "012", "freeserif", 72, &BottomLeft)
+	got := rotated.MBB()
+	// Rotating by 90 degrees about (10,20) maps (x,y) to (10-(y-20), 20+(x-10)).
+	if math.Abs(got.Min[0]-(30-want.Max[1])) > eps || math.Abs(got.Max[0]-(30-want.Min[1])) > eps ||
+		math.Abs(got.Min[1]-(10+want.Min[0])) > eps || math.Abs(got.Max[1]-(10+want.Max[0])) > eps {
+		t.Errorf("MBB = %v, want rotation of %v", got, want)
+	}
+	if math.Abs(rotated.Width()-text.Height()) > eps || math.Abs(rotated.Height()-text.Width()) > eps {
+		t.Errorf("Width, Height = %v, %v, want %v, %v", rotated.Width(), rotated.Height(), text.Height(), text.Width())
+	}
+}
+
+func TestTextOnPath(t *testing.T) {
+	const eps = 1e-9
+	// Along a straight path, the text is identical to regular text.
+	text := Text(10, 20, 1, "012", "freeserif", 12, &BottomLeft)
+	onPath := TextOnPath([]Pt{{10, 20}, {15, 20}, {30, 20}}, 1, "012", "freeserif", 12, &BottomLeft)
+	if err := text.renderText(); err != nil {
+		t.Fatal(err)
+	}
+	if err := onPath.renderText(); err != nil {
+		t.Fatal(err)
+	}
+	for i, poly := range text.Render.Polygons {
+		for j, want := range poly.Pts {
+			got := onPath.Render.Polygons[i].Pts[j]
+			if math.Abs(got[0]-want[0]) > eps || math.Abs(got[1]-want[1]) > eps {
+				t.Fatalf("polygon %v point %v = %v, want %v", i, j, got, want)
+			}
+		}
+	}
+
+	// Centered on a vertical path, the text reads upwards.
+	vertical := TextOnPath([]Pt{{0, 0}, {0, 100}}, 1, "012", "freeserif", 12, &BottomCenter)
+	mbb := vertical.MBB()
+	if mbb.Max[0] > eps || math.Abs(0.5*(mbb.Min[1]+mbb.Max[1])-50) > 0.5*text.Width() {
+		t.Errorf("MBB = %v, want left of x=0 and centered about y=50", mbb)
+	}
+	if math.Abs(vertical.Width()-text.Height()) > eps {
+		t.Errorf("Width = %v, want %v", vertical.Width(), text.Height())
+	}
+}
+
+func TestTextOnArc(t *testing.T) {
+	const radius = 20
+	center := Pt{5, 5}
+	for _, cw := range []bool{true, false} {
+		// Align the text so that it lies outside the circle: above the
+		// baseline when running clockwise and below it otherwise.
+		opts := &BottomCenter
+		if !cw {
+			opts = &TopCenter
+		}
+		text := TextOnArc(center, radius, 90, cw, 1, "012", "freeserif", 12, opts)
+		if err := text.renderText(); err != nil {
+			t.Fatal(err)
+		}
+		var angles []float64
+		for _, poly := range text.Render.Polygons {
+			for _, pt := range poly.Pts {
+				if d := math.Hypot(pt[0]-center[0], pt[1]-center[1]); d < radius-1e-9 {
+					t.Fatalf("cw=%v: point %v is inside the circle (%v)", cw, pt, d)
+				}
+			}
+			if poly.Dark {
+				c := Pt{0.5*(poly.MBB.Min[0]+poly.MBB.Max[0]) - center[0], 0.5*(poly.MBB.Min[1]+poly.MBB.Max[1]) - center[1]}
+				angles = append(angles, math.Atan2(c[1], c[0]))
+			}
+		}
+		for i := 1; i < len(angles); i++ {
+			if (angles[i] < angles[i-1]) != cw {
+				t.Errorf("cw=%v: glyph angles %v are out of order", cw, angles)
+				break
+			}
+		}
+	}
+}
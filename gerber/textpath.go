@@ -0,0 +1,143 @@
+package gerber
+
+import (
+	"math"
+)
+
+// baseline maps a distance s along a text baseline to the point
+// at that distance and the unit tangent (reading direction) there.
+type baseline func(s float64) (pt, dir Pt)
+
+// TextOnPath returns a text primitive whose baseline follows the
+// polyline path. Each glyph is placed upright (perpendicular to the
+// path) at the point along the path beneath its center.
+//
+// The horizontal alignment of opts positions the text along the path:
+// XLeft starts it at the first point, XCenter centers it on the path's
+// length, and XRight ends it at the last point. The vertical alignment
+// offsets the text from the path as usual. Text overhanging the ends of
+// the path continues along their tangents.
+// All dimensions are in millimeters.
+func TextOnPath(path []Pt, xScale float64, message, fontName string, pts float64, opts *TextOpts) *TextT {
+	t := Text(0, 0, xScale, message, fontName, pts, opts)
+	t.baseline = polylineBaseline(path, alignOffset(opts))
+	return t
+}
+
+// TextOnArc returns a text primitive whose baseline follows the
+// circle of the given radius (in millimeters) about center. The text is
+// aligned (as by opts) to the point at angle degrees on the circle.
+//
+// If cw is true, the text runs clockwise with the tops of the glyphs
+// facing away from the center, so it reads upright across the top of
+// the circle. Otherwise, it runs counter-clockwise with the tops facing
+// the center, reading upright across the bottom.
+// All dimensions are in millimeters.
+func TextOnArc(center Pt, radius, angle float64, cw bool, xScale float64, message, fontName string, pts float64, opts *TextOpts) *TextT {
+	t := Text(0, 0, xScale, message, fontName, pts, opts)
+	a0 := math.Pi * angle / 180.0
+	t.baseline = func(s float64) (Pt, Pt) {
+		a := a0 + s/radius
+		if cw {
+			a = a0 - s/radius
+		}
+		sin, cos := math.Sincos(a)
+		pt := Pt{center[0] + radius*cos, center[1] + radius*sin}
+		if cw {
+			return pt, Pt{sin, -cos}
+		}
+		return pt, Pt{-sin, cos}
+	}
+	return t
+}
+
+// alignOffset returns the fraction of a path's length at which
+// text with the given options is anchored.
+func alignOffset(opts *TextOpts) float64 {
+	if opts == nil {
+		return 0
+	}
+	return -float64(opts.XAlign)
+}
+
+// polylineBaseline returns the baseline following the path,
+// with distance zero at fraction anchor of its length.
+func polylineBaseline(path []Pt, anchor float64) baseline {
+	type seg struct {
+		start, dir Pt
+		s, length  float64 // distance to start, and length
+	}
+	var segs []seg
+	var total float64
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		l := math.Hypot(b[0]-a[0], b[1]-a[1])
+		if l < 1e-12 {
+			continue
+		}
+		segs = append(segs, seg{start: a, dir: Pt{(b[0] - a[0]) / l, (b[1] - a[1]) / l}, s: total, length: l})
+		total += l
+	}
+	if len(segs) == 0 {
+		// A degenerate path: run horizontally from its first point.
+		var start Pt
+		if len(path) > 0 {
+			start = path[0]
+		}
+		segs = append(segs, seg{start: start, dir: Pt{1, 0}})
+	}
+
+	offset := anchor * total
+	return func(s float64) (Pt, Pt) {
+		s += offset
+		i := 0
+		for i < len(segs)-1 && s > segs[i].s+segs[i].length {
+			i++
+		}
+		sg := segs[i]
+		d := s - sg.s
+		return Pt{sg.start[0] + d*sg.dir[0], sg.start[1] + d*sg.dir[1]}, sg.dir
+	}
+}
+
+// placeOnBaseline moves each glyph of the (straight) rendered text
+// onto the baseline. The dark polygons are placed independently at the
+// point beneath their centers, and each clear polygon (a counter) moves
+// with the smallest dark polygon surrounding it.
+func (t *TextT) placeOnBaseline() {
+	polys := t.Render.Polygons
+	t.updateMBB()
+
+	// owner[i] is the dark polygon that polygon i moves with.
+	owner := make([]int, len(polys))
+	for i, poly := range polys {
+		owner[i] = i
+		if poly.Dark {
+			continue
+		}
+		c := Pt{0.5 * (poly.MBB.Min[0] + poly.MBB.Max[0]), 0.5 * (poly.MBB.Min[1] + poly.MBB.Max[1])}
+		bestArea := math.Inf(1)
+		for j, dark := range polys {
+			if !dark.Dark || !pointInContour(c, dark.Pts) {
+				continue
+			}
+			if area := math.Abs(signedArea(dark.Pts)); area < bestArea {
+				owner[i], bestArea = j, area
+			}
+		}
+	}
+
+	centers := make([]float64, len(polys))
+	for i, poly := range polys {
+		centers[i] = 0.5 * (poly.MBB.Min[0] + poly.MBB.Max[0])
+	}
+	for i, poly := range polys {
+		cx := centers[owner[i]]
+		origin, dir := t.baseline(cx)
+		for j, pt := range poly.Pts {
+			dx, dy := pt[0]-cx, pt[1]
+			poly.Pts[j] = Pt{origin[0] + dx*dir[0] - dy*dir[1], origin[1] + dx*dir[1] + dy*dir[0]}
+		}
+	}
+	t.updateMBB()
+}
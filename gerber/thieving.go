@@ -0,0 +1,229 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Thieving configures copper thieving: a pattern of dots or squares
+// that fills the empty areas of a copper layer so that it plates more
+// evenly and balances the copper of the other layers.
+// All dimensions are in millimeters.
+type Thieving struct {
+	// Shape is the shape of the elements of the pattern: CircleShape
+	// for dots (the default) or RectShape for squares.
+	Shape Shape
+	// Size is the diameter of the dots or the side of the squares
+	// (default 1).
+	Size float64
+	// Pitch is the distance between the centers of the elements
+	// (default twice the Size). It is the smallest pitch used when
+	// aiming for a Density.
+	Pitch float64
+	// Stagger offsets alternate rows of the pattern by half the pitch.
+	Stagger bool
+	// KeepAway is the smallest distance from the existing copper and
+	// the drilled holes to the pattern (default 1).
+	KeepAway float64
+	// Margin is the smallest distance from the board outline
+	// to the pattern (default KeepAway).
+	Margin float64
+	// Density is the percentage of the board that the copper of the
+	// layer should cover, pattern included. The pitch is increased as
+	// far as it can be while reaching it, and no pattern is added to
+	// layers that already reach it. Zero means the empty areas are
+	// filled at Pitch, as they are when it cannot be reached.
+	Density float64
+}
+
+func (t *Thieving) withDefaults() (Thieving, error) {
+	var v Thieving
+	if t != nil {
+		v = *t
+	}
+	if v.Shape == "" {
+		v.Shape = CircleShape
+	}
+	if v.Shape != CircleShape && v.Shape != RectShape {
+		return v, fmt.Errorf("unknown thieving shape %q", v.Shape)
+	}
+	if v.Size <= 0 {
+		v.Size = 1
+	}
+	if v.Pitch <= 0 {
+		v.Pitch = 2 * v.Size
+	}
+	if v.Pitch <= v.Size {
+		return v, fmt.Errorf("thieving pitch %v must exceed the size %v", v.Pitch, v.Size)
+	}
+	if v.KeepAway <= 0 {
+		v.KeepAway = 1
+	}
+	if v.Margin <= 0 {
+		v.Margin = v.KeepAway
+	}
+	return v, nil
+}
+
+// radius returns the radius of the circle enclosing an element.
+func (t Thieving) radius() float64 {
+	if t.Shape == RectShape {
+		return t.Size / math.Sqrt2
+	}
+	return 0.5 * t.Size
+}
+
+// element returns the element of the pattern at the point.
+func (t Thieving) element(pt Pt) Primitive {
+	if t.Shape == RectShape {
+		h := 0.5 * t.Size
+		return Polygon(pt, true, []Pt{{-h, -h}, {h, -h}, {h, h}, {-h, h}}, 0)
+	}
+	return Circle(pt, t.Size)
+}
+
+// area returns the area of an element.
+func (t Thieving) area() float64 {
+	if t.Shape == RectShape {
+		return t.Size * t.Size
+	}
+	return 0.25 * math.Pi * t.Size * t.Size
+}
+
+// Thieve balances the copper layers of the design
+// by thieving each of them in turn (see Layer.Thieve).
+// It returns the number of elements added to all of them.
+func (g *Gerber) Thieve(t *Thieving) (int, error) {
+	var total int
+	for _, layer := range g.Layers {
+		if !layer.Role.IsCopper() {
+			continue
+		}
+		n, err := layer.Thieve(t)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// Thieve fills the empty areas of the copper layer within the board
+// outline, away from its copper and from the drilled holes of the
+// design, with a pattern of dots or squares. It returns the number
+// of elements added to the layer.
+func (l *Layer) Thieve(t *Thieving) (int, error) {
+	cfg, err := t.withDefaults()
+	if err != nil {
+		return 0, err
+	}
+	if !l.Role.IsCopper() || l.g == nil {
+		return 0, fmt.Errorf("layer %v is not a copper layer of a design", l.Filename)
+	}
+	loops := l.g.boardLoops()
+	board := loopsArea(loops)
+	if board <= 0 {
+		return 0, errors.New("the design has no closed board outline")
+	}
+
+	keepOut := l.keepOut(loops, cfg)
+	mbb := keepOut.shapes[0].mbb
+	sites := func(pitch float64) []Pt {
+		var result []Pt
+		for row, y := 0, mbb.Min[1]+0.5*pitch; y < mbb.Max[1]; row, y = row+1, y+pitch {
+			x := mbb.Min[0] + 0.5*pitch
+			if cfg.Stagger && row%2 == 1 {
+				x += 0.5 * pitch
+			}
+			for ; x < mbb.Max[0]; x += pitch {
+				if pt := (Pt{x, y}); keepOut.top(pt) < 0 {
+					result = append(result, pt)
+				}
+			}
+		}
+		return result
+	}
+
+	best := sites(cfg.Pitch)
+	if cfg.Density > 0 {
+		need := (cfg.Density - l.stats(loops, board).Coverage) / 100 * board
+		if need <= 0 {
+			return 0, nil
+		}
+		// Find the largest pitch at which the pattern reaches the density.
+		lo, hi := cfg.Pitch, math.Max(mbb.Max[0]-mbb.Min[0], mbb.Max[1]-mbb.Min[1])
+		for i := 0; i < 30 && float64(len(best))*cfg.area() > need; i++ {
+			mid := 0.5 * (lo + hi)
+			if s := sites(mid); float64(len(s))*cfg.area() >= need {
+				best, lo = s, mid
+			} else {
+				hi = mid
+			}
+		}
+	}
+
+	for _, pt := range best {
+		l.Add(cfg.element(pt))
+	}
+	return len(best), nil
+}
+
+// keepOut returns the places that the centers of the elements of the
+// pattern must keep out of: beyond the board outline (its first shape,
+// whose bounding box surrounds the board), near its edges, and near the
+// copper of the layer and the holes of the design.
+func (l *Layer) keepOut(loops [][]Pt, t Thieving) *flatImage {
+	im := &flatImage{}
+	var mbb MBB
+	for i, loop := range loops {
+		for j, pt := range loop {
+			if i == 0 && j == 0 {
+				mbb = MBB{Min: pt, Max: pt}
+			}
+			mbb.Join(&MBB{Min: pt, Max: pt})
+		}
+	}
+	beyond := [][]Pt{{
+		{mbb.Min[0] - 1, mbb.Min[1] - 1},
+		{mbb.Max[0] + 1, mbb.Min[1] - 1},
+		{mbb.Max[0] + 1, mbb.Max[1] + 1},
+		{mbb.Min[0] - 1, mbb.Max[1] + 1},
+	}}
+	im.add(true, append(beyond, loops...)...)
+
+	// Curves are flattened within their true outlines, so allow for that.
+	r := t.radius() + DefaultTolerance
+	for _, loop := range loops {
+		for i, pt := range loop {
+			im.add(true, capsule(pt, loop[(i+1)%len(loop)], r+t.Margin))
+		}
+	}
+	for _, layer := range l.g.Layers {
+		if layer != l && layer.Role != DrillRole {
+			continue
+		}
+		for _, p := range layer.Primitives {
+			for _, f := range Features(p) {
+				if f.IsRegion() {
+					im.add(true, append([][]Pt{f.Region}, f.Holes...)...)
+					for _, c := range append([][]Pt{f.Region}, f.Holes...) {
+						for i, pt := range c {
+							im.add(true, capsule(pt, c[(i+1)%len(c)], r+t.KeepAway))
+						}
+					}
+					continue
+				}
+				w := 0.5*f.Width + r + t.KeepAway
+				if len(f.Path) == 1 {
+					im.add(true, capsule(f.Path[0], f.Path[0], w))
+				}
+				for i := 1; i < len(f.Path); i++ {
+					im.add(true, capsule(f.Path[i-1], f.Path[i], w))
+				}
+			}
+		}
+	}
+	im.grid = newShapeGrid(im.shapes)
+	return im
+}
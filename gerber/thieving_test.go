@@ -0,0 +1,134 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// thievingBoard returns a 20x20mm board with a pad and a via at its
+// center.
+func thievingBoard() (*Gerber, *Layer) {
+	g := New("test")
+	top := g.TopCopper()
+	drill := g.Drill()
+	outline := g.Outline()
+	outline.Add(Polygon(Pt{}, false, square(0, 0, 20), 0.1))
+	top.Add(Circle(Pt{10, 10}, 4))
+	drill.Add(Circle(Pt{15, 15}, 0.8))
+	return g, top
+}
+
+func TestLayer_Thieve(t *testing.T) {
+	for _, shape := range []Shape{CircleShape, RectShape} {
+		t.Run(string(shape), func(t *testing.T) {
+			_, top := thievingBoard()
+			pad := top.Primitives[0]
+			n, err := top.Thieve(&Thieving{Shape: shape, Size: 0.5, Pitch: 1, KeepAway: 0.5, Stagger: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n == 0 || len(top.Primitives) != n+1 {
+				t.Fatalf("Thieve = %v with %v primitives, want some added", n, len(top.Primitives))
+			}
+
+			padFeature := Features(pad)[0]
+			via := Features(Circle(Pt{15, 15}, 0.8))[0]
+			for _, p := range top.Primitives[1:] {
+				f := Features(p)[0]
+				if shape == RectShape != f.IsRegion() {
+					t.Fatalf("element %+v, want shape %v", f, shape)
+				}
+				if d, _, _ := f.Distance(padFeature); d < 0.5 {
+					t.Errorf("element at %v is %v from the pad, want at least 0.5", f.MBB(), d)
+				}
+				if d, _, _ := f.Distance(via); d < 0.5 {
+					t.Errorf("element at %v is %v from the via, want at least 0.5", f.MBB(), d)
+				}
+				if mbb := f.MBB(); mbb.Min[0] < 0.5 || mbb.Min[1] < 0.5 || mbb.Max[0] > 19.5 || mbb.Max[1] > 19.5 {
+					t.Errorf("element at %v is within 0.5 of the outline", mbb)
+				}
+			}
+
+			// Squares are written as regions closed back to their start.
+			var buf bytes.Buffer
+			if err := top.WriteGerber(&buf); err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			if shape == RectShape {
+				want = n
+			}
+			if got := checkRegions(t, buf.String()); got != want {
+				t.Errorf("WriteGerber wrote %v regions, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLayer_Thieve_Density(t *testing.T) {
+	g, top := thievingBoard()
+	before := top.Stats().Coverage
+	n, err := top.Thieve(&Thieving{Pitch: 1.25, Density: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := top.Stats().Coverage
+	if n == 0 || got < 30 || got > 33 {
+		t.Errorf("Thieve = %v, coverage %v -> %v, want about 30", n, before, got)
+	}
+
+	// A layer that already reaches the density is left as it is.
+	if n, err := top.Thieve(&Thieving{Density: 25}); n != 0 || err != nil {
+		t.Errorf("Thieve = %v, %v, want 0, nil", n, err)
+	}
+
+	// A density that cannot be reached fills at the pitch.
+	bottom := g.BottomCopper()
+	full, err := bottom.Thieve(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Layers = g.Layers[:len(g.Layers)-1]
+	bottom = g.BottomCopper()
+	if n, err := bottom.Thieve(&Thieving{Density: 90}); n != full || err != nil {
+		t.Errorf("Thieve = %v, %v, want %v, nil", n, err, full)
+	}
+}
+
+func TestGerber_Thieve(t *testing.T) {
+	g, top := thievingBoard()
+	bottom := g.BottomCopper()
+	if _, err := g.Thieve(&Thieving{Pitch: 1.25, Density: 20}); err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range []*Layer{top, bottom} {
+		if got := l.Stats().Coverage; math.Abs(got-20) > 3 {
+			t.Errorf("%v coverage = %v, want about 20", l.Filename, got)
+		}
+	}
+	if got := g.Layers[1].Primitives; len(got) != 1 {
+		t.Errorf("drill layer has %v primitives, want 1", len(got))
+	}
+}
+
+func TestLayer_Thieve_Errors(t *testing.T) {
+	g, top := thievingBoard()
+	tests := []struct {
+		name  string
+		layer *Layer
+		t     *Thieving
+	}{
+		{name: "pitch within size", layer: top, t: &Thieving{Size: 1, Pitch: 1}},
+		{name: "unknown shape", layer: top, t: &Thieving{Shape: "X"}},
+		{name: "not copper", layer: g.Layers[1]},
+		{name: "no outline", layer: New("test").TopCopper()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n, err := tt.layer.Thieve(tt.t); err == nil {
+				t.Errorf("Thieve = %v, want an error", n)
+			}
+		})
+	}
+}
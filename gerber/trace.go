@@ -0,0 +1,266 @@
+package gerber
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// TraceT represents a polyline trace drawn with a single aperture
+// as one continuous chain of draws, and satisfies the Primitive interface.
+type TraceT struct {
+	Points    []Pt
+	Shape     Shape
+	Thickness float64
+	// FilletRadius rounds each corner with a circular arc of this
+	// (centerline) radius. The radius is reduced where the adjacent
+	// segments are too short to fit it. Zero means sharp corners.
+	// Fillets require a CircleShape aperture.
+	FilletRadius float64
+
+	mbb *MBB // cached minimum bounding box
+}
+
+// Trace returns a polyline trace primitive.
+// All dimensions are in millimeters.
+func Trace(points []Pt, shape Shape, thickness float64) *TraceT {
+	return &TraceT{
+		Points:    points,
+		Shape:     shape,
+		Thickness: thickness,
+	}
+}
+
+// traceSeg is one draw of a trace: a straight line or (filleted)
+// circular arc from the end of the previous draw to end.
+type traceSeg struct {
+	end    Pt
+	arc    bool
+	center Pt
+	ccw    bool
+}
+
+// segs returns the draws making up the trace after its first point.
+func (t *TraceT) segs() []traceSeg {
+	n := len(t.Points)
+	var segs []traceSeg
+	// used is the length of the current segment already
+	// consumed by the fillet at its start.
+	var used float64
+	for i := 1; i < n; i++ {
+		p := t.Points[i]
+		if t.FilletRadius <= 0 || i == n-1 {
+			segs = append(segs, traceSeg{end: p})
+			continue
+		}
+
+		a, b := t.Points[i-1], t.Points[i+1]
+		l1, l2 := math.Hypot(p[0]-a[0], p[1]-a[1]), math.Hypot(b[0]-p[0], b[1]-p[1])
+		if l1 < 1e-12 || l2 < 1e-12 {
+			segs = append(segs, traceSeg{end: p})
+			used = 0
+			continue
+		}
+		u := Pt{(p[0] - a[0]) / l1, (p[1] - a[1]) / l1}
+		v := Pt{(b[0] - p[0]) / l2, (b[1] - p[1]) / l2}
+		cross := u[0]*v[1] - u[1]*v[0]
+		phi := math.Atan2(math.Abs(cross), u[0]*v[0]+u[1]*v[1])
+		if math.Abs(cross) < 1e-12 || phi >= math.Pi-1e-9 {
+			segs = append(segs, traceSeg{end: p})
+			used = 0
+			continue
+		}
+
+		// Distance from the corner to each tangent point, limited so that
+		// the fillets never overlap: an interior segment is shared with the
+		// fillet at its other end.
+		tan := math.Tan(0.5 * phi)
+		d := t.FilletRadius * tan
+		max1, max2 := l1-used, 0.5*l2
+		if i+1 == n-1 {
+			max2 = l2
+		}
+		if d > max1 {
+			d = max1
+		}
+		if d > max2 {
+			d = max2
+		}
+		if d < 1e-9 {
+			segs = append(segs, traceSeg{end: p})
+			used = 0
+			continue
+		}
+		r := d / tan
+		t1 := Pt{p[0] - d*u[0], p[1] - d*u[1]}
+		t2 := Pt{p[0] + d*v[0], p[1] + d*v[1]}
+		normal := Pt{-u[1], u[0]} // to the left of the incoming segment
+		if cross < 0 {
+			normal = Pt{u[1], -u[0]}
+		}
+		// Skip the straight draw if the previous fillet already ends at t1.
+		prev := t.Points[0]
+		if len(segs) > 0 {
+			prev = segs[len(segs)-1].end
+		}
+		if math.Hypot(t1[0]-prev[0], t1[1]-prev[1]) > 1e-9 {
+			segs = append(segs, traceSeg{end: t1})
+		}
+		segs = append(segs, traceSeg{end: t2, arc: true, center: Pt{t1[0] + r*normal[0], t1[1] + r*normal[1]}, ccw: cross > 0})
+		used = d
+	}
+	return segs
+}
+
+// Flatten returns the centerline of the trace with any fillets
+// flattened into straight segments within DefaultTolerance.
+func (t *TraceT) Flatten() []Pt {
+	if len(t.Points) == 0 {
+		return nil
+	}
+	pts := []Pt{t.Points[0]}
+	for _, s := range t.segs() {
+		if s.arc {
+			pts = append(pts, arcPoints(pts[len(pts)-1], s, DefaultTolerance)...)
+			continue
+		}
+		pts = append(pts, s.end)
+	}
+	return pts
+}
+
+// arcSweep returns the radius, start angle and (signed) sweep
+// of the arc from start to s.end.
+func arcSweep(start Pt, s traceSeg) (r, a1, sweep float64) {
+	r = math.Hypot(start[0]-s.center[0], start[1]-s.center[1])
+	a1 = math.Atan2(start[1]-s.center[1], start[0]-s.center[0])
+	a2 := math.Atan2(s.end[1]-s.center[1], s.end[0]-s.center[0])
+	sweep = a2 - a1
+	if s.ccw && sweep < 0 {
+		sweep += 2 * math.Pi
+	}
+	if !s.ccw && sweep > 0 {
+		sweep -= 2 * math.Pi
+	}
+	return r, a1, sweep
+}
+
+// arcPoints returns the points (excluding start) along the arc
+// from start to s.end.
+func arcPoints(start Pt, s traceSeg, tol float64) []Pt {
+	r, a1, sweep := arcSweep(start, s)
+	n := int(math.Ceil(math.Abs(sweep) / segmentAngle(r, tol)))
+	if n < 1 {
+		n = 1
+	}
+	var pts []Pt
+	for i := 1; i < n; i++ {
+		a := a1 + sweep*float64(i)/float64(n)
+		pts = append(pts, Pt{s.center[0] + r*math.Cos(a), s.center[1] + r*math.Sin(a)})
+	}
+	return append(pts, s.end)
+}
+
+// WriteGerber writes the primitive to the Gerber file.
+func (t *TraceT) WriteGerber(w io.Writer, apertureIndex int) error {
+	if len(t.Points) < 2 {
+		return errors.New("trace must have at least two points")
+	}
+	segs := t.segs()
+	var arcs bool
+	for _, s := range segs {
+		arcs = arcs || s.arc
+	}
+	if arcs && t.Shape != CircleShape {
+		return fmt.Errorf("filleted trace requires a circular aperture, got %q", t.Shape)
+	}
+
+	fmt.Fprintf(w, "G54D%d*\n", apertureIndex)
+	if arcs {
+		io.WriteString(w, "G75*\n")
+	}
+	start := t.Points[0]
+	fmt.Fprintf(w, "X%06dY%06dD02*\n", int(0.5+sf*start[0]), int(0.5+sf*start[1]))
+	var inArc bool
+	for _, s := range segs {
+		switch {
+		case s.arc:
+			g := "G02"
+			if s.ccw {
+				g = "G03"
+			}
+			fmt.Fprintf(w, "%vX%06dY%06dI%06dJ%06dD01*\n", g,
+				int(0.5+sf*s.end[0]), int(0.5+sf*s.end[1]),
+				int(0.5+sf*(s.center[0]-start[0])), int(0.5+sf*(s.center[1]-start[1])))
+			inArc = true
+		case inArc:
+			fmt.Fprintf(w, "G01X%06dY%06dD01*\n", int(0.5+sf*s.end[0]), int(0.5+sf*s.end[1]))
+			inArc = false
+		default:
+			fmt.Fprintf(w, "X%06dY%06dD01*\n", int(0.5+sf*s.end[0]), int(0.5+sf*s.end[1]))
+		}
+		start = s.end
+	}
+	if inArc {
+		io.WriteString(w, "G01*\n")
+	}
+	return nil
+}
+
+// Aperture returns the primitive's desired aperture.
+func (t *TraceT) Aperture() *Aperture {
+	return &Aperture{
+		Shape: t.Shape,
+		Size:  t.Thickness,
+	}
+}
+
+// MBB returns the minimum bounding box of the trace,
+// including its fillets and thickness.
+func (t *TraceT) MBB() MBB {
+	if t.mbb != nil {
+		return *t.mbb
+	}
+	if len(t.Points) == 0 {
+		t.mbb = &MBB{}
+		return *t.mbb
+	}
+	start := t.Points[0]
+	t.mbb = &MBB{Min: start, Max: start}
+	add := func(pt Pt) {
+		t.mbb.Join(&MBB{Min: pt, Max: pt})
+	}
+	for _, s := range t.segs() {
+		if s.arc {
+			// Include any of the circle's axis extremes within the sweep.
+			r, a1, sweep := arcSweep(start, s)
+			lo, hi := a1, a1+sweep
+			if sweep < 0 {
+				lo, hi = hi, lo
+			}
+			for k := math.Ceil(2 * lo / math.Pi); k*0.5*math.Pi <= hi; k++ {
+				a := k * 0.5 * math.Pi
+				add(Pt{s.center[0] + r*math.Cos(a), s.center[1] + r*math.Sin(a)})
+			}
+		}
+		add(s.end)
+		start = s.end
+	}
+	t.mbb.Min[0] -= 0.5 * t.Thickness
+	t.mbb.Min[1] -= 0.5 * t.Thickness
+	t.mbb.Max[0] += 0.5 * t.Thickness
+	t.mbb.Max[1] += 0.5 * t.Thickness
+	return *t.mbb
+}
+
+// Transform returns a transformed copy of the trace.
+// The fillets are recomputed from the transformed points.
+func (t *TraceT) Transform(xf Transform) Primitive {
+	n := *t
+	n.Points = xf.transformPts(t.Points)
+	n.Thickness *= xf.ScaleFactor()
+	n.FilletRadius *= xf.ScaleFactor()
+	n.mbb = nil
+	return &n
+}
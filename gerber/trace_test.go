@@ -0,0 +1,185 @@
+package gerber
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestTraceT_Primitive(t *testing.T) {
+	var p Primitive = &TraceT{}
+	if p == nil {
+		// In actuality, this test won't compile if it isn't a Primitive.
+		t.Errorf("TraceT does not implement the Primitive interface")
+	}
+}
+
+func TestTraceT_WriteGerber(t *testing.T) {
+	lShape := []Pt{{0, 0}, {10, 0}, {10, 10}}
+	tests := []struct {
+		name   string
+		t      *TraceT
+		fillet float64
+		want   string
+	}{
+		{
+			name: "sharp corners",
+			t:    Trace(lShape, RectShape, 0.2),
+			want: `G54D12*
+X000000Y000000D02*
+X10000000Y000000D01*
+X10000000Y10000000D01*
+`,
+		},
+		{
+			name:   "left fillet",
+			t:      Trace(lShape, CircleShape, 0.2),
+			fillet: 2,
+			want: `G54D12*
+G75*
+X000000Y000000D02*
+X8000000Y000000D01*
+G03X10000000Y2000000I000000J2000000D01*
+G01X10000000Y10000000D01*
+`,
+		},
+		{
+			name:   "right fillet at end",
+			t:      Trace([]Pt{{0, 0}, {0, 10}, {10, 10}, {10, 0}}, CircleShape, 0.2),
+			fillet: 20, // clamped to half of the middle segment
+			want: `G54D12*
+G75*
+X000000Y000000D02*
+X000000Y5000000D01*
+G02X5000000Y10000000I5000000J000000D01*
+G02X10000000Y5000000I000000J-4999999D01*
+G01X10000000Y000000D01*
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.t.FilletRadius = tt.fillet
+			var buf bytes.Buffer
+			if err := tt.t.WriteGerber(&buf, 12); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("WriteGerber =\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraceT_WriteGerber_Errors(t *testing.T) {
+	rect := Trace([]Pt{{0, 0}, {10, 0}, {10, 10}}, RectShape, 0.2)
+	rect.FilletRadius = 1
+	for _, tr := range []*TraceT{rect, Trace([]Pt{{0, 0}}, CircleShape, 0.2)} {
+		var buf bytes.Buffer
+		if err := tr.WriteGerber(&buf, 12); err == nil {
+			t.Errorf("WriteGerber(%v) = nil error, want error", tr.Points)
+		}
+	}
+}
+
+func TestTraceT_MBB(t *testing.T) {
+	tests := []struct {
+		name   string
+		pts    []Pt
+		fillet float64
+		want   MBB
+	}{
+		{
+			name: "sharp",
+			pts:  []Pt{{0, 0}, {10, 0}, {10, 10}},
+			want: MBB{Min: Pt{-0.1, -0.1}, Max: Pt{10.1, 10.1}},
+		},
+		{
+			name:   "filleted",
+			pts:    []Pt{{0, 0}, {10, 0}, {10, 10}},
+			fillet: 2,
+			want:   MBB{Min: Pt{-0.1, -0.1}, Max: Pt{10.1, 10.1}},
+		},
+		{
+			name:   "hairpin",
+			pts:    []Pt{{0, 0}, {10, 0}, {10, 4}, {0, 4}},
+			fillet: 2,
+			want:   MBB{Min: Pt{-0.1, -0.1}, Max: Pt{10.1, 4.1}},
+		},
+		{
+			name:   "acute",
+			pts:    []Pt{{0, 0}, {10, 0}, {0, 2}},
+			fillet: 1,
+		},
+		{
+			name:   "zig-zag",
+			pts:    []Pt{{0, 0}, {3, 5}, {6, -1}, {9, 4}, {-2, 3}},
+			fillet: 1.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := Trace(tt.pts, CircleShape, 0.2)
+			tr.FilletRadius = tt.fillet
+			got := tr.MBB()
+
+			want := tt.want
+			if want == (MBB{}) {
+				// Compare with the flattened centerline.
+				pts := tr.Flatten()
+				want = MBB{Min: pts[0], Max: pts[0]}
+				for _, pt := range pts {
+					want.Join(&MBB{Min: pt, Max: pt})
+				}
+				want.Min[0] -= 0.1
+				want.Min[1] -= 0.1
+				want.Max[0] += 0.1
+				want.Max[1] += 0.1
+			}
+			// The flattened chords lie up to DefaultTolerance inside the arcs.
+			const eps = 1e-9
+			for i := 0; i < 2; i++ {
+				if got.Min[i] > want.Min[i]+eps || got.Max[i] < want.Max[i]-eps ||
+					got.Min[i] < want.Min[i]-DefaultTolerance-eps || got.Max[i] > want.Max[i]+DefaultTolerance+eps {
+					t.Errorf("MBB = %v, want %v", got, want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestTraceT_Flatten(t *testing.T) {
+	tr := Trace([]Pt{{0, 0}, {10, 0}, {10, 10}, {0, 10}}, CircleShape, 0.2)
+	tr.FilletRadius = 3
+	pts := tr.Flatten()
+	if pts[0] != tr.Points[0] || pts[len(pts)-1] != tr.Points[3] {
+		t.Fatalf("Flatten end points = %v, %v", pts[0], pts[len(pts)-1])
+	}
+	// The fillets must be tangent to the segments, so the direction
+	// never changes abruptly.
+	for i := 2; i < len(pts); i++ {
+		a1 := math.Atan2(pts[i-1][1]-pts[i-2][1], pts[i-1][0]-pts[i-2][0])
+		a2 := math.Atan2(pts[i][1]-pts[i-1][1], pts[i][0]-pts[i-1][0])
+		if d := math.Abs(math.Remainder(a2-a1, 2*math.Pi)); d > 0.2 {
+			t.Errorf("direction changes by %v at point %v", d, i-1)
+		}
+	}
+}
+
+func TestTraceT_Transform(t *testing.T) {
+	tr := Trace([]Pt{{0, 0}, {10, 0}, {10, 10}}, CircleShape, 0.2)
+	tr.FilletRadius = 2
+	got := tr.Transform(Scale(2, 2).Then(Translate(1, 1))).(*TraceT)
+	if got.FilletRadius != 4 || got.Thickness != 0.4 {
+		t.Errorf("FilletRadius, Thickness = %v, %v, want 4, 0.4", got.FilletRadius, got.Thickness)
+	}
+	if want := (Pt{21, 21}); got.Points[2] != want {
+		t.Errorf("Points[2] = %v, want %v", got.Points[2], want)
+	}
+	if tr.Points[2] != (Pt{10, 10}) {
+		t.Errorf("Transform modified the original: %v", tr.Points)
+	}
+}
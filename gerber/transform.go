@@ -0,0 +1,202 @@
+package gerber
+
+import (
+	"math"
+)
+
+// Transform represents a 2D affine transformation that maps
+// a point (x,y) to (x',y') as follows:
+//
+//	x' = A*x + B*y + C
+//	y' = D*x + E*y + F
+//
+// Translations (C,F) are in millimeters.
+type Transform struct {
+	A, B, C float64
+	D, E, F float64
+}
+
+// Identity returns the identity transformation.
+func Identity() Transform {
+	return Transform{A: 1, E: 1}
+}
+
+// Translate returns a translation by (dx,dy) millimeters.
+func Translate(dx, dy float64) Transform {
+	return Transform{A: 1, C: dx, E: 1, F: dy}
+}
+
+// Rotate returns a counter-clockwise rotation about the origin.
+// The angle is specified in degrees.
+func Rotate(angle float64) Transform {
+	s, c := math.Sincos(math.Pi * angle / 180.0)
+	return Transform{A: c, B: -s, D: s, E: c}
+}
+
+// RotateAbout returns a counter-clockwise rotation about the center point.
+// The angle is specified in degrees.
+func RotateAbout(center Pt, angle float64) Transform {
+	return Translate(-center[0], -center[1]).Then(Rotate(angle)).Then(Translate(center[0], center[1]))
+}
+
+// Scale returns a scaling about the origin.
+func Scale(sx, sy float64) Transform {
+	return Transform{A: sx, E: sy}
+}
+
+// MirrorX returns a transformation that negates all X coordinates
+// (mirroring about the Y axis), as needed for bottom layers.
+func MirrorX() Transform {
+	return Scale(-1, 1)
+}
+
+// MirrorY returns a transformation that negates all Y coordinates
+// (mirroring about the X axis).
+func MirrorY() Transform {
+	return Scale(1, -1)
+}
+
+// Then returns the transformation that first applies t, then next.
+func (t Transform) Then(next Transform) Transform {
+	return Transform{
+		A: next.A*t.A + next.B*t.D,
+		B: next.A*t.B + next.B*t.E,
+		C: next.A*t.C + next.B*t.F + next.C,
+		D: next.D*t.A + next.E*t.D,
+		E: next.D*t.B + next.E*t.E,
+		F: next.D*t.C + next.E*t.F + next.F,
+	}
+}
+
+// Apply returns the transformed point.
+func (t Transform) Apply(pt Pt) Pt {
+	return Pt{
+		t.A*pt[0] + t.B*pt[1] + t.C,
+		t.D*pt[0] + t.E*pt[1] + t.F,
+	}
+}
+
+// Det returns the determinant of the linear part of the transformation.
+// A negative determinant means that the transformation mirrors.
+func (t Transform) Det() float64 {
+	return t.A*t.E - t.B*t.D
+}
+
+// ScaleFactor returns the factor by which areas are scaled, expressed
+// as a linear scale. This is used to scale the thickness of apertures,
+// which cannot themselves be skewed.
+func (t Transform) ScaleFactor() float64 {
+	return math.Sqrt(math.Abs(t.Det()))
+}
+
+// IsIdentity reports whether t is the identity transformation.
+func (t Transform) IsIdentity() bool {
+	return t == Identity()
+}
+
+// transformPts returns a transformed copy of pts.
+func (t Transform) transformPts(pts []Pt) []Pt {
+	result := make([]Pt, 0, len(pts))
+	for _, pt := range pts {
+		result = append(result, t.Apply(pt))
+	}
+	return result
+}
+
+// Transform returns a copy of the aperture scaled by the transformation.
+func (a *Aperture) Transform(xf Transform) Primitive {
+	return &Aperture{
+		Shape: a.Shape,
+		Size:  a.Size * xf.ScaleFactor(),
+	}
+}
+
+// Transform returns a transformed copy of the arc.
+// The exact elliptical geometry is preserved.
+func (a *ArcT) Transform(xf Transform) Primitive {
+	n := *a
+	n.mbb = nil
+	n.Thickness = a.Thickness * xf.ScaleFactor()
+	if a.xf != nil {
+		xf = a.xf.Then(xf)
+	}
+	n.xf = &xf
+	return &n
+}
+
+// Transform returns a transformed copy of the circle.
+func (c *CircleT) Transform(xf Transform) Primitive {
+	return &CircleT{
+		pt:        xf.Apply(c.pt),
+		thickness: c.thickness * xf.ScaleFactor(),
+	}
+}
+
+// Transform returns a transformed copy of the line.
+func (l *LineT) Transform(xf Transform) Primitive {
+	return &LineT{
+		P1:        xf.Apply(l.P1),
+		P2:        xf.Apply(l.P2),
+		Shape:     l.Shape,
+		Thickness: l.Thickness * xf.ScaleFactor(),
+	}
+}
+
+// Transform returns a transformed copy of the polygon.
+// The offset is folded into the transformed points.
+func (p *PolygonT) Transform(xf Transform) Primitive {
+	xf = Translate(p.Offset[0], p.Offset[1]).Then(xf)
+	var holes [][]Pt
+	for _, hole := range p.Holes {
+		holes = append(holes, xf.transformPts(hole))
+	}
+	return &PolygonT{
+		Points:     xf.transformPts(p.Points),
+		Filled:     p.Filled,
+		Thickness:  p.Thickness * xf.ScaleFactor(),
+		Holes:      holes,
+		ClearHoles: p.ClearHoles,
+	}
+}
+
+// Transform returns a transformed copy of the text.
+// The glyphs are transformed exactly after rendering.
+func (t *TextT) Transform(xf Transform) Primitive {
+	n := *t
+	n.Render = nil
+	if t.xf != nil {
+		xf = t.xf.Then(xf)
+	}
+	n.xf = &xf
+	return &n
+}
+
+// Transform applies the affine transformation to all primitives
+// in the layer, replacing them with their transformed copies.
+// Apertures are regenerated since their sizes may have changed.
+func (l *Layer) Transform(xf Transform) {
+	primitives := l.Primitives
+	l.Primitives = nil
+	l.Apertures = nil
+	l.apertureMap = map[string]int{"default": -1}
+	l.mbb = nil
+	l.index = nil
+	for _, p := range primitives {
+		l.Add(p.Transform(xf))
+	}
+	if l.g != nil {
+		l.g.mu.Lock()
+		l.g.mbb = nil
+		l.g.mu.Unlock()
+	}
+}
+
+// Transform applies the affine transformation to all layers in the design.
+func (g *Gerber) Transform(xf Transform) {
+	for _, layer := range g.Layers {
+		layer.Transform(xf)
+	}
+	g.mu.Lock()
+	g.mbb = nil
+	g.mu.Unlock()
+}
@@ -0,0 +1,173 @@
+package gerber
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTransform_Apply(t *testing.T) {
+	const eps = 1e-12
+	tests := []struct {
+		name string
+		xf   Transform
+		pt   Pt
+		want Pt
+	}{
+		{
+			name: "identity",
+			xf:   Identity(),
+			pt:   Pt{1, 2},
+			want: Pt{1, 2},
+		},
+		{
+			name: "translate",
+			xf:   Translate(10, 20),
+			pt:   Pt{1, 2},
+			want: Pt{11, 22},
+		},
+		{
+			name: "rotate 90",
+			xf:   Rotate(90),
+			pt:   Pt{1, 0},
+			want: Pt{0, 1},
+		},
+		{
+			name: "rotate about",
+			xf:   RotateAbout(Pt{1, 1}, 180),
+			pt:   Pt{2, 1},
+			want: Pt{0, 1},
+		},
+		{
+			name: "scale",
+			xf:   Scale(2, 3),
+			pt:   Pt{1, 1},
+			want: Pt{2, 3},
+		},
+		{
+			name: "mirror x",
+			xf:   MirrorX(),
+			pt:   Pt{1, 2},
+			want: Pt{-1, 2},
+		},
+		{
+			name: "mirror y",
+			xf:   MirrorY(),
+			pt:   Pt{1, 2},
+			want: Pt{1, -2},
+		},
+		{
+			name: "rotate then translate",
+			xf:   Rotate(90).Then(Translate(10, 0)),
+			pt:   Pt{1, 0},
+			want: Pt{10, 1},
+		},
+		{
+			name: "translate then rotate",
+			xf:   Translate(10, 0).Then(Rotate(90)),
+			pt:   Pt{1, 0},
+			want: Pt{0, 11},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.xf.Apply(tt.pt)
+			if math.Abs(got[0]-tt.want[0]) > eps || math.Abs(got[1]-tt.want[1]) > eps {
+				t.Errorf("Apply(%v) = %v, want %v", tt.pt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrimitive_Transform(t *testing.T) {
+	const eps = 1e-3
+	tests := []struct {
+		name string
+		p    Primitive
+		xf   Transform
+		want MBB
+	}{
+		{
+			name: "translated circle",
+			p:    Circle(Pt{0, 0}, 1),
+			xf:   Translate(10, 20),
+			want: MBB{Min: Pt{9.5, 19.5}, Max: Pt{10.5, 20.5}},
+		},
+		{
+			name: "scaled circle",
+			p:    Circle(Pt{1, 1}, 1),
+			xf:   Scale(2, 2),
+			want: MBB{Min: Pt{1, 1}, Max: Pt{3, 3}},
+		},
+		{
+			name: "rotated line",
+			p:    Line(0, 0, 10, 0, CircleShape, 2),
+			xf:   Rotate(90),
+			want: MBB{Min: Pt{-1, -1}, Max: Pt{1, 11}},
+		},
+		{
+			name: "mirrored polygon w/ offset",
+			p:    Polygon(Pt{10, 20}, true, []Pt{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, 0),
+			xf:   MirrorX(),
+			want: MBB{Min: Pt{-11, 20}, Max: Pt{-10, 21}},
+		},
+		{
+			name: "rotated first quadrant arc",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 1, 1, 0, 90, 2),
+			xf:   Rotate(90),
+			want: MBB{Min: Pt{-11, -1}, Max: Pt{1, 11}},
+		},
+		{
+			name: "rotated ellipse",
+			p:    Arc(Pt{0, 0}, 10, CircleShape, 2, 1, 0, 360, 0),
+			xf:   Rotate(90),
+			want: MBB{Min: Pt{-10, -20}, Max: Pt{10, 20}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.p.MBB()
+			got := tt.p.Transform(tt.xf).MBB()
+			if math.Abs(got.Min[0]-tt.want.Min[0]) > eps {
+				t.Errorf("Min[0]=%v, want %v", got.Min[0], tt.want.Min[0])
+			}
+			if math.Abs(got.Min[1]-tt.want.Min[1]) > eps {
+				t.Errorf("Min[1]=%v, want %v", got.Min[1], tt.want.Min[1])
+			}
+			if math.Abs(got.Max[0]-tt.want.Max[0]) > eps {
+				t.Errorf("Max[0]=%v, want %v", got.Max[0], tt.want.Max[0])
+			}
+			if math.Abs(got.Max[1]-tt.want.Max[1]) > eps {
+				t.Errorf("Max[1]=%v, want %v", got.Max[1], tt.want.Max[1])
+			}
+			if after := tt.p.MBB(); after != before {
+				t.Errorf("original MBB changed from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+func TestLayer_Transform(t *testing.T) {
+	g := New("test")
+	top := g.TopCopper()
+	top.Add(
+		Circle(Pt{0, 0}, 1),
+		Line(0, 0, 10, 0, RectShape, 1),
+	)
+	if got := g.MBB(); got != (MBB{Min: Pt{-0.5, -0.5}, Max: Pt{10.5, 0.5}}) {
+		t.Fatalf("MBB = %v, want (-0.5,-0.5)-(10.5,0.5)", got)
+	}
+
+	g.Transform(Translate(0, 10).Then(Scale(2, 2)))
+	want := MBB{Min: Pt{-1, 19}, Max: Pt{21, 21}}
+	if got := g.MBB(); got != want {
+		t.Errorf("MBB = %v, want %v", got, want)
+	}
+	if got, want := len(top.Apertures), 2; got != want {
+		t.Fatalf("len(Apertures) = %v, want %v", got, want)
+	}
+	if got, want := top.Apertures[0].Size, 2.0; got != want {
+		t.Errorf("Apertures[0].Size = %v, want %v", got, want)
+	}
+}
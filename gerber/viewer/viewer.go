@@ -21,7 +21,7 @@ import (
 )
 
 var (
-	layerRE = regexp.MustCompile(`\.g(\d+)l$`)
+	layerRE = regexp.MustCompile(`^Layer(\d+)$`)
 )
 
 type viewController struct {
@@ -77,10 +77,10 @@ func initController(g *gerber.Gerber, app fyne.App, allLayersOn bool) *viewContr
 
 	for i, layer := range g.Layers {
 
-		if m := layerRE.FindStringSubmatch(layer.Filename); len(m) == 2 {
+		if m := layerRE.FindStringSubmatch(string(layer.Role)); len(m) == 2 {
 			n, err := strconv.Atoi(m[1])
 			if err != nil || n < 2 {
-				log.Fatalf("error parsing layer suffix %v", layer.Filename)
+				log.Fatalf("error parsing layer role %v", layer.Role)
 			}
 			vc.indexLayerN[n] = i
 			if n > vc.maxN {
@@ -91,25 +91,25 @@ func initController(g *gerber.Gerber, app fyne.App, allLayersOn bool) *viewContr
 		}
 
 		vc.drawLayer[i] = true
-		switch layer.Filename[len(layer.Filename)-4:] {
-		case ".gtl":
+		switch layer.Role {
+		case gerber.TopCopperRole:
 			vc.indexTop = i
-		case ".gts":
+		case gerber.TopSolderMaskRole:
 			vc.indexTopSolderMask = i
-		case ".gto":
+		case gerber.TopSilkscreenRole:
 			vc.indexTopSilkscreen = i
-		case ".gbl":
+		case gerber.BottomCopperRole:
 			vc.indexBottom = i
-		case ".gbs":
+		case gerber.BottomSolderMaskRole:
 			vc.indexBottomSolderMask = i
-		case ".gbo":
+		case gerber.BottomSilkscreenRole:
 			vc.indexBottomSilkscreen = i
-		case ".xln":
+		case gerber.DrillRole:
 			vc.indexDrill = i
-		case ".gko":
+		case gerber.OutlineRole:
 			vc.indexOutline = i
 		default:
-			log.Fatalf("Unknown Gerber layer: %v", layer.Filename)
+			log.Fatalf("Unknown Gerber layer: %v (%v)", layer.Filename, layer.Role)
 		}
 	}
 
@@ -284,35 +284,73 @@ func (vc *viewController) Refresh() {
 			ctx.SetRGBA(fr, fg, fb, fa)
 		}
 		foreground(dc)
-		layer := vc.g.Layers[index]
-		for _, p := range layer.Primitives {
+		path := func(pts []gerber.Pt) {
+			for i, pt := range pts {
+				if i == 0 {
+					dc.MoveTo(xf(pt[0]), yf(pt[1]))
+				} else {
+					dc.LineTo(xf(pt[0]), yf(pt[1]))
+				}
+			}
+		}
+		// drawText renders the text (cleared out of the knockout box,
+		// if any) into a new context, then copies the foreground pixels only.
+		drawText := func(mbb gerber.MBB, t *gerber.TextT, knockout []gerber.Pt) {
+			bnds := vc.img.Bounds()
+			nc := gg.NewContext(bnds.Max.X, bnds.Max.Y)
+			if knockout != nil {
+				foreground(nc)
+				for i, pt := range knockout {
+					if i == 0 {
+						nc.MoveTo(xf(pt[0]), yf(pt[1]))
+					} else {
+						nc.LineTo(xf(pt[0]), yf(pt[1]))
+					}
+				}
+				nc.Fill()
+			}
+			for _, poly := range t.Render.Polygons {
+				if poly.Dark == (knockout == nil) {
+					foreground(nc)
+				} else {
+					nc.SetRGB(0, 0, 0)
+				}
+				for i, pt := range poly.Pts {
+					if i == 0 {
+						nc.MoveTo(xf(pt[0]), yf(pt[1]))
+					} else {
+						nc.LineTo(xf(pt[0]), yf(pt[1]))
+					}
+				}
+				nc.Fill()
+			}
+			llx, lly := int(xf(mbb.Min[0])), int(yf(mbb.Max[1]))
+			urx, ury := int(0.5+xf(mbb.Max[0])), int(0.5+yf(mbb.Min[1]))
+			img := nc.Image()
+			foreground(dc)
+			for y := lly; y <= ury; y++ {
+				for x := llx; x <= urx; x++ {
+					c := img.At(x, y)
+					cr, cg, cb, _ := c.RGBA()
+					if cr == 0 && cg == 0 && cb == 0 {
+						continue
+					}
+					dc.SetPixel(x, y)
+				}
+			}
+		}
+		var renderPrimitive func(p gerber.Primitive)
+		renderPrimitive = func(p gerber.Primitive) {
 			mbb := p.MBB()
 			if !bbox.Intersects(&mbb) {
-				continue
+				return
 			}
 			// Render this primitive.
 			switch v := p.(type) {
 			case *gerber.ArcT:
 				// TODO: account for line shape.
 				dc.SetLineWidth(v.Thickness * vc.scale)
-				delta := v.EndAngle - v.StartAngle
-				length := delta * v.Radius
-				// Resolution of segments is 0.1mm
-				segments := int(0.5+length*10.0) + 1
-				delta /= float64(segments)
-
-				angle := float64(v.StartAngle)
-				for i := 0; i < segments; i++ {
-					x1 := v.Center[0] + v.XScale*math.Cos(angle)*v.Radius
-					y1 := v.Center[1] + v.YScale*math.Sin(angle)*v.Radius
-
-					angle += delta
-
-					x2 := v.Center[0] + v.XScale*math.Cos(angle)*v.Radius
-					y2 := v.Center[1] + v.YScale*math.Sin(angle)*v.Radius
-
-					dc.DrawLine(xf(x1), yf(y1), xf(x2), yf(y2))
-				}
+				path(v.Flatten())
 				dc.Stroke()
 			case *gerber.CircleT:
 				x, y, r := 0.5*(mbb.Min[0]+mbb.Max[0]), 0.5*(mbb.Min[1]+mbb.Max[1]), 0.5*(mbb.Max[0]-mbb.Min[0])
@@ -324,54 +362,115 @@ func (vc *viewController) Refresh() {
 				dc.DrawLine(xf(v.P1[0]), yf(v.P1[1]), xf(v.P2[0]), yf(v.P2[1]))
 				dc.Stroke()
 			case *gerber.TextT:
-				// Render text into new context, the copy foreground pixels only.
-				bnds := vc.img.Bounds()
-				nc := gg.NewContext(bnds.Max.X, bnds.Max.Y)
-				// nc := gg.NewContextForImage(vc.img)
-				for _, poly := range v.Render.Polygons {
-					if poly.Dark {
-						foreground(nc)
-					} else {
-						nc.SetRGB(0, 0, 0)
-					}
-					for i, pt := range poly.Pts {
+				drawText(mbb, v, nil)
+			case *gerber.LabelT:
+				box := v.Box()
+				switch v.Style {
+				case gerber.Knockout:
+					drawText(mbb, v.Text, box)
+				case gerber.Framed:
+					drawText(v.Text.MBB(), v.Text, nil)
+					dc.SetLineWidth(v.Thickness * vc.scale)
+					path(append(box, box[0]))
+					dc.Stroke()
+				default:
+					drawText(v.Text.MBB(), v.Text, nil)
+				}
+			case *gerber.PolygonT:
+				for _, contour := range append([][]gerber.Pt{v.Points}, v.Holes...) {
+					dc.NewSubPath()
+					for i, pt := range contour {
+						p := gerber.Pt{pt[0] + v.Offset[0], pt[1] + v.Offset[1]}
 						if i == 0 {
-							nc.MoveTo(xf(pt[0]), yf(pt[1]))
+							dc.MoveTo(xf(p[0]), yf(p[1]))
 						} else {
-							nc.LineTo(xf(pt[0]), yf(pt[1]))
+							dc.LineTo(xf(p[0]), yf(p[1]))
 						}
 					}
-					nc.Fill()
+					dc.ClosePath()
 				}
-				llx, lly := int(xf(mbb.Min[0])), int(yf(mbb.Max[1]))
-				urx, ury := int(0.5+xf(mbb.Max[0])), int(0.5+yf(mbb.Min[1]))
-				// log.Printf("ll=(%v,%v), ur=(%v,%v)", llx, lly, urx, ury)
-				img := nc.Image()
-				foreground(dc)
-				for y := lly; y <= ury; y++ {
-					for x := llx; x <= urx; x++ {
-						c := img.At(x, y)
-						cr, cg, cb, _ := c.RGBA()
-						if cr == 0 && cg == 0 && cb == 0 {
-							continue
+				if !v.Filled {
+					dc.SetLineWidth(v.Thickness * vc.scale)
+					dc.Stroke()
+					break
+				}
+				dc.SetFillRule(gg.FillRuleEvenOdd)
+				dc.Fill()
+				dc.SetFillRule(gg.FillRuleWinding)
+			case *gerber.SpiralT:
+				if v.Arcs {
+					dc.SetLineWidth(v.Width * vc.scale)
+					path(v.Centerline())
+					dc.Stroke()
+					break
+				}
+				path(v.Outline())
+				dc.Fill()
+			case *gerber.TraceT:
+				// TODO: account for line shape.
+				dc.SetLineWidth(v.Thickness * vc.scale)
+				path(v.Flatten())
+				dc.Stroke()
+			case *gerber.BezierT:
+				path(v.Flatten())
+				if v.Filled {
+					dc.Fill()
+					break
+				}
+				// TODO: account for line shape.
+				dc.SetLineWidth(v.Thickness * vc.scale)
+				dc.Stroke()
+			case *gerber.MatrixCodeT:
+				for _, region := range v.Regions() {
+					path(region)
+					dc.Fill()
+				}
+			case *gerber.BarcodeT:
+				for _, region := range v.Regions() {
+					path(region)
+					dc.Fill()
+				}
+				if v.Text != nil {
+					drawText(v.Text.MBB(), v.Text, nil)
+				}
+			case *gerber.ClearT:
+				// Erase the area with the background, over whatever
+				// has been drawn before it.
+				dc.SetRGB(0, 0, 0)
+				for _, f := range gerber.Features(v.Primitive) {
+					if f.IsRegion() {
+						for _, contour := range append([][]gerber.Pt{f.Region}, f.Holes...) {
+							dc.NewSubPath()
+							path(contour)
+							dc.ClosePath()
 						}
-						dc.SetPixel(x, y)
+						dc.SetFillRule(gg.FillRuleEvenOdd)
+						dc.Fill()
+						dc.SetFillRule(gg.FillRuleWinding)
+						continue
 					}
-				}
-			case *gerber.PolygonT:
-				for i, pt := range v.Points {
-					p := gerber.Pt{pt[0] + v.Offset[0], pt[1] + v.Offset[1]}
-					if i == 0 {
-						dc.MoveTo(xf(p[0]), yf(p[1]))
-					} else {
-						dc.LineTo(xf(p[0]), yf(p[1]))
+					if len(f.Path) == 1 {
+						dc.DrawCircle(xf(f.Path[0][0]), yf(f.Path[0][1]), 0.5*f.Width*vc.scale)
+						dc.Fill()
+						continue
 					}
+					dc.SetLineWidth(f.Width * vc.scale)
+					path(f.Path)
+					dc.Stroke()
+				}
+				foreground(dc)
+			case gerber.Compound:
+				for _, cp := range v.Primitives() {
+					renderPrimitive(cp)
 				}
-				dc.Fill()
 			default:
 				log.Printf("%T not yet supported", v)
 			}
 		}
+		layer := vc.g.Layers[index]
+		for _, i := range layer.Search(*bbox) {
+			renderPrimitive(layer.Primitives[i])
+		}
 	}
 	// Draw layers from bottom up
 	renderLayer(vc.indexOutline, color.RGBA{R: 0, G: 255, B: 0, A: 255})
@@ -2,6 +2,7 @@ module github.com/gmlewis/go-gerber
 
 require (
 	fyne.io/fyne v0.0.0-20190218232030-08ef825cb5b2
+	github.com/boombuler/barcode v1.0.1
 	github.com/fogleman/gg v1.2.0
 	github.com/gmlewis/go-fonts v0.0.5
 	github.com/gmlewis/go3d v0.0.1